@@ -9,3 +9,14 @@ var GOOGLE_CLIENT_ID = os.Getenv("GOOGLE_CLIENT_ID")
 var JWT_SECRET = os.Getenv("JWT_SECRET")
 var MIDTRANS_SERVER_KEY = os.Getenv("MIDTRANS_SERVER_KEY")
 var MIDTRANS_CLIENT_KEY = os.Getenv("MIDTRANS_CLIENT_KEY")
+var MIDTRANS_ENV = os.Getenv("MIDTRANS_ENV")
+var PAYMENT_PROVIDER = os.Getenv("PAYMENT_PROVIDER")
+var PAYMENT_STALE_AFTER = os.Getenv("PAYMENT_STALE_AFTER")
+var PAYMENT_DEADLINE = os.Getenv("PAYMENT_DEADLINE")
+var CART_REMINDER_DELAY = os.Getenv("CART_REMINDER_DELAY")
+var CART_RETENTION = os.Getenv("CART_RETENTION")
+var IDEMPOTENCY_KEY_RETENTION = os.Getenv("IDEMPOTENCY_KEY_RETENTION")
+var DELIVERY_BASE_FEE = os.Getenv("DELIVERY_BASE_FEE")
+var DELIVERY_FEE_PER_KM = os.Getenv("DELIVERY_FEE_PER_KM")
+var DELIVERY_FREE_ABOVE = os.Getenv("DELIVERY_FREE_ABOVE")
+var DELIVERY_MAX_DISTANCE_KM = os.Getenv("DELIVERY_MAX_DISTANCE_KM")
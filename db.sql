@@ -34,6 +34,11 @@ CREATE TABLE IF NOT EXISTS products (
   image VARCHAR(255) NOT NULL,
   price INT NOT NULL,
   is_available BOOLEAN NOT NULL,
+  calories INT NOT NULL DEFAULT 0,
+  sugar NUMERIC(6,1) NOT NULL DEFAULT 0,
+  caffeine INT NOT NULL DEFAULT 0,
+  fat NUMERIC(6,1) NOT NULL DEFAULT 0,
+  allergens VARCHAR(30) ARRAY NOT NULL DEFAULT '{}',
   created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
   updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
@@ -44,6 +49,41 @@ CREATE TABLE IF NOT EXISTS toppings (
   image VARCHAR(255) NOT NULL,
   price INT NOT NULL,
   is_available BOOLEAN NOT NULL,
+  calories INT NOT NULL DEFAULT 0,
+  sugar NUMERIC(6,1) NOT NULL DEFAULT 0,
+  caffeine INT NOT NULL DEFAULT 0,
+  fat NUMERIC(6,1) NOT NULL DEFAULT 0,
+  allergens VARCHAR(30) ARRAY NOT NULL DEFAULT '{}',
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS product_variants (
+  id SERIAL PRIMARY KEY,
+  product_id INT NOT NULL,
+  name VARCHAR(100) NOT NULL,
+  price INT NOT NULL DEFAULT 0,
+  is_available BOOLEAN NOT NULL,
+  calories INT NOT NULL DEFAULT 0,
+  sugar NUMERIC(6,1) NOT NULL DEFAULT 0,
+  caffeine INT NOT NULL DEFAULT 0,
+  fat NUMERIC(6,1) NOT NULL DEFAULT 0,
+  allergens VARCHAR(30) ARRAY NOT NULL DEFAULT '{}',
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  CONSTRAINT fk_product FOREIGN KEY(product_id) REFERENCES products(id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS stores (
+  id SERIAL PRIMARY KEY,
+  name VARCHAR(100) NOT NULL,
+  address VARCHAR(255) NOT NULL,
+  city VARCHAR(100) NOT NULL,
+  longitude NUMERIC NOT NULL,
+  latitude NUMERIC NOT NULL,
+  timezone VARCHAR(50) NOT NULL DEFAULT 'Asia/Jakarta',
+  opening_hours JSONB NOT NULL DEFAULT '[]',
+  is_active BOOLEAN NOT NULL DEFAULT TRUE,
   created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
   updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
@@ -51,15 +91,25 @@ CREATE TABLE IF NOT EXISTS toppings (
 CREATE TABLE IF NOT EXISTS carts (
   id SERIAL PRIMARY KEY,
   user_id VARCHAR(36),
+  guest_id VARCHAR(36),
+  store_id INT,
   product_id INT NOT NULL,
+  variant_id INT,
   topping_id INT ARRAY,
   price INT NOT NULL,
+  price_breakdown JSONB,
   qty INT NOT NULL,
+  reminded_at TIMESTAMP,
   created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
   updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-  CONSTRAINT fk_user FOREIGN KEY(user_id) REFERENCES users(id) ON UPDATE CASCADE ON DELETE CASCADE
+  CONSTRAINT fk_user FOREIGN KEY(user_id) REFERENCES users(id) ON UPDATE CASCADE ON DELETE CASCADE,
+  CONSTRAINT fk_store FOREIGN KEY(store_id) REFERENCES stores(id) ON UPDATE CASCADE ON DELETE CASCADE,
+  CONSTRAINT cart_owner CHECK ((user_id IS NULL) <> (guest_id IS NULL))
 );
 
+CREATE INDEX IF NOT EXISTS carts_guest_idx ON carts (guest_id) WHERE guest_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS carts_updated_idx ON carts (updated_at);
+
 CREATE TABLE IF NOT EXISTS transactions (
   id VARCHAR(36) PRIMARY KEY,
   user_id VARCHAR(36),
@@ -68,17 +118,26 @@ CREATE TABLE IF NOT EXISTS transactions (
   address VARCHAR(255) NOT NULL,
   city VARCHAR(100) NOT NULL,
   postal_code INT NOT NULL,
+  address_id VARCHAR(36),
+  longitude NUMERIC,
+  latitude NUMERIC,
   total INT NOT NULL,
-  status VARCHAR(50),
+  price_breakdown JSONB,
+  status VARCHAR(50) NOT NULL DEFAULT 'pending_payment'
+    CHECK (status IN ('pending_payment', 'paid', 'preparing', 'ready', 'completed', 'cancelled', 'refunded', 'failed')),
+  store_id INT,
   created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
   updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-  CONSTRAINT fk_user FOREIGN KEY(user_id) REFERENCES users(id) ON UPDATE CASCADE ON DELETE CASCADE
+  CONSTRAINT fk_user FOREIGN KEY(user_id) REFERENCES users(id) ON UPDATE CASCADE ON DELETE CASCADE,
+  CONSTRAINT fk_store FOREIGN KEY(store_id) REFERENCES stores(id) ON UPDATE CASCADE ON DELETE SET NULL,
+  CONSTRAINT fk_address FOREIGN KEY(address_id) REFERENCES user_address(id) ON UPDATE CASCADE ON DELETE SET NULL
 );
 
 CREATE TABLE IF NOT EXISTS orders (
   id SERIAL PRIMARY KEY,
   transaction_id VARCHAR(100),
   product_id INT NOT NULL,
+  variant_id INT,
   topping_id INT ARRAY,
   price INT NOT NULL,
   qty SMALLINT NOT NULL,
@@ -100,5 +159,285 @@ CREATE TRIGGER trigger_product_update BEFORE UPDATE ON products FOR EACH ROW EXE
 CREATE TRIGGER trigger_user_update BEFORE UPDATE ON users FOR EACH ROW EXECUTE PROCEDURE change_update_at_column();
 CREATE TRIGGER trigger_address_update BEFORE UPDATE ON user_address FOR EACH ROW EXECUTE PROCEDURE change_update_at_column();
 CREATE TRIGGER trigger_topping_update BEFORE UPDATE ON toppings FOR EACH ROW EXECUTE PROCEDURE change_update_at_column();
+CREATE TRIGGER trigger_variant_update BEFORE UPDATE ON product_variants FOR EACH ROW EXECUTE PROCEDURE change_update_at_column();
+CREATE TRIGGER trigger_store_update BEFORE UPDATE ON stores FOR EACH ROW EXECUTE PROCEDURE change_update_at_column();
 CREATE TRIGGER trigger_transaction_update BEFORE UPDATE ON transactions FOR EACH ROW EXECUTE PROCEDURE change_update_at_column();
 
+CREATE TABLE IF NOT EXISTS product_reviews (
+  id SERIAL PRIMARY KEY,
+  product_id INT NOT NULL,
+  user_id VARCHAR(36) NOT NULL,
+  rating SMALLINT NOT NULL CHECK (rating BETWEEN 1 AND 5),
+  comment VARCHAR(800) NOT NULL DEFAULT '',
+  is_hidden BOOLEAN NOT NULL DEFAULT FALSE,
+  is_flagged BOOLEAN NOT NULL DEFAULT FALSE,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  CONSTRAINT product_reviews_user_unique UNIQUE (product_id, user_id),
+  CONSTRAINT fk_product FOREIGN KEY(product_id) REFERENCES products(id) ON UPDATE CASCADE ON DELETE CASCADE,
+  CONSTRAINT fk_user FOREIGN KEY(user_id) REFERENCES users(id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE TRIGGER trigger_review_update BEFORE UPDATE ON product_reviews FOR EACH ROW EXECUTE PROCEDURE change_update_at_column();
+
+CREATE TABLE IF NOT EXISTS user_favorites (
+  user_id VARCHAR(36) NOT NULL,
+  product_id INT NOT NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (user_id, product_id),
+  CONSTRAINT fk_user FOREIGN KEY(user_id) REFERENCES users(id) ON UPDATE CASCADE ON DELETE CASCADE,
+  CONSTRAINT fk_product FOREIGN KEY(product_id) REFERENCES products(id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS saved_customizations (
+  id SERIAL PRIMARY KEY,
+  user_id VARCHAR(36) NOT NULL,
+  name VARCHAR(100) NOT NULL,
+  product_id INT NOT NULL,
+  variant_id INT,
+  topping_id INT ARRAY,
+  qty SMALLINT NOT NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  CONSTRAINT fk_user FOREIGN KEY(user_id) REFERENCES users(id) ON UPDATE CASCADE ON DELETE CASCADE,
+  CONSTRAINT fk_product FOREIGN KEY(product_id) REFERENCES products(id) ON UPDATE CASCADE ON DELETE CASCADE,
+  CONSTRAINT fk_variant FOREIGN KEY(variant_id) REFERENCES product_variants(id) ON UPDATE CASCADE ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS product_revisions (
+  id SERIAL PRIMARY KEY,
+  product_id INT NOT NULL,
+  name VARCHAR(100) NOT NULL,
+  description VARCHAR(800) NOT NULL,
+  image VARCHAR(255) NOT NULL,
+  price INT NOT NULL,
+  is_available BOOLEAN NOT NULL,
+  is_deleted BOOLEAN NOT NULL DEFAULT FALSE,
+  effective_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS product_revisions_product_idx ON product_revisions (product_id, effective_at);
+
+CREATE TABLE IF NOT EXISTS topping_revisions (
+  id SERIAL PRIMARY KEY,
+  topping_id INT NOT NULL,
+  name VARCHAR(100) NOT NULL,
+  image VARCHAR(255) NOT NULL,
+  price INT NOT NULL,
+  is_available BOOLEAN NOT NULL,
+  is_deleted BOOLEAN NOT NULL DEFAULT FALSE,
+  effective_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS topping_revisions_topping_idx ON topping_revisions (topping_id, effective_at);
+
+CREATE OR REPLACE FUNCTION record_product_revision() RETURNS TRIGGER AS $$
+BEGIN
+  IF TG_OP = 'DELETE' THEN
+    INSERT INTO product_revisions (product_id, name, description, image, price, is_available, is_deleted)
+    VALUES (OLD.id, OLD.name, OLD.description, OLD.image, OLD.price, OLD.is_available, TRUE);
+    RETURN OLD;
+  END IF;
+
+  IF TG_OP = 'UPDATE' AND (OLD.name, OLD.description, OLD.image, OLD.price, OLD.is_available) IS NOT DISTINCT FROM (NEW.name, NEW.description, NEW.image, NEW.price, NEW.is_available) THEN
+    RETURN NEW;
+  END IF;
+
+  INSERT INTO product_revisions (product_id, name, description, image, price, is_available)
+  VALUES (NEW.id, NEW.name, NEW.description, NEW.image, NEW.price, NEW.is_available);
+  RETURN NEW;
+END;
+$$ LANGUAGE PLPGSQL;
+
+CREATE OR REPLACE FUNCTION record_topping_revision() RETURNS TRIGGER AS $$
+BEGIN
+  IF TG_OP = 'DELETE' THEN
+    INSERT INTO topping_revisions (topping_id, name, image, price, is_available, is_deleted)
+    VALUES (OLD.id, OLD.name, OLD.image, OLD.price, OLD.is_available, TRUE);
+    RETURN OLD;
+  END IF;
+
+  IF TG_OP = 'UPDATE' AND (OLD.name, OLD.image, OLD.price, OLD.is_available) IS NOT DISTINCT FROM (NEW.name, NEW.image, NEW.price, NEW.is_available) THEN
+    RETURN NEW;
+  END IF;
+
+  INSERT INTO topping_revisions (topping_id, name, image, price, is_available)
+  VALUES (NEW.id, NEW.name, NEW.image, NEW.price, NEW.is_available);
+  RETURN NEW;
+END;
+$$ LANGUAGE PLPGSQL;
+
+CREATE TRIGGER trigger_product_revision AFTER INSERT OR UPDATE OR DELETE ON products FOR EACH ROW EXECUTE PROCEDURE record_product_revision();
+CREATE TRIGGER trigger_topping_revision AFTER INSERT OR UPDATE OR DELETE ON toppings FOR EACH ROW EXECUTE PROCEDURE record_topping_revision();
+
+-- products and toppings created before versioning get their current state as first revision
+INSERT INTO product_revisions (product_id, name, description, image, price, is_available, effective_at)
+SELECT id, name, description, image, price, is_available, created_at FROM products
+WHERE id NOT IN (SELECT product_id FROM product_revisions);
+
+INSERT INTO topping_revisions (topping_id, name, image, price, is_available, effective_at)
+SELECT id, name, image, price, is_available, created_at FROM toppings
+WHERE id NOT IN (SELECT topping_id FROM topping_revisions);
+
+CREATE TABLE IF NOT EXISTS product_translations (
+  product_id INT NOT NULL,
+  locale VARCHAR(10) NOT NULL,
+  name VARCHAR(100) NOT NULL,
+  description VARCHAR(800) NOT NULL DEFAULT '',
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (product_id, locale),
+  CONSTRAINT fk_product FOREIGN KEY(product_id) REFERENCES products(id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS topping_translations (
+  topping_id INT NOT NULL,
+  locale VARCHAR(10) NOT NULL,
+  name VARCHAR(100) NOT NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (topping_id, locale),
+  CONSTRAINT fk_topping FOREIGN KEY(topping_id) REFERENCES toppings(id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS product_images (
+  id SERIAL PRIMARY KEY,
+  product_id INT NOT NULL,
+  image VARCHAR(255) NOT NULL,
+  alt_text VARCHAR(255) NOT NULL DEFAULT '',
+  sort_order INT NOT NULL DEFAULT 0,
+  is_primary BOOLEAN NOT NULL DEFAULT FALSE,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  CONSTRAINT fk_product FOREIGN KEY(product_id) REFERENCES products(id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS product_images_primary_unique ON product_images (product_id) WHERE is_primary;
+
+INSERT INTO product_images (product_id, image, is_primary)
+SELECT id, image, TRUE FROM products p
+WHERE image <> '' AND NOT EXISTS (SELECT 1 FROM product_images WHERE product_id = p.id);
+
+CREATE TABLE IF NOT EXISTS product_affinities (
+  product_id INT NOT NULL,
+  related_product_id INT NOT NULL,
+  support INT NOT NULL,
+  confidence NUMERIC(6,5) NOT NULL,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (product_id, related_product_id),
+  CONSTRAINT fk_product FOREIGN KEY(product_id) REFERENCES products(id) ON UPDATE CASCADE ON DELETE CASCADE,
+  CONSTRAINT fk_related_product FOREIGN KEY(related_product_id) REFERENCES products(id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS topping_affinities (
+  product_id INT NOT NULL,
+  topping_id INT NOT NULL,
+  support INT NOT NULL,
+  confidence NUMERIC(6,5) NOT NULL,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (product_id, topping_id),
+  CONSTRAINT fk_product FOREIGN KEY(product_id) REFERENCES products(id) ON UPDATE CASCADE ON DELETE CASCADE,
+  CONSTRAINT fk_topping FOREIGN KEY(topping_id) REFERENCES toppings(id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+-- price overrides the catalog price when set, availability is combined with the catalog availability
+CREATE TABLE IF NOT EXISTS store_products (
+  store_id INT NOT NULL,
+  product_id INT NOT NULL,
+  price INT,
+  is_available BOOLEAN NOT NULL DEFAULT TRUE,
+  PRIMARY KEY (store_id, product_id),
+  CONSTRAINT fk_store FOREIGN KEY(store_id) REFERENCES stores(id) ON UPDATE CASCADE ON DELETE CASCADE,
+  CONSTRAINT fk_product FOREIGN KEY(product_id) REFERENCES products(id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS store_toppings (
+  store_id INT NOT NULL,
+  topping_id INT NOT NULL,
+  price INT,
+  is_available BOOLEAN NOT NULL DEFAULT TRUE,
+  PRIMARY KEY (store_id, topping_id),
+  CONSTRAINT fk_store FOREIGN KEY(store_id) REFERENCES stores(id) ON UPDATE CASCADE ON DELETE CASCADE,
+  CONSTRAINT fk_topping FOREIGN KEY(topping_id) REFERENCES toppings(id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+-- admins listed here only manage their stores, admins not listed manage every store
+CREATE TABLE IF NOT EXISTS store_staff (
+  store_id INT NOT NULL,
+  user_id VARCHAR(36) NOT NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (store_id, user_id),
+  CONSTRAINT fk_store FOREIGN KEY(store_id) REFERENCES stores(id) ON UPDATE CASCADE ON DELETE CASCADE,
+  CONSTRAINT fk_user FOREIGN KEY(user_id) REFERENCES users(id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+-- every status change of a transaction, actor_id is empty for the system and the payment gateway
+CREATE TABLE IF NOT EXISTS transaction_events (
+  id SERIAL PRIMARY KEY,
+  transaction_id VARCHAR(36) NOT NULL,
+  from_status VARCHAR(50),
+  to_status VARCHAR(50) NOT NULL,
+  actor VARCHAR(20) NOT NULL,
+  actor_id VARCHAR(36),
+  note TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  CONSTRAINT fk_transaction FOREIGN KEY(transaction_id) REFERENCES transactions(id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS transaction_events_transaction_idx ON transaction_events (transaction_id, id);
+
+-- statuses written before the order lifecycle
+UPDATE transactions SET status = CASE status
+  WHEN 'success' THEN 'paid'
+  WHEN 'failure' THEN 'failed'
+  ELSE 'pending_payment'
+END
+WHERE status IS NULL OR status IN ('success', 'failure', 'pending');
+
+CREATE TABLE IF NOT EXISTS refunds (
+  id SERIAL PRIMARY KEY,
+  transaction_id VARCHAR(36) NOT NULL,
+  refund_key VARCHAR(50) NOT NULL UNIQUE,
+  amount INT NOT NULL CHECK (amount > 0),
+  order_id INT ARRAY NOT NULL,
+  reason VARCHAR(255) NOT NULL DEFAULT '',
+  status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'succeeded', 'failed')),
+  actor_id VARCHAR(36),
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  CONSTRAINT fk_transaction FOREIGN KEY(transaction_id) REFERENCES transactions(id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS refunds_transaction_idx ON refunds (transaction_id);
+CREATE TRIGGER trigger_refund_update BEFORE UPDATE ON refunds FOR EACH ROW EXECUTE PROCEDURE change_update_at_column();
+
+CREATE TABLE IF NOT EXISTS payment_notifications (
+  id SERIAL PRIMARY KEY,
+  transaction_id VARCHAR(36) NOT NULL,
+  status VARCHAR(50) NOT NULL DEFAULT '',
+  refund_keys VARCHAR(50) ARRAY NOT NULL DEFAULT '{}',
+  gross_amount INT NOT NULL DEFAULT 0,
+  note VARCHAR(255) NOT NULL DEFAULT '',
+  dedupe_key VARCHAR(255) NOT NULL UNIQUE,
+  payload JSONB NOT NULL,
+  processed_at TIMESTAMP,
+  error TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS payment_notifications_transaction_idx ON payment_notifications (transaction_id, id);
+
+CREATE INDEX IF NOT EXISTS transactions_pending_idx ON transactions (created_at) WHERE status = 'pending_payment';
+
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+  owner VARCHAR(100) NOT NULL,
+  idempotency_key VARCHAR(255) NOT NULL,
+  request_hash CHAR(64) NOT NULL,
+  status_code INT,
+  content_type VARCHAR(100) NOT NULL DEFAULT '',
+  response_body BYTEA,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  completed_at TIMESTAMP,
+  PRIMARY KEY (owner, idempotency_key)
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_keys_created_idx ON idempotency_keys (created_at);
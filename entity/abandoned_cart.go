@@ -0,0 +1,55 @@
+package entity
+
+import "time"
+
+// AbandonedCart sums up a cart left untouched since LastActivity. Guest carts
+// have no name or email and cannot be reminded.
+type AbandonedCart struct {
+	UserId       string     `db:"user_id" json:"user_id,omitempty"`
+	GuestId      string     `db:"guest_id" json:"guest_id,omitempty"`
+	Name         string     `db:"name" json:"name,omitempty"`
+	Email        string     `db:"email" json:"email,omitempty"`
+	Lines        int        `db:"lines" json:"lines"`
+	Value        int        `db:"value" json:"value"`
+	LastActivity time.Time  `db:"last_activity" json:"last_activity"`
+	RemindedAt   *time.Time `db:"reminded_at" json:"reminded_at"`
+}
+
+// NeedsReminder reports whether the owner can be reminded and was not
+// reminded since the last change of the cart
+func (c AbandonedCart) NeedsReminder() bool {
+	return c.Email != "" && (c.RemindedAt == nil || c.RemindedAt.Before(c.LastActivity))
+}
+
+type AbandonedCartReport struct {
+	Since    time.Time       `json:"since"`
+	Count    int             `json:"count"`
+	Guests   int             `json:"guests"`
+	Reminded int             `json:"reminded"`
+	Lines    int             `json:"lines"`
+	Value    int             `json:"value"`
+	Carts    []AbandonedCart `json:"carts"`
+}
+
+// NewAbandonedCartReport totals the carts abandoned since the given time
+func NewAbandonedCartReport(since time.Time, carts []AbandonedCart) AbandonedCartReport {
+	report := AbandonedCartReport{Since: since, Carts: carts}
+	if report.Carts == nil {
+		report.Carts = []AbandonedCart{}
+	}
+
+	for _, c := range report.Carts {
+		report.Count++
+		report.Lines += c.Lines
+		report.Value += c.Value
+
+		if c.UserId == "" {
+			report.Guests++
+		}
+		if c.RemindedAt != nil && !c.NeedsReminder() {
+			report.Reminded++
+		}
+	}
+
+	return report
+}
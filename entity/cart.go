@@ -1,41 +1,170 @@
 package entity
 
+import (
+	"sort"
+
+	"github.com/lib/pq"
+)
+
+// MaxCartQty is the maximum quantity of a single cart line
+const MaxCartQty = 20
+
 type Cart struct {
-	Id         int           `db:"id" json:"id"`
-	Price      int           `db:"price" json:"price"`
-	Qty        int           `db:"qty" json:"qty"`
-	ProductId  int           `db:"product_id" json:"-"`
-	ToppingIds []int64       `db:"topping_id" json:"-"`
-	UserId     string        `db:"user_id" json:"-"`
-	Product    CartProduct   `json:"product"`
-	Topping    []CartTopping `json:"toppings"`
+	Id         int             `db:"id" json:"id"`
+	Price      int             `db:"price" json:"price"`
+	Qty        int             `db:"qty" json:"qty"`
+	ProductId  int             `db:"product_id" json:"-"`
+	VariantId  *int            `db:"variant_id" json:"-"`
+	ToppingIds []int64         `db:"topping_id" json:"-"`
+	UserId     string          `db:"user_id" json:"-"`
+	GuestId    string          `db:"guest_id" json:"-"`
+	StoreId    *int            `db:"store_id" json:"store_id"`
+	Breakdown  *PriceBreakdown `db:"price_breakdown" json:"price_breakdown"`
+	Product    CartProduct     `json:"product"`
+	Variant    *CartVariant    `json:"variant,omitempty"`
+	Topping    []CartTopping   `json:"toppings"`
+	Nutrition  Nutrition       `json:"nutrition"`
+	Allergens  []string        `json:"allergens"`
 }
 
+// CartRequest price is optional. When set it must match the server price.
 type CartRequest struct {
-	Price      int     `json:"price" validate:"required"`
-	Qty        int     `json:"qty" validate:"required"`
+	Price      int     `json:"price"`
+	Qty        int     `json:"qty" validate:"required,min=1,max=20"`
 	ProductId  int     `json:"product_id" validate:"required"`
+	VariantId  *int    `json:"variant_id"`
+	StoreId    *int    `json:"store_id"`
 	ToppingIds []int64 `json:"topping_id"`
 }
 
+type CartQtyRequest struct {
+	Qty int `json:"qty" validate:"required,min=1,max=20"`
+}
+
+type CartToppingsRequest struct {
+	ToppingIds []int64 `json:"topping_id"`
+}
+
+// CartOwner identifies the cart of a signed in user or, when UserId is empty,
+// the cart of a guest holding a signed cart token
+type CartOwner struct {
+	UserId  string
+	GuestId string
+}
+
+func (o CartOwner) IsGuest() bool {
+	return o.UserId == ""
+}
+
+// CartMerge reports how the lines of a guest cart were moved into the user
+// cart. Capped lines hit MaxCartQty, dropped lines are no longer available.
+// Error is set when the merge failed and the guest cart was left as it was.
+type CartMerge struct {
+	Merged  int    `json:"merged"`
+	Capped  int    `json:"capped"`
+	Dropped int    `json:"dropped"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Kinds of cart issues found by a cart validation
+const (
+	CartIssueUnavailable    = "unavailable"
+	CartIssuePriceChanged   = "price_changed"
+	CartIssueToppingRemoved = "topping_removed"
+)
+
+// CartIssue is a line that no longer matches the catalog. OldPrice and
+// NewPrice are line totals, ToppingIds lists the toppings that were removed.
+type CartIssue struct {
+	CartId     int     `json:"cart_id,omitempty"`
+	ProductId  int     `json:"product_id"`
+	Kind       string  `json:"kind"`
+	Message    string  `json:"message"`
+	OldPrice   int     `json:"old_price,omitempty"`
+	NewPrice   int     `json:"new_price,omitempty"`
+	ToppingIds []int64 `json:"topping_ids,omitempty"`
+}
+
+type CartValidation struct {
+	Valid   bool        `json:"valid"`
+	Applied bool        `json:"applied"`
+	Issues  []CartIssue `json:"issues"`
+}
+
 type CartProduct struct {
-	Id    int    `db:"id" json:"id"`
-	Name  string `db:"name" json:"name"`
-	Image string `db:"image" json:"image"`
-	Price int    `db:"price" json:"price"`
+	Id        int    `db:"id" json:"id"`
+	Name      string `db:"name" json:"name"`
+	Image     string `db:"image" json:"image"`
+	Price     int    `db:"price" json:"price"`
+	Nutrition `json:"-"`
+	Allergens pq.StringArray `db:"allergens" json:"-"`
+}
+
+type CartVariant struct {
+	Id        int    `db:"id" json:"id"`
+	Name      string `db:"name" json:"name"`
+	Nutrition `json:"-"`
+	Allergens pq.StringArray `db:"allergens" json:"-"`
 }
 
 type CartTopping struct {
-	Id   int    `db:"id" json:"id"`
-	Name string `db:"name" json:"name"`
+	Id        int    `db:"id" json:"id"`
+	Name      string `db:"name" json:"name"`
+	Nutrition `json:"-"`
+	Allergens pq.StringArray `db:"allergens" json:"-"`
 }
 
-func NewCart(productID int, price int, qty int, toppingID []int64, userID string) Cart {
+func NewCart(storeID *int, productID int, variantID *int, breakdown *PriceBreakdown, qty int, toppingID []int64, owner CartOwner) Cart {
 	return Cart{
-		Price:      price,
+		Price:      breakdown.Total,
+		Breakdown:  breakdown,
 		Qty:        qty,
 		ProductId:  productID,
-		ToppingIds: toppingID,
-		UserId:     userID,
+		VariantId:  variantID,
+		ToppingIds: NormalizeToppingIds(toppingID),
+		UserId:     owner.UserId,
+		GuestId:    owner.GuestId,
+		StoreId:    storeID,
+	}
+}
+
+func (c Cart) Owner() CartOwner {
+	return CartOwner{UserId: c.UserId, GuestId: c.GuestId}
+}
+
+// NormalizeToppingIds sorts the topping ids and removes duplicates so equal
+// topping sets compare equal
+func NormalizeToppingIds(ids []int64) []int64 {
+	normalized := make([]int64, 0, len(ids))
+	seen := make(map[int64]bool, len(ids))
+
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			normalized = append(normalized, id)
+		}
 	}
+
+	sort.Slice(normalized, func(i, j int) bool { return normalized[i] < normalized[j] })
+	return normalized
+}
+
+// ComputeNutrition sums the nutrition facts and allergens of the product,
+// variant and toppings of the cart line, multiplied by its quantity.
+func (c *Cart) ComputeNutrition() {
+	unit := c.Product.Nutrition
+	allergens := [][]string{c.Product.Allergens}
+
+	if c.Variant != nil {
+		unit = unit.Add(c.Variant.Nutrition)
+		allergens = append(allergens, c.Variant.Allergens)
+	}
+
+	for _, topping := range c.Topping {
+		unit = unit.Add(topping.Nutrition)
+		allergens = append(allergens, topping.Allergens)
+	}
+
+	c.Nutrition = unit.Multiply(c.Qty)
+	c.Allergens = MergeAllergens(allergens...)
 }
@@ -0,0 +1,69 @@
+package entity
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCartComputeNutrition(t *testing.T) {
+	cart := Cart{
+		Qty: 2,
+		Product: CartProduct{
+			Nutrition: Nutrition{Calories: 150, Sugar: 12.5, Caffeine: 80, Fat: 4.2},
+			Allergens: []string{AllergenMilk},
+		},
+		Variant: &CartVariant{
+			Nutrition: Nutrition{Calories: 50, Sugar: 4, Caffeine: 40, Fat: 1.1},
+		},
+		Topping: []CartTopping{
+			{Nutrition: Nutrition{Calories: 100, Sugar: 10.1}, Allergens: []string{AllergenSoy, AllergenMilk}},
+		},
+	}
+
+	cart.ComputeNutrition()
+
+	want := Nutrition{Calories: 600, Sugar: 53.2, Caffeine: 240, Fat: 10.6}
+	if cart.Nutrition != want {
+		t.Errorf("expected %+v, got %+v", want, cart.Nutrition)
+	}
+
+	if !reflect.DeepEqual(cart.Allergens, []string{AllergenMilk, AllergenSoy}) {
+		t.Errorf("unexpected allergens %v", cart.Allergens)
+	}
+}
+
+func TestNormalizeToppingIds(t *testing.T) {
+	tests := []struct {
+		name string
+		ids  []int64
+		want []int64
+	}{
+		{name: "nil", ids: nil, want: []int64{}},
+		{name: "sorted", ids: []int64{3, 1, 2}, want: []int64{1, 2, 3}},
+		{name: "duplicates", ids: []int64{2, 1, 2, 1}, want: []int64{1, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeToppingIds(tt.ids); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NormalizeToppingIds() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewAbandonedCartReport(t *testing.T) {
+	lastActivity := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	remindedAt := lastActivity.Add(24 * time.Hour)
+
+	report := NewAbandonedCartReport(lastActivity, []AbandonedCart{
+		{UserId: "a", Email: "a@mail.com", Lines: 2, Value: 60000, LastActivity: lastActivity, RemindedAt: &remindedAt},
+		{UserId: "b", Email: "b@mail.com", Lines: 1, Value: 30000, LastActivity: lastActivity},
+		{GuestId: "c", Lines: 3, Value: 90000, LastActivity: lastActivity},
+	})
+
+	if report.Count != 3 || report.Guests != 1 || report.Reminded != 1 || report.Lines != 6 || report.Value != 180000 {
+		t.Errorf("NewAbandonedCartReport() = %+v", report)
+	}
+}
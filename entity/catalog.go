@@ -0,0 +1,47 @@
+package entity
+
+const (
+	ImportActionCreate    = "create"
+	ImportActionUpdate    = "update"
+	ImportActionUnchanged = "unchanged"
+	ImportActionError     = "error"
+)
+
+type ImportReport struct {
+	DryRun    bool        `json:"dry_run"`
+	Applied   bool        `json:"applied"`
+	Created   int         `json:"created"`
+	Updated   int         `json:"updated"`
+	Unchanged int         `json:"unchanged"`
+	Failed    int         `json:"failed"`
+	Rows      []ImportRow `json:"rows"`
+}
+
+type ImportRow struct {
+	Line    int               `json:"line"`
+	Id      int               `json:"id,omitempty"`
+	Name    string            `json:"name"`
+	Action  string            `json:"action"`
+	Error   string            `json:"error,omitempty"`
+	Changes map[string]Change `json:"changes,omitempty"`
+}
+
+type Change struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+func (r *ImportReport) Add(row ImportRow) {
+	switch row.Action {
+	case ImportActionCreate:
+		r.Created++
+	case ImportActionUpdate:
+		r.Updated++
+	case ImportActionUnchanged:
+		r.Unchanged++
+	case ImportActionError:
+		r.Failed++
+	}
+
+	r.Rows = append(r.Rows, row)
+}
@@ -0,0 +1,71 @@
+package entity
+
+import "math"
+
+// earthRadiusKm is the mean radius of the earth used for distances
+const earthRadiusKm = 6371.0
+
+// DeliveryRules prices the delivery of an order from its store. The fee is
+// the base fee plus the per km fee for every started km. Orders whose
+// subtotal reaches FreeAbove are delivered for free, and addresses further
+// than MaxDistanceKm are not delivered to. Zero disables either limit.
+type DeliveryRules struct {
+	BaseFee       int
+	PerKmFee      int
+	FreeAbove     int
+	MaxDistanceKm int
+}
+
+// DeliveryQuote is the delivery fee of an order of subtotal from a store
+type DeliveryQuote struct {
+	StoreId    int     `json:"store_id"`
+	DistanceKm float64 `json:"distance_km"`
+	Subtotal   int     `json:"subtotal"`
+	Fee        int     `json:"fee"`
+	FreeAbove  int     `json:"free_above,omitempty"`
+}
+
+// DeliveryQuoteRequest locates the address by AddressId, or by Latitude and
+// Longitude when AddressId is empty
+type DeliveryQuoteRequest struct {
+	StoreId   int
+	AddressId string
+	Latitude  *float64
+	Longitude *float64
+	Subtotal  int
+	UserId    string
+}
+
+// DistanceKm returns the great circle distance between two coordinates
+func DistanceKm(lat1 float64, lng1 float64, lat2 float64, lng2 float64) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+
+	dLat := toRad(lat2 - lat1)
+	dLng := toRad(lng2 - lng1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLng/2)*math.Sin(dLng/2)
+
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
+
+// Quote prices the delivery from the store to the coordinates. It returns
+// false when they are out of range.
+func (r DeliveryRules) Quote(store Store, latitude float64, longitude float64, subtotal int) (DeliveryQuote, bool) {
+	distance := DistanceKm(store.Latitude, store.Longitude, latitude, longitude)
+	if r.MaxDistanceKm > 0 && distance > float64(r.MaxDistanceKm) {
+		return DeliveryQuote{}, false
+	}
+
+	quote := DeliveryQuote{
+		StoreId:    store.Id,
+		DistanceKm: math.Round(distance*100) / 100,
+		Subtotal:   subtotal,
+		FreeAbove:  r.FreeAbove,
+	}
+
+	if r.FreeAbove == 0 || subtotal < r.FreeAbove {
+		quote.Fee = r.BaseFee + r.PerKmFee*int(math.Ceil(distance))
+	}
+
+	return quote, true
+}
@@ -0,0 +1,42 @@
+package entity
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDistanceKm(t *testing.T) {
+	// Monas to Bundaran HI in Jakarta
+	if d := DistanceKm(-6.1754, 106.8272, -6.1950, 106.8230); math.Abs(d-2.23) > 0.05 {
+		t.Errorf("DistanceKm() = %v, want about 2.23", d)
+	}
+
+	if d := DistanceKm(-6.1754, 106.8272, -6.1754, 106.8272); d != 0 {
+		t.Errorf("DistanceKm() same point = %v, want 0", d)
+	}
+}
+
+func TestDeliveryRulesQuote(t *testing.T) {
+	rules := DeliveryRules{BaseFee: 5000, PerKmFee: 2000, FreeAbove: 150000, MaxDistanceKm: 10}
+	store := Store{Id: 1, Latitude: -6.1754, Longitude: 106.8272}
+
+	quote, ok := rules.Quote(store, -6.1950, 106.8230, 50000)
+	if !ok || quote.DistanceKm != 2.23 || quote.Fee != 11000 || quote.StoreId != 1 {
+		t.Errorf("Quote() = %+v, %v, want 11000 for 2.23 km", quote, ok)
+	}
+
+	if quote, ok := rules.Quote(store, -6.1950, 106.8230, 150000); !ok || quote.Fee != 0 {
+		t.Errorf("Quote() above the free threshold = %+v, %v, want a free delivery", quote, ok)
+	}
+
+	// Bogor is about 45 km away
+	if _, ok := rules.Quote(store, -6.5950, 106.8166, 50000); ok {
+		t.Error("Quote() out of range, want false")
+	}
+
+	rules.MaxDistanceKm = 0
+	rules.FreeAbove = 0
+	if quote, ok := rules.Quote(store, -6.5950, 106.8166, 500000); !ok || quote.Fee == 0 {
+		t.Errorf("Quote() without limits = %+v, %v", quote, ok)
+	}
+}
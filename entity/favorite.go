@@ -0,0 +1,31 @@
+package entity
+
+type Customization struct {
+	Id         int          `db:"id" json:"id"`
+	UserId     string       `db:"user_id" json:"-"`
+	Name       string       `db:"name" json:"name"`
+	ProductId  int          `db:"product_id" json:"product_id"`
+	VariantId  *int         `db:"variant_id" json:"variant_id"`
+	ToppingIds []int64      `db:"topping_id" json:"topping_id"`
+	Qty        int          `db:"qty" json:"qty"`
+	Product    *CartProduct `json:"product,omitempty"`
+}
+
+type CustomizationRequest struct {
+	Name       string  `json:"name" validate:"required,max=100"`
+	ProductId  int     `json:"product_id" validate:"required"`
+	VariantId  *int    `json:"variant_id"`
+	ToppingIds []int64 `json:"topping_id"`
+	Qty        int     `json:"qty" validate:"required,min=1"`
+}
+
+func NewCustomization(userID string, req CustomizationRequest) Customization {
+	return Customization{
+		UserId:     userID,
+		Name:       req.Name,
+		ProductId:  req.ProductId,
+		VariantId:  req.VariantId,
+		ToppingIds: req.ToppingIds,
+		Qty:        req.Qty,
+	}
+}
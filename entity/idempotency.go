@@ -0,0 +1,41 @@
+package entity
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// IdempotencyKey stores the first response to a request sent with an
+// Idempotency-Key header so a retry gets the same response. StatusCode is
+// zero until the first request completes.
+type IdempotencyKey struct {
+	Owner        string     `db:"owner"`
+	Key          string     `db:"idempotency_key"`
+	RequestHash  string     `db:"request_hash"`
+	StatusCode   int        `db:"status_code"`
+	ContentType  string     `db:"content_type"`
+	ResponseBody []byte     `db:"response_body"`
+	CreatedAt    time.Time  `db:"created_at"`
+	CompletedAt  *time.Time `db:"completed_at"`
+}
+
+// NewIdempotencyKey starts the key of owner for a request. The request hash
+// covers the method, the path and the body, so reusing the key for another
+// request can be told apart from a retry.
+func NewIdempotencyKey(owner string, key string, method string, path string, body []byte) IdempotencyKey {
+	h := sha256.New()
+	h.Write([]byte(method + " " + path + "\n"))
+	h.Write(body)
+
+	return IdempotencyKey{
+		Owner:       owner,
+		Key:         key,
+		RequestHash: hex.EncodeToString(h.Sum(nil)),
+	}
+}
+
+// Completed reports whether the response of the first request is stored
+func (k IdempotencyKey) Completed() bool {
+	return k.CompletedAt != nil
+}
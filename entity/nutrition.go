@@ -0,0 +1,76 @@
+package entity
+
+import (
+	"math"
+	"sort"
+)
+
+const (
+	AllergenMilk    = "milk"
+	AllergenSoy     = "soy"
+	AllergenNuts    = "nuts"
+	AllergenGluten  = "gluten"
+	AllergenEgg     = "egg"
+	AllergenSesame  = "sesame"
+	AllergenSulfite = "sulfite"
+)
+
+// Nutrition facts of a single serving. Sugar and fat are in grams and
+// caffeine is in milligrams.
+type Nutrition struct {
+	Calories int     `db:"calories" json:"calories"`
+	Sugar    float64 `db:"sugar" json:"sugar"`
+	Caffeine int     `db:"caffeine" json:"caffeine"`
+	Fat      float64 `db:"fat" json:"fat"`
+}
+
+type NutritionRequest struct {
+	Calories int     `json:"calories" validate:"min=0"`
+	Sugar    float64 `json:"sugar" validate:"min=0"`
+	Caffeine int     `json:"caffeine" validate:"min=0"`
+	Fat      float64 `json:"fat" validate:"min=0"`
+}
+
+func NewNutrition(req NutritionRequest) Nutrition {
+	return Nutrition(req)
+}
+
+func (n Nutrition) Add(other Nutrition) Nutrition {
+	return Nutrition{
+		Calories: n.Calories + other.Calories,
+		Sugar:    round1(n.Sugar + other.Sugar),
+		Caffeine: n.Caffeine + other.Caffeine,
+		Fat:      round1(n.Fat + other.Fat),
+	}
+}
+
+func (n Nutrition) Multiply(qty int) Nutrition {
+	return Nutrition{
+		Calories: n.Calories * qty,
+		Sugar:    round1(n.Sugar * float64(qty)),
+		Caffeine: n.Caffeine * qty,
+		Fat:      round1(n.Fat * float64(qty)),
+	}
+}
+
+// MergeAllergens returns the sorted union of all given allergen lists
+func MergeAllergens(lists ...[]string) []string {
+	seen := make(map[string]bool)
+	merged := []string{}
+
+	for _, list := range lists {
+		for _, allergen := range list {
+			if !seen[allergen] {
+				seen[allergen] = true
+				merged = append(merged, allergen)
+			}
+		}
+	}
+
+	sort.Strings(merged)
+	return merged
+}
+
+func round1(v float64) float64 {
+	return math.Round(v*10) / 10
+}
@@ -0,0 +1,39 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// PaymentCharge is the payment page opened at the payment gateway for a
+// transaction
+type PaymentCharge struct {
+	Token       string `json:"token"`
+	RedirectURL string `json:"redirect_url"`
+}
+
+// PaymentUpdate is a payment status reported by the payment gateway. Status
+// is empty when the transaction keeps its status. RefundKeys lists the
+// refunds the gateway has completed. GrossAmount is the amount the gateway
+// charged, zero when unknown.
+type PaymentUpdate struct {
+	TransactionId string   `json:"transaction_id"`
+	Status        string   `json:"status"`
+	RefundKeys    []string `json:"refund_keys"`
+	GrossAmount   int      `json:"gross_amount"`
+	Note          string   `json:"note"`
+}
+
+// PaymentNotification is an authenticated notification of the payment
+// gateway. DedupeKey is the same for every delivery of one notification so
+// it is applied once. ProcessedAt is nil until it is applied and Error keeps
+// the reason of the last failed attempt.
+type PaymentNotification struct {
+	Id int `json:"id"`
+	PaymentUpdate
+	DedupeKey   string          `json:"-"`
+	Payload     json.RawMessage `json:"payload"`
+	ProcessedAt *time.Time      `json:"processed_at"`
+	Error       string          `json:"error,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
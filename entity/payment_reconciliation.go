@@ -0,0 +1,66 @@
+package entity
+
+import "time"
+
+// Statuses of a payment at the provider that do not change the transaction
+const (
+	ProviderStatusPending  = "pending"
+	ProviderStatusNotFound = "not_found"
+)
+
+// PaymentActionExpire fails a transaction still unpaid after the payment
+// deadline
+const PaymentActionExpire = "expire"
+
+// PendingPayment is a transaction waiting for its payment since CreatedAt
+type PendingPayment struct {
+	TransactionId string    `db:"id" json:"transaction_id"`
+	Total         int       `db:"total" json:"total"`
+	CreatedAt     time.Time `db:"created_at" json:"created_at"`
+}
+
+// PaymentCheck compares a pending transaction with its payment at the
+// provider. Action is the status the transaction moves to, or expire, and is
+// empty when nothing changes. Mismatch reports a provider status that cannot
+// be applied.
+type PaymentCheck struct {
+	PendingPayment
+	ProviderStatus string `json:"provider_status"`
+	Action         string `json:"action,omitempty"`
+	Mismatch       bool   `json:"mismatch"`
+	Error          string `json:"error,omitempty"`
+}
+
+type PaymentReconciliation struct {
+	Since      time.Time      `json:"since"`
+	Applied    bool           `json:"applied"`
+	Checked    int            `json:"checked"`
+	Updated    int            `json:"updated"`
+	Expired    int            `json:"expired"`
+	Mismatches int            `json:"mismatches"`
+	Checks     []PaymentCheck `json:"checks"`
+}
+
+// NewPaymentReconciliation totals the checks of the transactions pending
+// since the given time
+func NewPaymentReconciliation(since time.Time, applied bool, checks []PaymentCheck) PaymentReconciliation {
+	report := PaymentReconciliation{Since: since, Applied: applied, Checks: checks}
+	if report.Checks == nil {
+		report.Checks = []PaymentCheck{}
+	}
+
+	for _, c := range report.Checks {
+		report.Checked++
+
+		switch {
+		case c.Mismatch:
+			report.Mismatches++
+		case c.Action == PaymentActionExpire:
+			report.Expired++
+		case c.Action != "":
+			report.Updated++
+		}
+	}
+
+	return report
+}
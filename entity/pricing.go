@@ -0,0 +1,138 @@
+package entity
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+)
+
+type ToppingPrice struct {
+	Id    int    `json:"id"`
+	Name  string `json:"name"`
+	Price int    `json:"price"`
+}
+
+// PriceBreakdown splits the price of a cart or order line by its components.
+// Every price is a unit price except Total.
+type PriceBreakdown struct {
+	Product  int            `json:"product"`
+	Variant  int            `json:"variant"`
+	Toppings []ToppingPrice `json:"toppings"`
+	Unit     int            `json:"unit"`
+	Qty      int            `json:"qty"`
+	Total    int            `json:"total"`
+}
+
+func NewPriceBreakdown(product int, variant int, toppings []ToppingPrice, qty int) PriceBreakdown {
+	unit := product + variant
+	for _, t := range toppings {
+		unit += t.Price
+	}
+
+	if toppings == nil {
+		toppings = []ToppingPrice{}
+	}
+
+	return PriceBreakdown{
+		Product:  product,
+		Variant:  variant,
+		Toppings: toppings,
+		Unit:     unit,
+		Qty:      qty,
+		Total:    unit * qty,
+	}
+}
+
+// ToppingIds returns the sorted ids of the priced toppings
+func (b PriceBreakdown) ToppingIds() []int64 {
+	ids := make([]int64, 0, len(b.Toppings))
+	for _, t := range b.Toppings {
+		ids = append(ids, int64(t.Id))
+	}
+
+	return NormalizeToppingIds(ids)
+}
+
+func (b PriceBreakdown) Value() (driver.Value, error) {
+	return json.Marshal(b)
+}
+
+func (b *PriceBreakdown) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, b)
+	case string:
+		return json.Unmarshal([]byte(v), b)
+	default:
+		return errors.New("price breakdown must be json")
+	}
+}
+
+// Codes of the fees charged on every transaction
+const (
+	FeeService  = "service"
+	FeeDelivery = "delivery"
+)
+
+// Fee is a charge added on top of the ordered lines
+type Fee struct {
+	Code   string `json:"code"`
+	Name   string `json:"name"`
+	Amount int    `json:"amount"`
+}
+
+// OrderBreakdown totals the lines and fees of a transaction. It is stored
+// with the transaction so receipts show what the customer was charged.
+type OrderBreakdown struct {
+	Lines    []PriceBreakdown `json:"lines"`
+	Subtotal int              `json:"subtotal"`
+	Fees     []Fee            `json:"fees"`
+	Total    int              `json:"total"`
+}
+
+func NewOrderBreakdown(lines []PriceBreakdown, fees []Fee) OrderBreakdown {
+	if lines == nil {
+		lines = []PriceBreakdown{}
+	}
+	if fees == nil {
+		fees = []Fee{}
+	}
+
+	b := OrderBreakdown{Lines: lines, Fees: fees}
+	for _, line := range lines {
+		b.Subtotal += line.Total
+	}
+
+	b.Total = b.Subtotal
+	for _, fee := range fees {
+		b.Total += fee.Amount
+	}
+
+	return b
+}
+
+// Fee returns the amount of the fee with the given code
+func (b OrderBreakdown) Fee(code string) int {
+	for _, fee := range b.Fees {
+		if fee.Code == code {
+			return fee.Amount
+		}
+	}
+
+	return 0
+}
+
+func (b OrderBreakdown) Value() (driver.Value, error) {
+	return json.Marshal(b)
+}
+
+func (b *OrderBreakdown) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, b)
+	case string:
+		return json.Unmarshal([]byte(v), b)
+	default:
+		return errors.New("order breakdown must be json")
+	}
+}
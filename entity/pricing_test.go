@@ -0,0 +1,16 @@
+package entity
+
+import "testing"
+
+func TestNewOrderBreakdown(t *testing.T) {
+	lines := []PriceBreakdown{
+		NewPriceBreakdown(30000, 5000, []ToppingPrice{{Id: 1, Name: "Boba", Price: 4000}}, 2),
+		NewPriceBreakdown(25000, 0, nil, 1),
+	}
+
+	b := NewOrderBreakdown(lines, []Fee{{Code: FeeService, Name: "Service Fee", Amount: 5000}})
+
+	if b.Subtotal != 103000 || b.Total != 108000 || b.Fee(FeeService) != 5000 || b.Fee("delivery") != 0 {
+		t.Errorf("NewOrderBreakdown() = %+v", b)
+	}
+}
@@ -1,24 +1,42 @@
 package entity
 
-import "time"
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
 
 type Product struct {
-	Id          int       `db:"id" json:"id"`
-	Name        string    `db:"name" json:"name"`
-	Description string    `db:"description" json:"description"`
-	Image       string    `db:"image" json:"image"`
-	Price       int       `db:"price" json:"price"`
-	IsAvailable bool      `db:"is_available" json:"is_available"`
-	Created_At  time.Time `db:"created_at" json:"created_at"`
-	Updated_At  time.Time `db:"updated_at" json:"updated_at"`
+	Id          int    `db:"id" json:"id"`
+	Name        string `db:"name" json:"name"`
+	Description string `db:"description" json:"description"`
+	Image       string `db:"image" json:"image"`
+	Price       int    `db:"price" json:"price"`
+	IsAvailable bool   `db:"is_available" json:"is_available"`
+	Nutrition   `json:"nutrition"`
+	Allergens   pq.StringArray `db:"allergens" json:"allergens"`
+	Rating      float64        `db:"rating" json:"rating"`
+	ReviewCount int            `db:"review_count" json:"review_count"`
+	Images      []ProductImage `json:"images,omitempty"`
+	Created_At  time.Time      `db:"created_at" json:"created_at"`
+	Updated_At  time.Time      `db:"updated_at" json:"updated_at"`
 }
 
 type ProductRequest struct {
-	Name        string `json:"name" validate:"required"`
-	Description string `json:"description" validate:"required"`
-	Image       string `json:"image" validate:"required"`
-	Price       int    `json:"price" validate:"required"`
-	IsAvailable bool   `json:"is_available"`
+	Name        string           `json:"name" validate:"required"`
+	Description string           `json:"description" validate:"required"`
+	Image       string           `json:"image" validate:"required"`
+	Price       int              `json:"price" validate:"required"`
+	IsAvailable bool             `json:"is_available"`
+	Nutrition   NutritionRequest `json:"nutrition"`
+	Allergens   []string         `json:"allergens" validate:"omitempty,dive,oneof=milk soy nuts gluten egg sesame sulfite"`
+}
+
+// ProductUpdateRequest holds the fields of a partial product or topping update
+// that are validated before the update body is written
+type ProductUpdateRequest struct {
+	Nutrition *NutritionRequest `json:"nutrition"`
+	Allergens []string          `json:"allergens" validate:"omitempty,dive,oneof=milk soy nuts gluten egg sesame sulfite"`
 }
 
 type ProductTopping struct {
@@ -27,13 +45,37 @@ type ProductTopping struct {
 	Image       string `db:"image" json:"image"`
 	Price       int    `db:"price" json:"price"`
 	IsAvailable bool   `db:"is_available" json:"is_available"`
+	Nutrition   `json:"nutrition"`
+	Allergens   pq.StringArray `db:"allergens" json:"allergens"`
+	Updated_At  time.Time      `db:"updated_at" json:"-"`
 }
 
 type ProductToppingRequest struct {
-	Name        string `json:"name" validate:"required"`
-	Image       string `json:"image" validate:"required"`
-	Price       int    `json:"price" validate:"required"`
-	IsAvailable bool   `json:"is_available"`
+	Name        string           `json:"name" validate:"required"`
+	Image       string           `json:"image" validate:"required"`
+	Price       int              `json:"price" validate:"required"`
+	IsAvailable bool             `json:"is_available"`
+	Nutrition   NutritionRequest `json:"nutrition"`
+	Allergens   []string         `json:"allergens" validate:"omitempty,dive,oneof=milk soy nuts gluten egg sesame sulfite"`
+}
+
+// ProductVariant nutrition and price are added on top of the product values
+type ProductVariant struct {
+	Id          int    `db:"id" json:"id"`
+	ProductId   int    `db:"product_id" json:"product_id"`
+	Name        string `db:"name" json:"name"`
+	Price       int    `db:"price" json:"price"`
+	IsAvailable bool   `db:"is_available" json:"is_available"`
+	Nutrition   `json:"nutrition"`
+	Allergens   pq.StringArray `db:"allergens" json:"allergens"`
+}
+
+type ProductVariantRequest struct {
+	Name        string           `json:"name" validate:"required"`
+	Price       int              `json:"price"`
+	IsAvailable bool             `json:"is_available"`
+	Nutrition   NutritionRequest `json:"nutrition"`
+	Allergens   []string         `json:"allergens" validate:"omitempty,dive,oneof=milk soy nuts gluten egg sesame sulfite"`
 }
 
 func NewProduct(req ProductRequest) Product {
@@ -43,6 +85,8 @@ func NewProduct(req ProductRequest) Product {
 		Image:       req.Image,
 		Price:       req.Price,
 		IsAvailable: req.IsAvailable,
+		Nutrition:   NewNutrition(req.Nutrition),
+		Allergens:   MergeAllergens(req.Allergens),
 	}
 }
 
@@ -52,5 +96,46 @@ func NewProductTopping(req ProductToppingRequest) ProductTopping {
 		Image:       req.Image,
 		Price:       req.Price,
 		IsAvailable: req.IsAvailable,
+		Nutrition:   NewNutrition(req.Nutrition),
+		Allergens:   MergeAllergens(req.Allergens),
+	}
+}
+
+func NewProductVariant(productID int, req ProductVariantRequest) ProductVariant {
+	return ProductVariant{
+		ProductId:   productID,
+		Name:        req.Name,
+		Price:       req.Price,
+		IsAvailable: req.IsAvailable,
+		Nutrition:   NewNutrition(req.Nutrition),
+		Allergens:   MergeAllergens(req.Allergens),
+	}
+}
+
+type ProductImage struct {
+	Id        int    `db:"id" json:"id"`
+	ProductId int    `db:"product_id" json:"-"`
+	Image     string `db:"image" json:"image"`
+	AltText   string `db:"alt_text" json:"alt_text"`
+	SortOrder int    `db:"sort_order" json:"sort_order"`
+	IsPrimary bool   `db:"is_primary" json:"is_primary"`
+}
+
+type ProductImageRequest struct {
+	Image     string `json:"image" validate:"required"`
+	AltText   string `json:"alt_text" validate:"max=255"`
+	IsPrimary bool   `json:"is_primary"`
+}
+
+type ProductImageOrderRequest struct {
+	ImageIds []int `json:"image_ids" validate:"required,min=1"`
+}
+
+func NewProductImage(productID int, req ProductImageRequest) ProductImage {
+	return ProductImage{
+		ProductId: productID,
+		Image:     req.Image,
+		AltText:   req.AltText,
+		IsPrimary: req.IsPrimary,
 	}
 }
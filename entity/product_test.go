@@ -0,0 +1,29 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/yosepalexsander/waysbucks-api/helper"
+)
+
+func TestProductUpdateRequestValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		request ProductUpdateRequest
+		valid   bool
+	}{
+		{name: "no validated fields", request: ProductUpdateRequest{}, valid: true},
+		{name: "known allergens", request: ProductUpdateRequest{Allergens: []string{AllergenMilk, AllergenNuts}}, valid: true},
+		{name: "unknown allergen", request: ProductUpdateRequest{Allergens: []string{AllergenMilk, "peanut butter"}}},
+		{name: "nutrition", request: ProductUpdateRequest{Nutrition: &NutritionRequest{Calories: 120, Sugar: 12.5}}, valid: true},
+		{name: "negative nutrition", request: ProductUpdateRequest{Nutrition: &NutritionRequest{Calories: -1}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if valid, msg := helper.Validate(tt.request); valid != tt.valid {
+				t.Errorf("Validate() = %v (%s), want %v", valid, msg, tt.valid)
+			}
+		})
+	}
+}
@@ -0,0 +1,22 @@
+package entity
+
+type RecommendedProduct struct {
+	Id    int     `db:"id" json:"id"`
+	Name  string  `db:"name" json:"name"`
+	Image string  `db:"image" json:"image"`
+	Price int     `db:"price" json:"price"`
+	Score float64 `db:"score" json:"score"`
+}
+
+type RecommendedTopping struct {
+	Id    int     `db:"id" json:"id"`
+	Name  string  `db:"name" json:"name"`
+	Image string  `db:"image" json:"image"`
+	Price int     `db:"price" json:"price"`
+	Score float64 `db:"score" json:"score"`
+}
+
+type Recommendations struct {
+	Products []RecommendedProduct `json:"products"`
+	Toppings []RecommendedTopping `json:"toppings"`
+}
@@ -0,0 +1,111 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/yosepalexsander/waysbucks-api/helper"
+)
+
+// Statuses of a refund. A refund is pending until the payment gateway
+// accepts or rejects it.
+const (
+	RefundPending   = "pending"
+	RefundSucceeded = "succeeded"
+	RefundFailed    = "failed"
+)
+
+// Refund gives back the price of some order lines of a transaction, or the
+// whole remaining amount including fees when nothing is left afterwards.
+// RefundKey identifies the refund at the payment gateway so a retry never
+// refunds twice.
+type Refund struct {
+	Id            int       `db:"id" json:"id"`
+	TransactionId string    `db:"transaction_id" json:"transaction_id"`
+	RefundKey     string    `db:"refund_key" json:"refund_key"`
+	Amount        int       `db:"amount" json:"amount"`
+	OrderIds      []int64   `db:"order_id" json:"order_ids"`
+	Reason        string    `db:"reason" json:"reason"`
+	Status        string    `db:"status" json:"status"`
+	ActorId       *string   `db:"actor_id" json:"actor_id,omitempty"`
+	CreatedAt     time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt     time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// RefundRequest refunds the given order lines, or everything not refunded
+// yet when OrderIds is empty
+type RefundRequest struct {
+	OrderIds []int64 `json:"order_ids"`
+	Reason   string  `json:"reason" validate:"required"`
+}
+
+type CancelRequest struct {
+	Reason string `json:"reason"`
+}
+
+// NewRefund plans the refund of the given order lines of t, or of every line
+// not refunded yet when orderIDs is empty. Lines of failed refunds can be
+// refunded again. It returns false when a line is unknown, already refunded
+// or when nothing is left to refund. full reports whether no line is left
+// afterwards, in which case the refund covers the fees too.
+func NewRefund(t Transaction, orderIDs []int64, refunds []Refund, reason string, actor TransactionActor) (refund Refund, full bool, ok bool) {
+	refunded := make(map[int64]bool)
+	refundedAmount := 0
+	for _, r := range refunds {
+		if r.Status == RefundFailed {
+			continue
+		}
+
+		refundedAmount += r.Amount
+		for _, id := range r.OrderIds {
+			refunded[id] = true
+		}
+	}
+
+	remaining := make(map[int64]int)
+	all := []int64{}
+	for _, o := range t.Orders {
+		if !refunded[int64(o.Id)] {
+			remaining[int64(o.Id)] = o.Price
+			all = append(all, int64(o.Id))
+		}
+	}
+
+	if len(orderIDs) == 0 {
+		orderIDs = all
+	}
+
+	amount := 0
+	for _, id := range orderIDs {
+		price, found := remaining[id]
+		if !found {
+			return Refund{}, false, false
+		}
+
+		amount += price
+		// a line listed twice is not found the second time
+		delete(remaining, id)
+	}
+
+	if len(orderIDs) == 0 {
+		return Refund{}, false, false
+	}
+
+	full = len(remaining) == 0
+	if full {
+		amount = t.Total - refundedAmount
+	}
+
+	refund = Refund{
+		TransactionId: t.Id,
+		RefundKey:     "REFUND-" + helper.RandString(20),
+		Amount:        amount,
+		OrderIds:      orderIDs,
+		Reason:        reason,
+		Status:        RefundPending,
+	}
+	if actor.UserId != "" {
+		refund.ActorId = &actor.UserId
+	}
+
+	return refund, full, true
+}
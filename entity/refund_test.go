@@ -0,0 +1,45 @@
+package entity
+
+import "testing"
+
+func TestNewRefund(t *testing.T) {
+	transaction := Transaction{
+		Id:     "ORDER-1",
+		Total:  71000,
+		Orders: []Order{{Id: 1, Price: 34000}, {Id: 2, Price: 32000}},
+	}
+	partial := Refund{Amount: 32000, OrderIds: []int64{2}, Status: RefundSucceeded}
+	failed := Refund{Amount: 32000, OrderIds: []int64{2}, Status: RefundFailed}
+
+	tests := []struct {
+		name     string
+		orderIDs []int64
+		refunds  []Refund
+		amount   int
+		full     bool
+		ok       bool
+	}{
+		{name: "everything", amount: 71000, full: true, ok: true},
+		{name: "one line", orderIDs: []int64{1}, amount: 34000, ok: true},
+		{name: "last line with fees", orderIDs: []int64{1}, refunds: []Refund{partial}, amount: 39000, full: true, ok: true},
+		{name: "rest after partial", refunds: []Refund{partial}, amount: 39000, full: true, ok: true},
+		{name: "line of a failed refund", orderIDs: []int64{2}, refunds: []Refund{failed}, amount: 32000, ok: true},
+		{name: "refunded line", orderIDs: []int64{2}, refunds: []Refund{partial}},
+		{name: "unknown line", orderIDs: []int64{3}},
+		{name: "line listed twice", orderIDs: []int64{1, 1}},
+		{name: "nothing left", refunds: []Refund{{Amount: 71000, OrderIds: []int64{1, 2}, Status: RefundPending}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			refund, full, ok := NewRefund(transaction, tt.orderIDs, tt.refunds, "reason", TransactionActor{Kind: ActorStaff, UserId: "admin"})
+			if ok != tt.ok || full != tt.full || refund.Amount != tt.amount {
+				t.Fatalf("NewRefund() = %d, %v, %v, want %d, %v, %v", refund.Amount, full, ok, tt.amount, tt.full, tt.ok)
+			}
+
+			if ok && (refund.Status != RefundPending || refund.RefundKey == "" || *refund.ActorId != "admin") {
+				t.Errorf("NewRefund() = %+v", refund)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package entity
+
+import "time"
+
+type Review struct {
+	Id         int       `db:"id" json:"id"`
+	ProductId  int       `db:"product_id" json:"product_id"`
+	UserId     string    `db:"user_id" json:"-"`
+	UserName   string    `db:"user_name" json:"user_name"`
+	Rating     int       `db:"rating" json:"rating"`
+	Comment    string    `db:"comment" json:"comment"`
+	IsHidden   bool      `db:"is_hidden" json:"is_hidden"`
+	IsFlagged  bool      `db:"is_flagged" json:"is_flagged"`
+	Created_At time.Time `db:"created_at" json:"created_at"`
+	Updated_At time.Time `db:"updated_at" json:"updated_at"`
+}
+
+type ReviewRequest struct {
+	Rating  int    `json:"rating" validate:"required,min=1,max=5"`
+	Comment string `json:"comment" validate:"max=800"`
+}
+
+type ReviewModerationRequest struct {
+	IsHidden  *bool `json:"is_hidden"`
+	IsFlagged *bool `json:"is_flagged"`
+}
+
+func NewReview(productID int, userID string, req ReviewRequest) Review {
+	return Review{
+		ProductId: productID,
+		UserId:    userID,
+		Rating:    req.Rating,
+		Comment:   req.Comment,
+	}
+}
@@ -0,0 +1,52 @@
+package entity
+
+import "time"
+
+const (
+	RevisionActionAdded   = "added"
+	RevisionActionRemoved = "removed"
+	RevisionActionChanged = "changed"
+)
+
+type ProductRevision struct {
+	Id          int       `db:"id" json:"revision_id"`
+	ProductId   int       `db:"product_id" json:"id"`
+	Name        string    `db:"name" json:"name"`
+	Description string    `db:"description" json:"description"`
+	Image       string    `db:"image" json:"image"`
+	Price       int       `db:"price" json:"price"`
+	IsAvailable bool      `db:"is_available" json:"is_available"`
+	IsDeleted   bool      `db:"is_deleted" json:"is_deleted"`
+	EffectiveAt time.Time `db:"effective_at" json:"effective_at"`
+}
+
+type ToppingRevision struct {
+	Id          int       `db:"id" json:"revision_id"`
+	ToppingId   int       `db:"topping_id" json:"id"`
+	Name        string    `db:"name" json:"name"`
+	Image       string    `db:"image" json:"image"`
+	Price       int       `db:"price" json:"price"`
+	IsAvailable bool      `db:"is_available" json:"is_available"`
+	IsDeleted   bool      `db:"is_deleted" json:"is_deleted"`
+	EffectiveAt time.Time `db:"effective_at" json:"effective_at"`
+}
+
+type CatalogSnapshot struct {
+	At       time.Time         `json:"at"`
+	Products []ProductRevision `json:"products"`
+	Toppings []ToppingRevision `json:"toppings"`
+}
+
+type CatalogDiff struct {
+	From     time.Time      `json:"from"`
+	To       time.Time      `json:"to"`
+	Products []RevisionDiff `json:"products"`
+	Toppings []RevisionDiff `json:"toppings"`
+}
+
+type RevisionDiff struct {
+	Id      int               `json:"id"`
+	Name    string            `json:"name"`
+	Action  string            `json:"action"`
+	Changes map[string]Change `json:"changes,omitempty"`
+}
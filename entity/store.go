@@ -0,0 +1,179 @@
+package entity
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+type Store struct {
+	Id           int          `db:"id" json:"id"`
+	Name         string       `db:"name" json:"name"`
+	Address      string       `db:"address" json:"address"`
+	City         string       `db:"city" json:"city"`
+	Longitude    float64      `db:"longitude" json:"longitude"`
+	Latitude     float64      `db:"latitude" json:"latitude"`
+	Timezone     string       `db:"timezone" json:"timezone"`
+	OpeningHours OpeningHours `db:"opening_hours" json:"opening_hours"`
+	IsActive     bool         `db:"is_active" json:"is_active"`
+}
+
+// OpeningHour is the opening time of a store on a weekday, 0 being Sunday.
+// Closes before Opens means the store closes after midnight.
+type OpeningHour struct {
+	Weekday int    `json:"weekday" validate:"min=0,max=6"`
+	Opens   string `json:"opens" validate:"required,datetime=15:04"`
+	Closes  string `json:"closes" validate:"required,datetime=15:04"`
+}
+
+type OpeningHours []OpeningHour
+
+type StoreRequest struct {
+	Name         string        `json:"name" validate:"required,max=100"`
+	Address      string        `json:"address" validate:"required,max=255"`
+	City         string        `json:"city" validate:"required,max=100"`
+	Longitude    float64       `json:"longitude" validate:"required"`
+	Latitude     float64       `json:"latitude" validate:"required"`
+	Timezone     string        `json:"timezone" validate:"omitempty,timezone"`
+	OpeningHours []OpeningHour `json:"opening_hours" validate:"dive"`
+	IsActive     bool          `json:"is_active"`
+}
+
+// StoreItem overrides the catalog price and availability of a product or
+// topping in a store. A nil price keeps the catalog price.
+type StoreItem struct {
+	StoreId     int  `db:"store_id" json:"store_id"`
+	ItemId      int  `db:"item_id" json:"item_id"`
+	Price       *int `db:"price" json:"price"`
+	IsAvailable bool `db:"is_available" json:"is_available"`
+}
+
+type StoreItemRequest struct {
+	Price       *int `json:"price" validate:"omitempty,min=0"`
+	IsAvailable bool `json:"is_available"`
+}
+
+type StoreMenu struct {
+	Store    Store            `json:"store"`
+	Products []Product        `json:"products"`
+	Toppings []ProductTopping `json:"toppings"`
+}
+
+func NewStore(req StoreRequest) Store {
+	timezone := req.Timezone
+	if timezone == "" {
+		timezone = "Asia/Jakarta"
+	}
+
+	return Store{
+		Name:         req.Name,
+		Address:      req.Address,
+		City:         req.City,
+		Longitude:    req.Longitude,
+		Latitude:     req.Latitude,
+		Timezone:     timezone,
+		OpeningHours: req.OpeningHours,
+		IsActive:     req.IsActive,
+	}
+}
+
+func NewStoreItem(storeID int, itemID int, req StoreItemRequest) StoreItem {
+	return StoreItem{
+		StoreId:     storeID,
+		ItemId:      itemID,
+		Price:       req.Price,
+		IsAvailable: req.IsAvailable,
+	}
+}
+
+// IsOpenAt reports whether the store is active and open at t in the store timezone
+func (s Store) IsOpenAt(t time.Time) bool {
+	if !s.IsActive {
+		return false
+	}
+
+	if loc, err := time.LoadLocation(s.Timezone); err == nil {
+		t = t.In(loc)
+	}
+
+	now := t.Format("15:04")
+	today := int(t.Weekday())
+	yesterday := (today + 6) % 7
+
+	for _, h := range s.OpeningHours {
+		overnight := h.Closes <= h.Opens
+
+		if h.Weekday == today && now >= h.Opens && (overnight || now < h.Closes) {
+			return true
+		}
+
+		if h.Weekday == yesterday && overnight && now < h.Closes {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (h OpeningHours) Value() (driver.Value, error) {
+	if h == nil {
+		return []byte("[]"), nil
+	}
+
+	return json.Marshal(h)
+}
+
+func (h *OpeningHours) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, h)
+	case string:
+		return json.Unmarshal([]byte(v), h)
+	case nil:
+		*h = OpeningHours{}
+		return nil
+	default:
+		return errors.New("opening hours must be json")
+	}
+}
+
+// ApplyStoreProducts replaces the catalog price and availability of products
+// with the store overrides. Products without an override keep catalog values.
+func ApplyStoreProducts(products []Product, items []StoreItem) {
+	overrides := storeItemsByID(items)
+
+	for i := range products {
+		if item, ok := overrides[products[i].Id]; ok {
+			products[i].Price, products[i].IsAvailable = item.apply(products[i].Price, products[i].IsAvailable)
+		}
+	}
+}
+
+// ApplyStoreToppings works like ApplyStoreProducts for toppings
+func ApplyStoreToppings(toppings []ProductTopping, items []StoreItem) {
+	overrides := storeItemsByID(items)
+
+	for i := range toppings {
+		if item, ok := overrides[toppings[i].Id]; ok {
+			toppings[i].Price, toppings[i].IsAvailable = item.apply(toppings[i].Price, toppings[i].IsAvailable)
+		}
+	}
+}
+
+func (item StoreItem) apply(price int, isAvailable bool) (int, bool) {
+	if item.Price != nil {
+		price = *item.Price
+	}
+
+	return price, isAvailable && item.IsAvailable
+}
+
+func storeItemsByID(items []StoreItem) map[int]StoreItem {
+	overrides := make(map[int]StoreItem, len(items))
+	for _, item := range items {
+		overrides[item.ItemId] = item
+	}
+
+	return overrides
+}
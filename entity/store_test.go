@@ -0,0 +1,70 @@
+package entity
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStoreIsOpenAt(t *testing.T) {
+	store := Store{
+		Timezone: "UTC",
+		IsActive: true,
+		OpeningHours: OpeningHours{
+			{Weekday: 1, Opens: "08:00", Closes: "22:00"},
+			{Weekday: 5, Opens: "18:00", Closes: "02:00"},
+		},
+	}
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want bool
+	}{
+		{name: "monday morning", at: time.Date(2023, 5, 1, 9, 30, 0, 0, time.UTC), want: true},
+		{name: "monday at closing", at: time.Date(2023, 5, 1, 22, 0, 0, 0, time.UTC), want: false},
+		{name: "monday before opening", at: time.Date(2023, 5, 1, 7, 59, 0, 0, time.UTC), want: false},
+		{name: "tuesday", at: time.Date(2023, 5, 2, 9, 30, 0, 0, time.UTC), want: false},
+		{name: "friday night", at: time.Date(2023, 5, 5, 23, 0, 0, 0, time.UTC), want: true},
+		{name: "after midnight on saturday", at: time.Date(2023, 5, 6, 1, 0, 0, 0, time.UTC), want: true},
+		{name: "saturday morning", at: time.Date(2023, 5, 6, 3, 0, 0, 0, time.UTC), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := store.IsOpenAt(tt.at); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	store.IsActive = false
+	if store.IsOpenAt(time.Date(2023, 5, 1, 9, 30, 0, 0, time.UTC)) {
+		t.Error("expected inactive store to be closed")
+	}
+}
+
+func TestApplyStoreProducts(t *testing.T) {
+	price := 28000
+	products := []Product{
+		{Id: 1, Price: 30000, IsAvailable: true},
+		{Id: 2, Price: 32000, IsAvailable: true},
+		{Id: 3, Price: 35000, IsAvailable: false},
+	}
+
+	ApplyStoreProducts(products, []StoreItem{
+		{ItemId: 1, Price: &price, IsAvailable: true},
+		{ItemId: 2, IsAvailable: false},
+		{ItemId: 3, IsAvailable: true},
+	})
+
+	want := []struct {
+		price       int
+		isAvailable bool
+	}{{28000, true}, {32000, false}, {35000, false}}
+
+	for i, w := range want {
+		if products[i].Price != w.price || products[i].IsAvailable != w.isAvailable {
+			t.Errorf("product %d: expected %d/%v, got %d/%v", products[i].Id, w.price, w.isAvailable, products[i].Price, products[i].IsAvailable)
+		}
+	}
+}
@@ -3,18 +3,27 @@ package entity
 import "github.com/yosepalexsander/waysbucks-api/helper"
 
 type Transaction struct {
-	Id         string  `db:"id" json:"id"`
-	Name       string  `db:"name" json:"name"`
-	Email      string  `json:"email,omitempty"`
-	Phone      string  `db:"phone" json:"phone"`
-	Address    string  `db:"address" json:"address"`
-	City       string  `db:"city" json:"city"`
-	PostalCode int     `db:"postal_code" json:"postal_code"`
-	Total      int     `db:"total" json:"total"`
-	ServiceFee int     `json:"service_fee"`
-	Status     string  `db:"status" json:"status"`
-	UserId     string  `db:"user_id" json:"-"`
-	Orders     []Order `json:"orders"`
+	Id          string             `db:"id" json:"id"`
+	Name        string             `db:"name" json:"name"`
+	Email       string             `json:"email,omitempty"`
+	Phone       string             `db:"phone" json:"phone"`
+	Address     string             `db:"address" json:"address"`
+	City        string             `db:"city" json:"city"`
+	PostalCode  int                `db:"postal_code" json:"postal_code"`
+	AddressId   *string            `db:"address_id" json:"address_id,omitempty"`
+	Longitude   *float64           `db:"longitude" json:"longitude,omitempty"`
+	Latitude    *float64           `db:"latitude" json:"latitude,omitempty"`
+	Total       int                `db:"total" json:"total"`
+	ServiceFee  int                `json:"service_fee"`
+	DeliveryFee int                `json:"delivery_fee"`
+	Status      string             `db:"status" json:"status"`
+	StoreId     *int               `db:"store_id" json:"store_id"`
+	UserId      string             `db:"user_id" json:"-"`
+	Breakdown   *OrderBreakdown    `db:"price_breakdown" json:"price_breakdown"`
+	Orders      []Order            `json:"orders"`
+	Timeline    []TransactionEvent `json:"timeline,omitempty"`
+	Refunds     []Refund           `json:"refunds,omitempty"`
+	Payment     *PaymentCharge     `json:"payment,omitempty"`
 }
 
 type Order struct {
@@ -22,7 +31,9 @@ type Order struct {
 	Price         int     `db:"price" json:"price"`
 	Qty           int     `db:"qty" json:"qty"`
 	ProductId     int     `db:"product_id" json:"product_id,omitempty"`
-	ToppingIds    []int64 `db:"topping_id" json:"topping_id,omitempty"`
+	VariantId     *int    `db:"variant_id" json:"variant_id,omitempty"`
+	VariantName   string  `db:"variant_name" json:"variant_name,omitempty"`
+	ToppingIds    []int64 `db:"topping_id" json:"topping_ids,omitempty"`
 	TransactionId string  `db:"transaction_id" json:"-"`
 	OrderProduct
 	Toppings []OrderTopping `json:"toppings"`
@@ -44,56 +55,96 @@ type OrderTopping struct {
 }
 
 type OrderRequest struct {
-	Qty        int     `json:"qty" validate:"required"`
-	Price      int     `json:"price" validate:"required"`
+	Qty        int     `json:"qty" validate:"required,min=1,max=20"`
+	Price      int     `json:"price"` // optional, must match the server price
 	ProductId  int     `json:"product_id" validate:"required"`
+	VariantId  *int    `json:"variant_id"`
 	ToppingIds []int64 `json:"topping_id"`
 }
 
+// TransactionRequest delivers to the saved address of AddressId, or to the
+// address typed in the request when AddressId is empty. The coordinates of
+// a typed address are required to price the delivery, since addresses are
+// not geocoded.
 type TransactionRequest struct {
-	Email      string         `json:"email" validate:"required"`
-	Name       string         `json:"name" validate:"required"`
-	Address    string         `json:"address" validate:"required"`
-	City       string         `json:"city" validate:"required"`
-	Phone      string         `json:"phone" validate:"required"`
-	ServiceFee int            `json:"service_fee" validate:"required"`
-	PostalCode int            `json:"postal_code" validate:"required"`
-	Total      int            `json:"total" validate:"required"`
-	Status     string         `json:"status" validate:"required"`
-	Order      []OrderRequest `json:"orders" validate:"required"`
-	UserId     string
+	Email       string         `json:"email" validate:"required"`
+	AddressId   string         `json:"address_id"`
+	Name        string         `json:"name" validate:"required_without=AddressId"`
+	Address     string         `json:"address" validate:"required_without=AddressId"`
+	City        string         `json:"city" validate:"required_without=AddressId"`
+	Phone       string         `json:"phone" validate:"required_without=AddressId"`
+	PostalCode  int            `json:"postal_code" validate:"required_without=AddressId"`
+	Longitude   *float64       `json:"longitude" validate:"required_without=AddressId"`
+	Latitude    *float64       `json:"latitude" validate:"required_without=AddressId"`
+	ServiceFee  int            `json:"service_fee"`  // optional, must match the server fee
+	DeliveryFee int            `json:"delivery_fee"` // optional, must match the server fee
+	Total       int            `json:"total"`        // optional, must match the server total
+	StoreId     int            `json:"store_id" validate:"required"`
+	Order       []OrderRequest `json:"orders" validate:"required,min=1,dive"`
+	UserId      string
 }
 
-func NewTransaction(r TransactionRequest) TransactionTxParams {
+// UseAddress replaces the delivery details of the request with a copy of the
+// saved address, so later edits of the address do not change the order
+func (r *TransactionRequest) UseAddress(a Address) {
+	r.AddressId = a.Id
+	r.Name = a.Name
+	r.Phone = a.Phone
+	r.Address = a.Address
+	r.City = a.City
+	r.PostalCode = int(a.PostalCode)
+	r.Longitude = a.Longitude
+	r.Latitude = a.Latitude
+}
+
+// NewTransaction builds the transaction from the request with the prices,
+// fees and total of the server breakdown, whose lines follow r.Order
+func NewTransaction(r TransactionRequest, breakdown OrderBreakdown) TransactionTxParams {
 	var orders []Order
+	var addressID *string
+	if r.AddressId != "" {
+		addressID = &r.AddressId
+	}
 
-	for _, v := range r.Order {
-		orders = append(orders, newOrder(v))
+	for i, v := range r.Order {
+		orders = append(orders, newOrder(v, breakdown.Lines[i]))
 	}
 
 	return TransactionTxParams{
 		Transaction: Transaction{
-			Id:         "ORDER-" + helper.RandString(20),
-			UserId:     r.UserId,
-			Name:       r.Name,
-			Email:      r.Email,
-			Address:    r.Address,
-			City:       r.City,
-			PostalCode: r.PostalCode,
-			Phone:      r.Phone,
-			Total:      r.Total,
-			ServiceFee: r.ServiceFee,
-			Status:     r.Status,
+			Id:          "ORDER-" + helper.RandString(20),
+			UserId:      r.UserId,
+			Name:        r.Name,
+			Email:       r.Email,
+			Address:     r.Address,
+			City:        r.City,
+			PostalCode:  r.PostalCode,
+			Phone:       r.Phone,
+			AddressId:   addressID,
+			Longitude:   r.Longitude,
+			Latitude:    r.Latitude,
+			Total:       breakdown.Total,
+			ServiceFee:  breakdown.Fee(FeeService),
+			DeliveryFee: breakdown.Fee(FeeDelivery),
+			Breakdown:   &breakdown,
+			Status:      StatusPendingPayment,
+			StoreId:     &r.StoreId,
 		},
 		Order: orders,
 	}
 }
 
-func newOrder(r OrderRequest) Order {
+func newOrder(r OrderRequest, line PriceBreakdown) Order {
 	return Order{
 		ProductId:  r.ProductId,
+		VariantId:  r.VariantId,
 		Qty:        r.Qty,
-		Price:      r.Price,
-		ToppingIds: r.ToppingIds,
+		Price:      line.Total,
+		ToppingIds: NormalizeToppingIds(r.ToppingIds),
 	}
 }
+
+type TransactionStatusRequest struct {
+	Status string `json:"status" validate:"required"`
+	Note   string `json:"note"`
+}
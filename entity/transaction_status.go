@@ -0,0 +1,102 @@
+package entity
+
+import "time"
+
+// Statuses of a transaction. A transaction starts as pending payment and
+// moves forward along TransactionTransitions only.
+const (
+	StatusPendingPayment = "pending_payment"
+	StatusPaid           = "paid"
+	StatusPreparing      = "preparing"
+	StatusReady          = "ready"
+	StatusCompleted      = "completed"
+	StatusCancelled      = "cancelled"
+	StatusRefunded       = "refunded"
+	StatusFailed         = "failed"
+)
+
+// TransactionTransitions lists the statuses each status can move to.
+// Cancelled, refunded and failed are final.
+var TransactionTransitions = map[string][]string{
+	StatusPendingPayment: {StatusPaid, StatusCancelled, StatusFailed},
+	StatusPaid:           {StatusPreparing, StatusCancelled, StatusRefunded},
+	StatusPreparing:      {StatusReady, StatusRefunded},
+	StatusReady:          {StatusCompleted, StatusRefunded},
+	StatusCompleted:      {StatusRefunded},
+}
+
+// PaidStatuses are the statuses of transactions the customer has paid for
+// and that were not refunded
+var PaidStatuses = []string{StatusPaid, StatusPreparing, StatusReady, StatusCompleted}
+
+// StaffStatuses are the statuses store staff advance orders to
+var StaffStatuses = []string{StatusPreparing, StatusReady, StatusCompleted}
+
+// Actors of a status change
+const (
+	ActorSystem   = "system"
+	ActorCustomer = "customer"
+	ActorStaff    = "staff"
+	ActorPayment  = "payment"
+)
+
+// TransactionActor is who changed the status. UserId is empty for the system
+// and the payment gateway.
+type TransactionActor struct {
+	Kind   string
+	UserId string
+}
+
+// TransactionEvent is a status change in the timeline of a transaction
+type TransactionEvent struct {
+	Id            int       `db:"id" json:"id"`
+	TransactionId string    `db:"transaction_id" json:"-"`
+	FromStatus    *string   `db:"from_status" json:"from_status"`
+	ToStatus      string    `db:"to_status" json:"to_status"`
+	Actor         string    `db:"actor" json:"actor"`
+	ActorId       *string   `db:"actor_id" json:"actor_id,omitempty"`
+	Note          string    `db:"note" json:"note,omitempty"`
+	CreatedAt     time.Time `db:"created_at" json:"created_at"`
+}
+
+// CanTransition reports whether a transaction can move from one status to
+// the other
+func CanTransition(from string, to string) bool {
+	return containsStatus(TransactionTransitions[from], to)
+}
+
+func IsPaidStatus(status string) bool {
+	return containsStatus(PaidStatuses, status)
+}
+
+func IsStaffStatus(status string) bool {
+	return containsStatus(StaffStatuses, status)
+}
+
+func NewTransactionEvent(transactionID string, from string, to string, actor TransactionActor, note string) TransactionEvent {
+	event := TransactionEvent{
+		TransactionId: transactionID,
+		ToStatus:      to,
+		Actor:         actor.Kind,
+		Note:          note,
+	}
+
+	if from != "" {
+		event.FromStatus = &from
+	}
+	if actor.UserId != "" {
+		event.ActorId = &actor.UserId
+	}
+
+	return event
+}
+
+func containsStatus(statuses []string, status string) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+
+	return false
+}
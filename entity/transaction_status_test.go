@@ -0,0 +1,28 @@
+package entity
+
+import "testing"
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from string
+		to   string
+		want bool
+	}{
+		{from: StatusPendingPayment, to: StatusPaid, want: true},
+		{from: StatusPendingPayment, to: StatusPreparing, want: false},
+		{from: StatusPaid, to: StatusPreparing, want: true},
+		{from: StatusPreparing, to: StatusReady, want: true},
+		{from: StatusReady, to: StatusCompleted, want: true},
+		{from: StatusReady, to: StatusPreparing, want: false},
+		{from: StatusCompleted, to: StatusRefunded, want: true},
+		{from: StatusPaid, to: StatusPaid, want: false},
+		{from: StatusFailed, to: StatusPaid, want: false},
+		{from: StatusCancelled, to: StatusRefunded, want: false},
+	}
+
+	for _, tt := range tests {
+		if got := CanTransition(tt.from, tt.to); got != tt.want {
+			t.Errorf("CanTransition(%s, %s) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
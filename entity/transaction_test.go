@@ -0,0 +1,61 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/yosepalexsander/waysbucks-api/helper"
+)
+
+func TestTransactionRequestValidation(t *testing.T) {
+	line := OrderRequest{Qty: 1, Price: 25000, ProductId: 1}
+
+	tests := []struct {
+		name  string
+		order []OrderRequest
+		valid bool
+	}{
+		{name: "positive qty", order: []OrderRequest{line}, valid: true},
+		{name: "no price", order: []OrderRequest{{Qty: 1, ProductId: 1}}, valid: true},
+		{name: "no lines", order: []OrderRequest{}},
+		{name: "zero qty", order: []OrderRequest{line, {Qty: 0, Price: 0, ProductId: 1}}},
+		{name: "negative qty", order: []OrderRequest{line, {Qty: -2, Price: -50000, ProductId: 1}}},
+		{name: "qty over the cart limit", order: []OrderRequest{{Qty: MaxCartQty + 1, Price: 25000, ProductId: 1}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := TransactionRequest{Email: "budi@mail.com", AddressId: "home", StoreId: 1, Order: tt.order}
+			if valid, msg := helper.Validate(request); valid != tt.valid {
+				t.Errorf("Validate() = %v (%s), want %v", valid, msg, tt.valid)
+			}
+		})
+	}
+}
+
+func TestTransactionRequestAddressValidation(t *testing.T) {
+	latitude, longitude := -6.2297, 106.8087
+	order := []OrderRequest{{Qty: 1, Price: 25000, ProductId: 1}}
+	typed := TransactionRequest{Email: "budi@mail.com", Name: "Budi", Phone: "0812", Address: "Jl. Senopati 2", City: "Jakarta", PostalCode: 12190,
+		StoreId: 1, Order: order}
+
+	located := typed
+	located.Latitude, located.Longitude = &latitude, &longitude
+
+	tests := []struct {
+		name    string
+		request TransactionRequest
+		valid   bool
+	}{
+		{name: "saved address", request: TransactionRequest{Email: "budi@mail.com", AddressId: "home", StoreId: 1, Order: order}, valid: true},
+		{name: "typed address with coordinates", request: located, valid: true},
+		{name: "typed address without coordinates", request: typed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if valid, msg := helper.Validate(tt.request); valid != tt.valid {
+				t.Errorf("Validate() = %v (%s), want %v", valid, msg, tt.valid)
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package entity
+
+type Translation struct {
+	Id          int    `db:"id" json:"-"`
+	Locale      string `db:"locale" json:"locale"`
+	Name        string `db:"name" json:"name"`
+	Description string `db:"description" json:"description,omitempty"`
+}
+
+type TranslationRequest struct {
+	Name        string `json:"name" validate:"required,max=100"`
+	Description string `json:"description" validate:"max=800"`
+}
+
+func NewTranslation(locale string, req TranslationRequest) Translation {
+	return Translation{
+		Locale:      locale,
+		Name:        req.Name,
+		Description: req.Description,
+	}
+}
@@ -12,15 +12,15 @@ type User struct {
 }
 
 type Address struct {
-	Id         string  `db:"id" json:"id"`
-	Name       string  `db:"name" json:"name"`
-	Phone      string  `db:"phone" json:"phone"`
-	Address    string  `db:"address" json:"address"`
-	City       string  `db:"city" json:"city"`
-	PostalCode uint16  `db:"postal_code" json:"postal_code"`
-	Longitude  float64 `db:"longitude" json:"longitude"`
-	Latitude   float64 `db:"latitude" json:"latitude"`
-	UserId     string  `db:"user_id" json:"-"`
+	Id         string   `db:"id" json:"id"`
+	Name       string   `db:"name" json:"name"`
+	Phone      string   `db:"phone" json:"phone"`
+	Address    string   `db:"address" json:"address"`
+	City       string   `db:"city" json:"city"`
+	PostalCode uint16   `db:"postal_code" json:"postal_code"`
+	Longitude  *float64 `db:"longitude" json:"longitude"`
+	Latitude   *float64 `db:"latitude" json:"latitude"`
+	UserId     string   `db:"user_id" json:"-"`
 }
 
 type AddressRequest struct {
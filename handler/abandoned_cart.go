@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/yosepalexsander/waysbucks-api/entity"
+	"github.com/yosepalexsander/waysbucks-api/usecase"
+)
+
+type AbandonedCartHandler struct {
+	AbandonedCartUseCase usecase.AbandonedCartUseCase
+}
+
+func NewAbandonedCartHandler(u usecase.AbandonedCartUseCase) AbandonedCartHandler {
+	return AbandonedCartHandler{u}
+}
+
+// FindAbandonedCarts reports the abandoned carts and their value. The
+// older_than query param overrides the reminder delay, e.g. older_than=72h.
+func (s *AbandonedCartHandler) FindAbandonedCarts(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		commonResponse
+		Payload *entity.AbandonedCartReport `json:"payload"`
+	}
+
+	var olderThan time.Duration
+	if value := r.URL.Query().Get("older_than"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			badRequest(w, "older_than must be a positive duration such as 48h")
+			return
+		}
+		olderThan = d
+	}
+
+	report, err := s.AbandonedCartUseCase.FindAbandonedCarts(r.Context(), time.Now(), olderThan)
+	if err != nil {
+		internalServerError(w)
+		return
+	}
+
+	resp, _ := json.Marshal(response{
+		commonResponse: commonResponse{
+			Message: "resource has successfully get",
+		},
+		Payload: report,
+	})
+
+	responseOK(w, resp)
+}
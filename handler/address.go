@@ -85,7 +85,7 @@ func (s *AddressHandler) CreateAddress(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if isValid, msg := helper.Validate(body); !isValid {
+	if isValid, msg := helper.ValidateLocale(body, requestLocale(r)); !isValid {
 		badRequest(w, msg)
 		return
 	}
@@ -1,11 +1,13 @@
 package handler
 
 import (
+	"database/sql"
 	"encoding/json"
 	"net/http"
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 	"github.com/yosepalexsander/waysbucks-api/entity"
 	"github.com/yosepalexsander/waysbucks-api/helper"
 	"github.com/yosepalexsander/waysbucks-api/middleware"
@@ -20,6 +22,40 @@ func NewCartHandler(u usecase.CartUseCase) CartHandler {
 	return CartHandler{u}
 }
 
+// CreateGuestCart issues a cart token so visitors can keep a cart before they
+// sign in. The cart is merged into the user cart when the token is sent along
+// with the login or register request.
+func (s *CartHandler) CreateGuestCart(w http.ResponseWriter, r *http.Request) {
+	type ResponsePayload struct {
+		CartToken string `json:"cart_token"`
+	}
+	type response struct {
+		commonResponse
+		Payload ResponsePayload `json:"payload"`
+	}
+
+	guestID, err := uuid.NewRandom()
+	if err != nil {
+		internalServerError(w)
+		return
+	}
+
+	token, err := helper.GenerateGuestToken(guestID.String())
+	if err != nil {
+		internalServerError(w)
+		return
+	}
+
+	resp, _ := json.Marshal(response{
+		commonResponse: commonResponse{
+			Message: "resource has successfully created",
+		},
+		Payload: ResponsePayload{CartToken: token},
+	})
+
+	responseOK(w, resp)
+}
+
 func (s *CartHandler) FindCarts(w http.ResponseWriter, r *http.Request) {
 	type response struct {
 		commonResponse
@@ -27,14 +63,14 @@ func (s *CartHandler) FindCarts(w http.ResponseWriter, r *http.Request) {
 	}
 
 	ctx := r.Context()
-	claims, ok := ctx.Value(middleware.TokenCtxKey).(*helper.MyClaims)
+	owner, ok := cartOwner(r)
 
 	if !ok {
 		w.WriteHeader(http.StatusUnprocessableEntity)
 		return
 	}
 
-	carts, err := s.CartUseCase.FindCarts(ctx, claims.UserID)
+	carts, err := s.CartUseCase.FindCarts(ctx, owner, requestLocale(r))
 	if err != nil {
 		internalServerError(w)
 		return
@@ -52,7 +88,7 @@ func (s *CartHandler) FindCarts(w http.ResponseWriter, r *http.Request) {
 
 func (s *CartHandler) CreateCart(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	claims, ok := ctx.Value(middleware.TokenCtxKey).(*helper.MyClaims)
+	owner, ok := cartOwner(r)
 
 	if !ok {
 		w.WriteHeader(http.StatusUnprocessableEntity)
@@ -66,14 +102,19 @@ func (s *CartHandler) CreateCart(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if valid, msg := helper.Validate(body); !valid {
+	if valid, msg := helper.ValidateLocale(body, requestLocale(r)); !valid {
 		badRequest(w, msg)
 		return
 	}
 
-	err := s.CartUseCase.SaveCart(ctx, body, claims.UserID)
+	err := s.CartUseCase.SaveCart(ctx, body, owner)
 	if err != nil {
-		internalServerError(w)
+		switch err {
+		case usecase.ErrProductUnavailable, usecase.ErrVariantUnavailable, usecase.ErrToppingUnavailable, usecase.ErrStoreUnavailable, usecase.ErrPriceMismatch, usecase.ErrCartQtyExceeded, usecase.ErrInvalidQty:
+			badRequest(w, err.Error())
+		default:
+			internalServerError(w)
+		}
 		return
 	}
 
@@ -84,24 +125,57 @@ func (s *CartHandler) CreateCart(w http.ResponseWriter, r *http.Request) {
 	responseOK(w, resBody)
 }
 
-func (s *CartHandler) UpdateCart(w http.ResponseWriter, r *http.Request) {
+func (s *CartHandler) UpdateCartQty(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	cartID, _ := strconv.Atoi(chi.URLParam(r, "cartID"))
-	claims, ok := ctx.Value(middleware.TokenCtxKey).(*helper.MyClaims)
+	owner, ok := cartOwner(r)
 
 	if !ok {
 		w.WriteHeader(http.StatusUnprocessableEntity)
 		return
 	}
 
-	body := make(map[string]interface{})
+	body := entity.CartQtyRequest{}
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 		badRequest(w, "invalid request")
 		return
 	}
 
-	if err := s.CartUseCase.UpdateCart(ctx, cartID, claims.UserID, body); err != nil {
-		internalServerError(w)
+	if valid, msg := helper.ValidateLocale(body, requestLocale(r)); !valid {
+		badRequest(w, msg)
+		return
+	}
+
+	if err := s.CartUseCase.UpdateCartQty(ctx, cartID, owner, body.Qty); err != nil {
+		cartUpdateError(w, err)
+		return
+	}
+
+	resBody, _ := json.Marshal(commonResponse{
+		Message: "resource has successfully updated",
+	})
+
+	responseOK(w, resBody)
+}
+
+func (s *CartHandler) UpdateCartToppings(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	cartID, _ := strconv.Atoi(chi.URLParam(r, "cartID"))
+	owner, ok := cartOwner(r)
+
+	if !ok {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		return
+	}
+
+	body := entity.CartToppingsRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		badRequest(w, "invalid request")
+		return
+	}
+
+	if err := s.CartUseCase.UpdateCartToppings(ctx, cartID, owner, body.ToppingIds); err != nil {
+		cartUpdateError(w, err)
 		return
 	}
 
@@ -112,17 +186,80 @@ func (s *CartHandler) UpdateCart(w http.ResponseWriter, r *http.Request) {
 	responseOK(w, resBody)
 }
 
+func (s *CartHandler) ValidateCart(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		commonResponse
+		Payload *entity.CartValidation `json:"payload"`
+	}
+
+	ctx := r.Context()
+	owner, ok := cartOwner(r)
+
+	if !ok {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		return
+	}
+
+	apply, _ := strconv.ParseBool(r.URL.Query().Get("apply"))
+
+	validation, err := s.CartUseCase.ValidateCart(ctx, owner, apply)
+	if err != nil {
+		internalServerError(w)
+		return
+	}
+
+	resp, _ := json.Marshal(response{
+		commonResponse: commonResponse{
+			Message: "resource has successfully get",
+		},
+		Payload: validation,
+	})
+
+	responseOK(w, resp)
+}
+
+// cartChanged answers a checkout of lines that drifted from the catalog with
+// the validation report so the client can refresh the cart
+func cartChanged(w http.ResponseWriter, err *usecase.CartChangedError) {
+	type response struct {
+		commonResponse
+		Payload *entity.CartValidation `json:"payload"`
+	}
+
+	resp, _ := json.Marshal(response{
+		commonResponse: commonResponse{
+			Error:   true,
+			Message: err.Error(),
+		},
+		Payload: err.Validation,
+	})
+
+	conflict(w, resp)
+}
+
+func cartUpdateError(w http.ResponseWriter, err error) {
+	switch err {
+	case sql.ErrNoRows:
+		notFound(w)
+	case usecase.ErrProductUnavailable, usecase.ErrVariantUnavailable, usecase.ErrToppingUnavailable,
+		usecase.ErrStoreUnavailable, usecase.ErrCartQtyExceeded, usecase.ErrInvalidQty:
+		badRequest(w, err.Error())
+	default:
+		internalServerError(w)
+	}
+}
+
 func (s CartHandler) DeleteCart(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	cartID, _ := strconv.Atoi(chi.URLParam(r, "cartID"))
-	claims, ok := ctx.Value(middleware.TokenCtxKey).(*helper.MyClaims)
+	owner, ok := cartOwner(r)
 
 	if !ok {
 		w.WriteHeader(http.StatusUnprocessableEntity)
 		return
 	}
 
-	if err := s.CartUseCase.DeleteCart(ctx, cartID, claims.UserID); err != nil {
+	if err := s.CartUseCase.DeleteCart(ctx, cartID, owner); err != nil {
 		internalServerError(w)
 		return
 	}
@@ -133,3 +270,17 @@ func (s CartHandler) DeleteCart(w http.ResponseWriter, r *http.Request) {
 
 	responseOK(w, resBody)
 }
+
+// cartOwner returns the owner of the cart from the user token or, for guests,
+// from the cart token
+func cartOwner(r *http.Request) (entity.CartOwner, bool) {
+	if claims, ok := r.Context().Value(middleware.TokenCtxKey).(*helper.MyClaims); ok {
+		return entity.CartOwner{UserId: claims.UserID}, true
+	}
+
+	if guestID, ok := r.Context().Value(middleware.GuestCtxKey).(string); ok {
+		return entity.CartOwner{GuestId: guestID}, true
+	}
+
+	return entity.CartOwner{}, false
+}
@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/yosepalexsander/waysbucks-api/entity"
+	"github.com/yosepalexsander/waysbucks-api/usecase"
+)
+
+func (s *ProductHandler) ExportProducts(w http.ResponseWriter, r *http.Request) {
+	exportCatalog(w, r, "products.csv", s.ProductUseCase.ExportProducts)
+}
+
+func (s *ProductHandler) ExportToppings(w http.ResponseWriter, r *http.Request) {
+	exportCatalog(w, r, "toppings.csv", s.ProductUseCase.ExportToppings)
+}
+
+type exportFunc func(ctx context.Context, w io.Writer) error
+
+// exportCatalog buffers the csv so a failed export still gets a json error
+// instead of a truncated file
+func exportCatalog(w http.ResponseWriter, r *http.Request, filename string, fn exportFunc) {
+	var buf bytes.Buffer
+	if err := fn(r.Context(), &buf); err != nil {
+		internalServerError(w)
+		return
+	}
+
+	w.Header().Add("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Add("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.Write(buf.Bytes())
+}
+
+func (s *ProductHandler) ImportProducts(w http.ResponseWriter, r *http.Request) {
+	s.importCatalog(w, r, s.ProductUseCase.ImportProducts)
+}
+
+func (s *ProductHandler) ImportToppings(w http.ResponseWriter, r *http.Request) {
+	s.importCatalog(w, r, s.ProductUseCase.ImportToppings)
+}
+
+type importFunc func(ctx context.Context, r io.Reader, dryRun bool) (*entity.ImportReport, error)
+
+// importCatalog reads the csv either from the "file" field of a multipart form
+// or from the raw request body. Imports are dry runs unless dry_run=false.
+func (s *ProductHandler) importCatalog(w http.ResponseWriter, r *http.Request, fn importFunc) {
+	type response struct {
+		commonResponse
+		Payload *entity.ImportReport `json:"payload"`
+	}
+
+	dryRun := true
+	if value := r.URL.Query().Get("dry_run"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			badRequest(w, "dry_run must be true or false")
+			return
+		}
+		dryRun = parsed
+	}
+
+	var body io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := r.ParseMultipartForm(5 << 20); err != nil {
+			badRequest(w, err.Error())
+			return
+		}
+
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			badRequest(w, err.Error())
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	report, err := fn(r.Context(), body, dryRun)
+	if err != nil {
+		if err == usecase.ErrInvalidCSV {
+			badRequest(w, err.Error())
+			return
+		}
+		internalServerError(w)
+		return
+	}
+
+	message := "import has successfully applied"
+	if report.DryRun {
+		message = "import has successfully checked"
+	} else if !report.Applied {
+		message = "import has invalid rows, nothing was applied"
+	}
+
+	resp, _ := json.Marshal(response{
+		commonResponse: commonResponse{
+			Error:   report.Failed > 0,
+			Message: message,
+		},
+		Payload: report,
+	})
+
+	responseOK(w, resp)
+}
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestExportCatalog(t *testing.T) {
+	tests := []struct {
+		name            string
+		fn              exportFunc
+		wantStatus      int
+		wantContentType string
+		wantBody        string
+	}{
+		{
+			name: "complete export",
+			fn: func(ctx context.Context, w io.Writer) error {
+				_, err := io.WriteString(w, "id,name\n1,Latte\n")
+				return err
+			},
+			wantStatus:      http.StatusOK,
+			wantContentType: "text/csv; charset=utf-8",
+			wantBody:        "id,name\n1,Latte\n",
+		},
+		{
+			name: "failure after the header row",
+			fn: func(ctx context.Context, w io.Writer) error {
+				io.WriteString(w, "id,name\n")
+				return errors.New("connection reset")
+			},
+			wantStatus:      http.StatusInternalServerError,
+			wantContentType: "application/json; charset=utf-8",
+			wantBody:        `{"error":true,"message":"server error"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			exportCatalog(rec, httptest.NewRequest(http.MethodGet, "/products/export", nil), "products.csv", tt.fn)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+
+			if got := rec.Header().Get("Content-Type"); got != tt.wantContentType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.wantContentType)
+			}
+
+			if got := strings.TrimSpace(rec.Body.String()); got != strings.TrimSpace(tt.wantBody) {
+				t.Errorf("body = %q, want %q", got, tt.wantBody)
+			}
+		})
+	}
+}
@@ -1,8 +1,15 @@
 package handler
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"net/http"
+	"strings"
+	"time"
+
+	"github.com/yosepalexsander/waysbucks-api/helper"
+	"github.com/yosepalexsander/waysbucks-api/middleware"
 )
 
 type commonResponse struct {
@@ -47,6 +54,48 @@ func responseOK(w http.ResponseWriter, resp []byte) {
 	w.Write(resp)
 }
 
+// responseCacheable writes resp with a strong ETag and Last-Modified and answers
+// conditional requests with 304 Not Modified
+func responseCacheable(w http.ResponseWriter, r *http.Request, resp []byte, lastModified time.Time) {
+	sum := sha256.Sum256(resp)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	if !lastModified.IsZero() {
+		w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if notModified(r, etag, lastModified) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	responseOK(w, resp)
+}
+
+// notModified reports whether the client copy is still fresh. If-None-Match
+// takes precedence over If-Modified-Since as in RFC 7232.
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		for _, tag := range strings.Split(match, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if tag == "*" || tag == etag {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil || lastModified.IsZero() {
+		return false
+	}
+
+	return !lastModified.After(since)
+}
+
 func badRequest(w http.ResponseWriter, msg string) {
 	resp, _ := json.Marshal(commonResponse{
 		Error:   true,
@@ -57,6 +106,12 @@ func badRequest(w http.ResponseWriter, msg string) {
 	w.Write(resp)
 }
 
+func conflict(w http.ResponseWriter, resp []byte) {
+	w.Header().Add("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusConflict)
+	w.Write(resp)
+}
+
 func serviceUnavailable(w http.ResponseWriter, msg string) {
 	resp, _ := json.Marshal(commonResponse{
 		Message: msg,
@@ -65,3 +120,12 @@ func serviceUnavailable(w http.ResponseWriter, msg string) {
 	w.WriteHeader(http.StatusServiceUnavailable)
 	w.Write(resp)
 }
+
+// requestLocale returns the locale negotiated by the Locale middleware
+func requestLocale(r *http.Request) string {
+	if locale, ok := r.Context().Value(middleware.LocaleCtxKey).(string); ok {
+		return locale
+	}
+
+	return helper.DefaultLocale
+}
@@ -0,0 +1,221 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/yosepalexsander/waysbucks-api/entity"
+	"github.com/yosepalexsander/waysbucks-api/helper"
+	"github.com/yosepalexsander/waysbucks-api/middleware"
+	"github.com/yosepalexsander/waysbucks-api/usecase"
+)
+
+type FavoriteHandler struct {
+	FavoriteUseCase usecase.FavoriteUseCase
+}
+
+func NewFavoriteHandler(u usecase.FavoriteUseCase) FavoriteHandler {
+	return FavoriteHandler{u}
+}
+
+func (s *FavoriteHandler) FindFavorites(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		commonResponse
+		Payload []entity.Product `json:"payload"`
+	}
+
+	ctx := r.Context()
+	claims, ok := ctx.Value(middleware.TokenCtxKey).(*helper.MyClaims)
+
+	if !ok {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		return
+	}
+
+	products, err := s.FavoriteUseCase.FindFavoriteProducts(ctx, claims.UserID, requestLocale(r))
+	if err != nil {
+		internalServerError(w)
+		return
+	}
+
+	resp, _ := json.Marshal(response{
+		commonResponse: commonResponse{
+			Message: "resources successfully get",
+		},
+		Payload: products,
+	})
+
+	responseOK(w, resp)
+}
+
+func (s *FavoriteHandler) AddFavorite(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	productID, _ := strconv.Atoi(chi.URLParam(r, "productID"))
+	claims, ok := ctx.Value(middleware.TokenCtxKey).(*helper.MyClaims)
+
+	if !ok {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		return
+	}
+
+	if err := s.FavoriteUseCase.AddFavorite(ctx, claims.UserID, productID); err != nil {
+		if err == sql.ErrNoRows {
+			notFound(w)
+			return
+		}
+		internalServerError(w)
+		return
+	}
+
+	resp, _ := json.Marshal(commonResponse{
+		Message: "resource has successfully created",
+	})
+
+	responseOK(w, resp)
+}
+
+func (s *FavoriteHandler) RemoveFavorite(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	productID, _ := strconv.Atoi(chi.URLParam(r, "productID"))
+	claims, ok := ctx.Value(middleware.TokenCtxKey).(*helper.MyClaims)
+
+	if !ok {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		return
+	}
+
+	if err := s.FavoriteUseCase.RemoveFavorite(ctx, claims.UserID, productID); err != nil {
+		internalServerError(w)
+		return
+	}
+
+	resp, _ := json.Marshal(commonResponse{
+		Message: "resource has successfully deleted",
+	})
+
+	responseOK(w, resp)
+}
+
+func (s *FavoriteHandler) FindCustomizations(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		commonResponse
+		Payload []entity.Customization `json:"payload"`
+	}
+
+	ctx := r.Context()
+	claims, ok := ctx.Value(middleware.TokenCtxKey).(*helper.MyClaims)
+
+	if !ok {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		return
+	}
+
+	customizations, err := s.FavoriteUseCase.FindCustomizations(ctx, claims.UserID, requestLocale(r))
+	if err != nil {
+		internalServerError(w)
+		return
+	}
+
+	resp, _ := json.Marshal(response{
+		commonResponse: commonResponse{
+			Message: "resources successfully get",
+		},
+		Payload: customizations,
+	})
+
+	responseOK(w, resp)
+}
+
+func (s *FavoriteHandler) CreateCustomization(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	claims, ok := ctx.Value(middleware.TokenCtxKey).(*helper.MyClaims)
+
+	if !ok {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		return
+	}
+
+	var body entity.CustomizationRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		badRequest(w, "invalid request")
+		return
+	}
+
+	if valid, msg := helper.ValidateLocale(body, requestLocale(r)); !valid {
+		badRequest(w, msg)
+		return
+	}
+
+	if err := s.FavoriteUseCase.CreateCustomization(ctx, claims.UserID, body); err != nil {
+		if err == sql.ErrNoRows {
+			notFound(w)
+			return
+		}
+		internalServerError(w)
+		return
+	}
+
+	resp, _ := json.Marshal(commonResponse{
+		Message: "resource has successfully created",
+	})
+
+	responseOK(w, resp)
+}
+
+func (s *FavoriteHandler) DeleteCustomization(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	customizationID, _ := strconv.Atoi(chi.URLParam(r, "customizationID"))
+	claims, ok := ctx.Value(middleware.TokenCtxKey).(*helper.MyClaims)
+
+	if !ok {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		return
+	}
+
+	if err := s.FavoriteUseCase.DeleteCustomization(ctx, customizationID, claims.UserID); err != nil {
+		if err.Error() == "no rows affected" {
+			notFound(w)
+			return
+		}
+		internalServerError(w)
+		return
+	}
+
+	resp, _ := json.Marshal(commonResponse{
+		Message: "resource has successfully deleted",
+	})
+
+	responseOK(w, resp)
+}
+
+func (s *FavoriteHandler) AddCustomizationToCart(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	customizationID, _ := strconv.Atoi(chi.URLParam(r, "customizationID"))
+	claims, ok := ctx.Value(middleware.TokenCtxKey).(*helper.MyClaims)
+
+	if !ok {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		return
+	}
+
+	if err := s.FavoriteUseCase.AddCustomizationToCart(ctx, customizationID, claims.UserID); err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			notFound(w)
+		case usecase.ErrProductUnavailable, usecase.ErrVariantUnavailable, usecase.ErrToppingUnavailable, usecase.ErrCartQtyExceeded, usecase.ErrInvalidQty:
+			badRequest(w, err.Error())
+		default:
+			internalServerError(w)
+		}
+		return
+	}
+
+	resp, _ := json.Marshal(commonResponse{
+		Message: "resource has successfully created",
+	})
+
+	responseOK(w, resp)
+}
@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/yosepalexsander/waysbucks-api/entity"
+	"github.com/yosepalexsander/waysbucks-api/usecase"
+)
+
+const (
+	// IdempotencyKeyHeader lets a client retry a POST without repeating it
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader marks a response replayed from a previous request
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+)
+
+type IdempotencyHandler struct {
+	IdempotencyUseCase usecase.IdempotencyUseCase
+}
+
+func NewIdempotencyHandler(u usecase.IdempotencyUseCase) IdempotencyHandler {
+	return IdempotencyHandler{u}
+}
+
+// Idempotent handles a request with an Idempotency-Key header once per user
+// or guest and key. A retry with the same method, path and body gets the
+// stored response, and reusing the key for another request is a conflict.
+// Server errors are not stored, so the retry is handled again, unless the
+// handler kept the key with keepIdempotencyKey.
+// Requests without the header, or without a user, pass through.
+func (s *IdempotencyHandler) Idempotent(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		value := r.Header.Get(IdempotencyKeyHeader)
+		owner := idempotencyOwner(r)
+		if value == "" || owner == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if len(value) > maxIdempotencyKeyLength {
+			badRequest(w, "idempotency key is too long")
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			badRequest(w, "failed to read request body")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		key := entity.NewIdempotencyKey(owner, value, r.Method, r.URL.Path, body)
+
+		stored, err := s.IdempotencyUseCase.BeginRequest(r.Context(), key)
+		switch err {
+		case nil:
+		case usecase.ErrIdempotencyKeyReused, usecase.ErrIdempotencyInProgress:
+			resp, _ := json.Marshal(commonResponse{
+				Error:   true,
+				Message: err.Error(),
+			})
+			conflict(w, resp)
+			return
+		default:
+			internalServerError(w)
+			return
+		}
+
+		if stored != nil {
+			if stored.ContentType != "" {
+				w.Header().Set("Content-Type", stored.ContentType)
+			}
+			w.Header().Set(IdempotentReplayedHeader, "true")
+			w.WriteHeader(stored.StatusCode)
+			w.Write(stored.ResponseBody)
+			return
+		}
+
+		rec := &responseRecorder{ResponseWriter: w}
+		completed := false
+		defer func() {
+			// a server error or a panicking handler frees the key for a retry
+			if !completed {
+				if err := s.IdempotencyUseCase.AbortRequest(r.Context(), key); err != nil {
+					log.Printf("abort idempotency key %q: %v", key.Key, err)
+				}
+			}
+		}()
+
+		next.ServeHTTP(rec, r)
+
+		if rec.statusCode() >= http.StatusInternalServerError && !rec.keep {
+			return
+		}
+
+		key.StatusCode = rec.statusCode()
+		key.ContentType = rec.Header().Get("Content-Type")
+		key.ResponseBody = rec.body.Bytes()
+		if err := s.IdempotencyUseCase.CompleteRequest(r.Context(), key, time.Now()); err != nil {
+			log.Printf("store idempotency key %q: %v", key.Key, err)
+			return
+		}
+		completed = true
+	})
+}
+
+// idempotencyOwner returns the user, or the guest of a cart token, the keys
+// belong to
+func idempotencyOwner(r *http.Request) string {
+	owner, ok := cartOwner(r)
+	switch {
+	case !ok:
+		return ""
+	case owner.UserId != "":
+		return owner.UserId
+	}
+
+	return "guest:" + owner.GuestId
+}
+
+// keepIdempotencyKey stores the response of the request even if it is a
+// server error. Handlers call it once their side effect is committed, so a
+// retry replays the error instead of repeating the side effect.
+func keepIdempotencyKey(w http.ResponseWriter) {
+	if rec, ok := w.(*responseRecorder); ok {
+		rec.keep = true
+	}
+}
+
+// responseRecorder copies the response written to the client
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+	keep   bool
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
+
+func (rec *responseRecorder) statusCode() int {
+	if rec.status == 0 {
+		return http.StatusOK
+	}
+	return rec.status
+}
@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yosepalexsander/waysbucks-api/entity"
+	"github.com/yosepalexsander/waysbucks-api/helper"
+	"github.com/yosepalexsander/waysbucks-api/middleware"
+	"github.com/yosepalexsander/waysbucks-api/usecase"
+)
+
+type stubIdempotencyRepository struct {
+	keys map[string]entity.IdempotencyKey
+}
+
+func (s *stubIdempotencyRepository) CreateIdempotencyKey(ctx context.Context, key entity.IdempotencyKey) (*entity.IdempotencyKey, bool, error) {
+	if stored, ok := s.keys[key.Owner+key.Key]; ok {
+		return &stored, false, nil
+	}
+	s.keys[key.Owner+key.Key] = key
+	return &key, true, nil
+}
+
+func (s *stubIdempotencyRepository) CompleteIdempotencyKey(ctx context.Context, key entity.IdempotencyKey) error {
+	s.keys[key.Owner+key.Key] = key
+	return nil
+}
+
+func (s *stubIdempotencyRepository) DeleteIdempotencyKey(ctx context.Context, owner string, key string) error {
+	if !s.keys[owner+key].Completed() {
+		delete(s.keys, owner+key)
+	}
+	return nil
+}
+
+func (s *stubIdempotencyRepository) PurgeIdempotencyKeys(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+func TestIdempotent(t *testing.T) {
+	h := NewIdempotencyHandler(usecase.NewIdempotencyUseCase(&stubIdempotencyRepository{keys: map[string]entity.IdempotencyKey{}}, time.Hour))
+
+	calls := 0
+	status := http.StatusServiceUnavailable
+	next := h.Idempotent(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(`{"call":` + strconv.Itoa(calls) + `}`))
+	}))
+
+	send := func(key string, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/transactions/", strings.NewReader(body))
+		r.Header.Set(IdempotencyKeyHeader, key)
+		r = r.WithContext(context.WithValue(r.Context(), middleware.TokenCtxKey, &helper.MyClaims{UserID: "user"}))
+		w := httptest.NewRecorder()
+		next.ServeHTTP(w, r)
+		return w
+	}
+
+	if w := send("key-1", `{"a":1}`); w.Code != http.StatusServiceUnavailable || calls != 1 {
+		t.Fatalf("first request = %d after %d calls", w.Code, calls)
+	}
+
+	// the server error was not stored so the retry is handled
+	status = http.StatusOK
+	if w := send("key-1", `{"a":1}`); w.Code != http.StatusOK || calls != 2 || w.Header().Get(IdempotentReplayedHeader) != "" {
+		t.Fatalf("retry after a server error = %d after %d calls", w.Code, calls)
+	}
+
+	w := send("key-1", `{"a":1}`)
+	if w.Code != http.StatusOK || calls != 2 || w.Header().Get(IdempotentReplayedHeader) != "true" || w.Body.String() != `{"call":2}` {
+		t.Errorf("retry = %d %q after %d calls, want the stored response", w.Code, w.Body.String(), calls)
+	}
+	if w.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("replayed content type = %q", w.Header().Get("Content-Type"))
+	}
+
+	if w := send("key-1", `{"a":2}`); w.Code != http.StatusConflict || calls != 2 {
+		t.Errorf("reused key = %d after %d calls, want a conflict", w.Code, calls)
+	}
+
+	// client errors are stored like successes
+	status = http.StatusBadRequest
+	send("key-2", `{}`)
+	if w := send("key-2", `{}`); w.Code != http.StatusBadRequest || calls != 3 || w.Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Errorf("retry of a client error = %d after %d calls, want the stored response", w.Code, calls)
+	}
+
+	if w := send("", `{}`); w.Code != http.StatusBadRequest || calls != 4 {
+		t.Errorf("request without a key = %d after %d calls", w.Code, calls)
+	}
+}
+
+func TestIdempotentKeptAfterServerError(t *testing.T) {
+	h := NewIdempotencyHandler(usecase.NewIdempotencyUseCase(&stubIdempotencyRepository{keys: map[string]entity.IdempotencyKey{}}, time.Hour))
+
+	// the order is stored before the payment gateway fails
+	calls := 0
+	next := h.Idempotent(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		keepIdempotencyKey(w)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"payload":{"transaction_id":"tx-` + strconv.Itoa(calls) + `"}}`))
+	}))
+
+	send := func() *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/transactions/", strings.NewReader(`{"a":1}`))
+		r.Header.Set(IdempotencyKeyHeader, "key-1")
+		r = r.WithContext(context.WithValue(r.Context(), middleware.TokenCtxKey, &helper.MyClaims{UserID: "user"}))
+		w := httptest.NewRecorder()
+		next.ServeHTTP(w, r)
+		return w
+	}
+
+	if w := send(); w.Code != http.StatusServiceUnavailable || calls != 1 {
+		t.Fatalf("first request = %d after %d calls", w.Code, calls)
+	}
+
+	w := send()
+	if w.Code != http.StatusServiceUnavailable || calls != 1 || w.Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Fatalf("retry after the gateway error = %d after %d calls, want the stored response", w.Code, calls)
+	}
+	if w.Body.String() != `{"payload":{"transaction_id":"tx-1"}}` {
+		t.Errorf("replayed body = %q, want the first transaction", w.Body.String())
+	}
+}
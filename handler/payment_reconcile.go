@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/yosepalexsander/waysbucks-api/entity"
+	"github.com/yosepalexsander/waysbucks-api/usecase"
+)
+
+type PaymentReconcileHandler struct {
+	PaymentReconcileUseCase usecase.PaymentReconcileUseCase
+}
+
+func NewPaymentReconcileHandler(u usecase.PaymentReconcileUseCase) PaymentReconcileHandler {
+	return PaymentReconcileHandler{u}
+}
+
+// FindPaymentMismatches reports the stale pending payments and what the
+// reconciler would do with them without changing anything. The older_than
+// query param overrides the stale delay, e.g. older_than=1h.
+func (s *PaymentReconcileHandler) FindPaymentMismatches(w http.ResponseWriter, r *http.Request) {
+	s.reconcilePayments(w, r, false)
+}
+
+// ReconcilePayments runs the reconciler right away
+func (s *PaymentReconcileHandler) ReconcilePayments(w http.ResponseWriter, r *http.Request) {
+	s.reconcilePayments(w, r, true)
+}
+
+func (s *PaymentReconcileHandler) reconcilePayments(w http.ResponseWriter, r *http.Request, apply bool) {
+	type response struct {
+		commonResponse
+		Payload *entity.PaymentReconciliation `json:"payload"`
+	}
+
+	var olderThan time.Duration
+	if value := r.URL.Query().Get("older_than"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			badRequest(w, "older_than must be a positive duration such as 1h")
+			return
+		}
+		olderThan = d
+	}
+
+	report, err := s.PaymentReconcileUseCase.ReconcilePayments(r.Context(), time.Now(), olderThan, apply)
+	if err != nil {
+		internalServerError(w)
+		return
+	}
+
+	resp, _ := json.Marshal(response{
+		commonResponse: commonResponse{
+			Message: "resource has successfully get",
+		},
+		Payload: report,
+	})
+
+	responseOK(w, resp)
+}
@@ -3,6 +3,7 @@ package handler
 import (
 	"database/sql"
 	"encoding/json"
+	"io"
 	"net/http"
 	"strconv"
 
@@ -28,7 +29,7 @@ func (s *ProductHandler) FindProducts(w http.ResponseWriter, r *http.Request) {
 	}
 
 	queries := r.URL.Query()
-	products, err := s.ProductUseCase.FindProducts(r.Context(), queries)
+	products, lastModified, err := s.ProductUseCase.FindProducts(r.Context(), queries, requestLocale(r))
 
 	if err != nil {
 		internalServerError(w)
@@ -43,7 +44,7 @@ func (s *ProductHandler) FindProducts(w http.ResponseWriter, r *http.Request) {
 	}
 
 	resp, _ := json.Marshal(responseStruct)
-	responseOK(w, resp)
+	responseCacheable(w, r, resp, lastModified)
 }
 
 func (s *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
@@ -55,7 +56,7 @@ func (s *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	productID, _ := strconv.Atoi(chi.URLParam(r, "productID"))
 
-	product, err := s.ProductUseCase.GetProduct(ctx, productID)
+	product, err := s.ProductUseCase.GetProduct(ctx, productID, requestLocale(r))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			notFound(w)
@@ -87,7 +88,7 @@ func (s *ProductHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if valid, msg := helper.Validate(body); !valid {
+	if valid, msg := helper.ValidateLocale(body, requestLocale(r)); !valid {
 		badRequest(w, msg)
 		return
 	}
@@ -108,12 +109,29 @@ func (s *ProductHandler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	productID, _ := strconv.Atoi(chi.URLParam(r, "productID"))
 	body := make(map[string]interface{})
+	update := entity.ProductUpdateRequest{}
 
-	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		badRequest(w, "invalid request body")
+		return
+	}
+
+	if err := json.Unmarshal(data, &body); err != nil {
 		badRequest(w, "invalid request body")
 		return
 	}
 
+	if err := json.Unmarshal(data, &update); err != nil {
+		badRequest(w, "invalid request body")
+		return
+	}
+
+	if valid, msg := helper.ValidateLocale(update, requestLocale(r)); !valid {
+		badRequest(w, msg)
+		return
+	}
+
 	if err := s.ProductUseCase.UpdateProduct(ctx, productID, body); err != nil {
 		internalServerError(w)
 		return
@@ -148,7 +166,7 @@ func (s *ProductHandler) FindToppings(w http.ResponseWriter, r *http.Request) {
 		Payload []entity.ProductTopping `json:"payload"`
 	}
 
-	toppings, err := s.ProductUseCase.FindToppings(r.Context())
+	toppings, lastModified, err := s.ProductUseCase.FindToppings(r.Context(), requestLocale(r))
 	if err != nil {
 		switch err {
 		case thirdparty.ErrServiceUnavailable:
@@ -168,7 +186,7 @@ func (s *ProductHandler) FindToppings(w http.ResponseWriter, r *http.Request) {
 	}
 	resBody, _ := json.Marshal(responseStruct)
 
-	responseOK(w, resBody)
+	responseCacheable(w, r, resBody, lastModified)
 }
 
 func (s *ProductHandler) CreateTopping(w http.ResponseWriter, r *http.Request) {
@@ -181,7 +199,7 @@ func (s *ProductHandler) CreateTopping(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if valid, msg := helper.Validate(body); !valid {
+	if valid, msg := helper.ValidateLocale(body, requestLocale(r)); !valid {
 		badRequest(w, msg)
 		return
 	}
@@ -202,12 +220,29 @@ func (s *ProductHandler) UpdateTopping(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	toppingID, _ := strconv.Atoi(chi.URLParam(r, "toppingID"))
 	body := make(map[string]interface{})
+	update := entity.ProductUpdateRequest{}
 
-	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		badRequest(w, "invalid request body")
+		return
+	}
+
+	if err := json.Unmarshal(data, &body); err != nil {
+		badRequest(w, "invalid request body")
+		return
+	}
+
+	if err := json.Unmarshal(data, &update); err != nil {
 		badRequest(w, "invalid request body")
 		return
 	}
 
+	if valid, msg := helper.ValidateLocale(update, requestLocale(r)); !valid {
+		badRequest(w, msg)
+		return
+	}
+
 	if err := s.ProductUseCase.UpdateTopping(ctx, toppingID, body); err != nil {
 		internalServerError(w)
 		return
@@ -235,3 +270,209 @@ func (s *ProductHandler) DeleteTopping(w http.ResponseWriter, r *http.Request) {
 
 	responseOK(w, resBody)
 }
+
+func (s *ProductHandler) FindProductVariants(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		commonResponse
+		Payload []entity.ProductVariant `json:"payload"`
+	}
+
+	productID, _ := strconv.Atoi(chi.URLParam(r, "productID"))
+
+	variants, err := s.ProductUseCase.FindProductVariants(r.Context(), productID)
+	if err != nil {
+		internalServerError(w)
+		return
+	}
+
+	resBody, _ := json.Marshal(response{
+		commonResponse: commonResponse{
+			Message: "resource has successfully get",
+		},
+		Payload: variants,
+	})
+
+	responseOK(w, resBody)
+}
+
+func (s *ProductHandler) CreateProductVariant(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	productID, _ := strconv.Atoi(chi.URLParam(r, "productID"))
+
+	body := entity.ProductVariantRequest{}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		badRequest(w, "invalid request body")
+		return
+	}
+
+	if valid, msg := helper.ValidateLocale(body, requestLocale(r)); !valid {
+		badRequest(w, msg)
+		return
+	}
+
+	if err := s.ProductUseCase.CreateProductVariant(ctx, productID, body); err != nil {
+		if err == sql.ErrNoRows {
+			notFound(w)
+			return
+		}
+		internalServerError(w)
+		return
+	}
+
+	resBody, _ := json.Marshal(commonResponse{
+		Message: "resource has successfully created",
+	})
+
+	responseOK(w, resBody)
+}
+
+func (s *ProductHandler) DeleteProductVariant(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	productID, _ := strconv.Atoi(chi.URLParam(r, "productID"))
+	variantID, _ := strconv.Atoi(chi.URLParam(r, "variantID"))
+
+	if err := s.ProductUseCase.DeleteProductVariant(ctx, variantID, productID); err != nil {
+		internalServerError(w)
+		return
+	}
+
+	resBody, _ := json.Marshal(commonResponse{
+		Message: "resource has successfully deleted",
+	})
+
+	responseOK(w, resBody)
+}
+
+func (s *ProductHandler) FindProductImages(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		commonResponse
+		Payload []entity.ProductImage `json:"payload"`
+	}
+
+	productID, _ := strconv.Atoi(chi.URLParam(r, "productID"))
+
+	images, err := s.ProductUseCase.FindProductImages(r.Context(), productID)
+	if err != nil {
+		internalServerError(w)
+		return
+	}
+
+	resp, _ := json.Marshal(response{
+		commonResponse: commonResponse{
+			Message: "resource has successfully get",
+		},
+		Payload: images,
+	})
+
+	responseOK(w, resp)
+}
+
+func (s *ProductHandler) AddProductImage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	productID, _ := strconv.Atoi(chi.URLParam(r, "productID"))
+
+	body := entity.ProductImageRequest{}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		badRequest(w, "invalid request body")
+		return
+	}
+
+	if valid, msg := helper.ValidateLocale(body, requestLocale(r)); !valid {
+		badRequest(w, msg)
+		return
+	}
+
+	if err := s.ProductUseCase.AddProductImage(ctx, productID, body); err != nil {
+		if err == sql.ErrNoRows {
+			notFound(w)
+			return
+		}
+		internalServerError(w)
+		return
+	}
+
+	resBody, _ := json.Marshal(commonResponse{
+		Message: "resource has successfully created",
+	})
+
+	responseOK(w, resBody)
+}
+
+func (s *ProductHandler) ReorderProductImages(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	productID, _ := strconv.Atoi(chi.URLParam(r, "productID"))
+
+	body := entity.ProductImageOrderRequest{}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		badRequest(w, "invalid request body")
+		return
+	}
+
+	if valid, msg := helper.ValidateLocale(body, requestLocale(r)); !valid {
+		badRequest(w, msg)
+		return
+	}
+
+	if err := s.ProductUseCase.ReorderProductImages(ctx, productID, body.ImageIds); err != nil {
+		if err == usecase.ErrInvalidImageOrder {
+			badRequest(w, err.Error())
+			return
+		}
+		internalServerError(w)
+		return
+	}
+
+	resBody, _ := json.Marshal(commonResponse{
+		Message: "resource has successfully updated",
+	})
+
+	responseOK(w, resBody)
+}
+
+func (s *ProductHandler) SetPrimaryProductImage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	productID, _ := strconv.Atoi(chi.URLParam(r, "productID"))
+	imageID, _ := strconv.Atoi(chi.URLParam(r, "imageID"))
+
+	if err := s.ProductUseCase.SetPrimaryProductImage(ctx, productID, imageID); err != nil {
+		if err == sql.ErrNoRows {
+			notFound(w)
+			return
+		}
+		internalServerError(w)
+		return
+	}
+
+	resBody, _ := json.Marshal(commonResponse{
+		Message: "resource has successfully updated",
+	})
+
+	responseOK(w, resBody)
+}
+
+func (s *ProductHandler) RemoveProductImage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	productID, _ := strconv.Atoi(chi.URLParam(r, "productID"))
+	imageID, _ := strconv.Atoi(chi.URLParam(r, "imageID"))
+
+	if err := s.ProductUseCase.RemoveProductImage(ctx, productID, imageID); err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			notFound(w)
+		case usecase.ErrLastProductImage:
+			badRequest(w, err.Error())
+		default:
+			internalServerError(w)
+		}
+		return
+	}
+
+	resBody, _ := json.Marshal(commonResponse{
+		Message: "resource has successfully deleted",
+	})
+
+	responseOK(w, resBody)
+}
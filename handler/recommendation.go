@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/yosepalexsander/waysbucks-api/entity"
+	"github.com/yosepalexsander/waysbucks-api/usecase"
+)
+
+type RecommendationHandler struct {
+	RecommendationUseCase usecase.RecommendationUseCase
+}
+
+func NewRecommendationHandler(u usecase.RecommendationUseCase) RecommendationHandler {
+	return RecommendationHandler{u}
+}
+
+func (s *RecommendationHandler) FindProductRecommendations(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		commonResponse
+		Payload *entity.Recommendations `json:"payload"`
+	}
+
+	productID, _ := strconv.Atoi(chi.URLParam(r, "productID"))
+
+	recommendations, err := s.RecommendationUseCase.FindProductRecommendations(r.Context(), productID)
+	if err != nil {
+		internalServerError(w)
+		return
+	}
+
+	resp, _ := json.Marshal(response{
+		commonResponse: commonResponse{
+			Message: "resource has successfully get",
+		},
+		Payload: recommendations,
+	})
+
+	responseOK(w, resp)
+}
+
+func (s *RecommendationHandler) FindCartRecommendations(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		commonResponse
+		Payload *entity.Recommendations `json:"payload"`
+	}
+
+	ctx := r.Context()
+	owner, ok := cartOwner(r)
+
+	if !ok {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		return
+	}
+
+	recommendations, err := s.RecommendationUseCase.FindCartRecommendations(ctx, owner)
+	if err != nil {
+		internalServerError(w)
+		return
+	}
+
+	resp, _ := json.Marshal(response{
+		commonResponse: commonResponse{
+			Message: "resource has successfully get",
+		},
+		Payload: recommendations,
+	})
+
+	responseOK(w, resp)
+}
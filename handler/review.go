@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/yosepalexsander/waysbucks-api/entity"
+	"github.com/yosepalexsander/waysbucks-api/helper"
+	"github.com/yosepalexsander/waysbucks-api/middleware"
+	"github.com/yosepalexsander/waysbucks-api/usecase"
+)
+
+type ReviewHandler struct {
+	ReviewUseCase usecase.ReviewUseCase
+}
+
+func NewReviewHandler(u usecase.ReviewUseCase) ReviewHandler {
+	return ReviewHandler{u}
+}
+
+func (s *ReviewHandler) FindProductReviews(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		commonResponse
+		Payload []entity.Review `json:"payload"`
+	}
+
+	productID, _ := strconv.Atoi(chi.URLParam(r, "productID"))
+
+	reviews, err := s.ReviewUseCase.FindProductReviews(r.Context(), productID)
+	if err != nil {
+		internalServerError(w)
+		return
+	}
+
+	resp, _ := json.Marshal(response{
+		commonResponse: commonResponse{
+			Message: "resource has successfully get",
+		},
+		Payload: reviews,
+	})
+
+	responseOK(w, resp)
+}
+
+func (s *ReviewHandler) CreateReview(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	productID, _ := strconv.Atoi(chi.URLParam(r, "productID"))
+	claims, ok := ctx.Value(middleware.TokenCtxKey).(*helper.MyClaims)
+
+	if !ok {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		return
+	}
+
+	var body entity.ReviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		badRequest(w, "invalid request")
+		return
+	}
+
+	if valid, msg := helper.ValidateLocale(body, requestLocale(r)); !valid {
+		badRequest(w, msg)
+		return
+	}
+
+	if err := s.ReviewUseCase.SubmitReview(ctx, productID, claims.UserID, body); err != nil {
+		if err == usecase.ErrNotVerifiedBuyer {
+			forbidden(w)
+			return
+		}
+		internalServerError(w)
+		return
+	}
+
+	resp, _ := json.Marshal(commonResponse{
+		Message: "resource has successfully created",
+	})
+
+	responseOK(w, resp)
+}
+
+func (s *ReviewHandler) DeleteReview(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	reviewID, _ := strconv.Atoi(chi.URLParam(r, "reviewID"))
+	claims, ok := ctx.Value(middleware.TokenCtxKey).(*helper.MyClaims)
+
+	if !ok {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		return
+	}
+
+	if err := s.ReviewUseCase.DeleteReview(ctx, reviewID, claims.UserID); err != nil {
+		if err.Error() == "no rows affected" {
+			notFound(w)
+			return
+		}
+		internalServerError(w)
+		return
+	}
+
+	resp, _ := json.Marshal(commonResponse{
+		Message: "resource has successfully deleted",
+	})
+
+	responseOK(w, resp)
+}
+
+func (s *ReviewHandler) FindFlaggedReviews(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		commonResponse
+		Payload []entity.Review `json:"payload"`
+	}
+
+	reviews, err := s.ReviewUseCase.FindFlaggedReviews(r.Context())
+	if err != nil {
+		internalServerError(w)
+		return
+	}
+
+	resp, _ := json.Marshal(response{
+		commonResponse: commonResponse{
+			Message: "resource has successfully get",
+		},
+		Payload: reviews,
+	})
+
+	responseOK(w, resp)
+}
+
+func (s *ReviewHandler) ModerateReview(w http.ResponseWriter, r *http.Request) {
+	reviewID, _ := strconv.Atoi(chi.URLParam(r, "reviewID"))
+
+	var body entity.ReviewModerationRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		badRequest(w, "invalid request")
+		return
+	}
+
+	if err := s.ReviewUseCase.ModerateReview(r.Context(), reviewID, body); err != nil {
+		if err == sql.ErrNoRows {
+			notFound(w)
+			return
+		}
+		internalServerError(w)
+		return
+	}
+
+	resp, _ := json.Marshal(commonResponse{
+		Message: "resource has successfully updated",
+	})
+
+	responseOK(w, resp)
+}
@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/yosepalexsander/waysbucks-api/entity"
+	"github.com/yosepalexsander/waysbucks-api/usecase"
+)
+
+type RevisionHandler struct {
+	RevisionUseCase usecase.RevisionUseCase
+}
+
+func NewRevisionHandler(u usecase.RevisionUseCase) RevisionHandler {
+	return RevisionHandler{u}
+}
+
+func (s *RevisionHandler) FindProductHistory(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		commonResponse
+		Payload []entity.ProductRevision `json:"payload"`
+	}
+
+	productID, _ := strconv.Atoi(chi.URLParam(r, "productID"))
+
+	revisions, err := s.RevisionUseCase.FindProductHistory(r.Context(), productID)
+	if err != nil {
+		internalServerError(w)
+		return
+	}
+
+	resp, _ := json.Marshal(response{
+		commonResponse: commonResponse{
+			Message: "resource has successfully get",
+		},
+		Payload: revisions,
+	})
+
+	responseOK(w, resp)
+}
+
+func (s *RevisionHandler) FindToppingHistory(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		commonResponse
+		Payload []entity.ToppingRevision `json:"payload"`
+	}
+
+	toppingID, _ := strconv.Atoi(chi.URLParam(r, "toppingID"))
+
+	revisions, err := s.RevisionUseCase.FindToppingHistory(r.Context(), toppingID)
+	if err != nil {
+		internalServerError(w)
+		return
+	}
+
+	resp, _ := json.Marshal(response{
+		commonResponse: commonResponse{
+			Message: "resource has successfully get",
+		},
+		Payload: revisions,
+	})
+
+	responseOK(w, resp)
+}
+
+func (s *RevisionHandler) GetCatalogSnapshot(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		commonResponse
+		Payload *entity.CatalogSnapshot `json:"payload"`
+	}
+
+	at, err := parseTimeParam(r.URL.Query().Get("at"))
+	if err != nil {
+		badRequest(w, "at must be a RFC3339 time or YYYY-MM-DD date")
+		return
+	}
+
+	snapshot, err := s.RevisionUseCase.GetCatalogAt(r.Context(), at)
+	if err != nil {
+		internalServerError(w)
+		return
+	}
+
+	resp, _ := json.Marshal(response{
+		commonResponse: commonResponse{
+			Message: "resource has successfully get",
+		},
+		Payload: snapshot,
+	})
+
+	responseOK(w, resp)
+}
+
+func (s *RevisionHandler) DiffCatalog(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		commonResponse
+		Payload *entity.CatalogDiff `json:"payload"`
+	}
+
+	queries := r.URL.Query()
+
+	from, err := parseTimeParam(queries.Get("from"))
+	if err != nil || queries.Get("from") == "" {
+		badRequest(w, "from must be a RFC3339 time or YYYY-MM-DD date")
+		return
+	}
+
+	to, err := parseTimeParam(queries.Get("to"))
+	if err != nil {
+		badRequest(w, "to must be a RFC3339 time or YYYY-MM-DD date")
+		return
+	}
+
+	diff, err := s.RevisionUseCase.DiffCatalog(r.Context(), from, to)
+	if err != nil {
+		internalServerError(w)
+		return
+	}
+
+	resp, _ := json.Marshal(response{
+		commonResponse: commonResponse{
+			Message: "resource has successfully get",
+		},
+		Payload: diff,
+	})
+
+	responseOK(w, resp)
+}
+
+// parseTimeParam accepts a RFC3339 time or a date. A date means the end of that
+// day and an empty value means now.
+func parseTimeParam(value string) (time.Time, error) {
+	if value == "" {
+		return time.Now(), nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return t.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+}
@@ -0,0 +1,343 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/yosepalexsander/waysbucks-api/entity"
+	"github.com/yosepalexsander/waysbucks-api/helper"
+	"github.com/yosepalexsander/waysbucks-api/middleware"
+	"github.com/yosepalexsander/waysbucks-api/usecase"
+)
+
+type StoreHandler struct {
+	StoreUseCase usecase.StoreUseCase
+}
+
+func NewStoreHandler(u usecase.StoreUseCase) StoreHandler {
+	return StoreHandler{u}
+}
+
+// StoreScope only lets admins and staff of the storeID url param through
+func (s *StoreHandler) StoreScope(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := r.Context().Value(middleware.TokenCtxKey).(*helper.MyClaims)
+		if !ok {
+			forbidden(w)
+			return
+		}
+
+		storeID, _ := strconv.Atoi(chi.URLParam(r, "storeID"))
+
+		allowed, err := s.StoreUseCase.CanManageStore(r.Context(), claims.UserID, claims.IsAdmin, storeID)
+		if err != nil {
+			internalServerError(w)
+			return
+		}
+
+		if !allowed {
+			forbidden(w)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// AllStoresScope only lets admins of every store through. Admins assigned
+// to a store are forbidden, like other users.
+func (s *StoreHandler) AllStoresScope(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := r.Context().Value(middleware.TokenCtxKey).(*helper.MyClaims)
+		if !ok {
+			forbidden(w)
+			return
+		}
+
+		allowed, err := s.StoreUseCase.ManagesAllStores(r.Context(), claims.UserID, claims.IsAdmin)
+		if err != nil {
+			internalServerError(w)
+			return
+		}
+
+		if !allowed {
+			forbidden(w)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *StoreHandler) FindStores(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		commonResponse
+		Payload []entity.Store `json:"payload"`
+	}
+
+	stores, err := s.StoreUseCase.FindStores(r.Context())
+	if err != nil {
+		internalServerError(w)
+		return
+	}
+
+	resp, _ := json.Marshal(response{
+		commonResponse: commonResponse{
+			Message: "resource has successfully get",
+		},
+		Payload: stores,
+	})
+
+	responseOK(w, resp)
+}
+
+func (s *StoreHandler) GetStore(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		commonResponse
+		Payload *entity.Store `json:"payload"`
+	}
+
+	storeID, _ := strconv.Atoi(chi.URLParam(r, "storeID"))
+
+	store, err := s.StoreUseCase.GetStore(r.Context(), storeID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			notFound(w)
+			return
+		}
+		internalServerError(w)
+		return
+	}
+
+	resp, _ := json.Marshal(response{
+		commonResponse: commonResponse{
+			Message: "resource has successfully get",
+		},
+		Payload: store,
+	})
+
+	responseOK(w, resp)
+}
+
+func (s *StoreHandler) GetStoreMenu(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		commonResponse
+		Payload *entity.StoreMenu `json:"payload"`
+	}
+
+	storeID, _ := strconv.Atoi(chi.URLParam(r, "storeID"))
+
+	menu, err := s.StoreUseCase.GetMenu(r.Context(), storeID, r.URL.Query(), requestLocale(r))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			notFound(w)
+			return
+		}
+		internalServerError(w)
+		return
+	}
+
+	resp, _ := json.Marshal(response{
+		commonResponse: commonResponse{
+			Message: "resource has successfully get",
+		},
+		Payload: menu,
+	})
+
+	responseOK(w, resp)
+}
+
+func (s *StoreHandler) CreateStore(w http.ResponseWriter, r *http.Request) {
+	body := entity.StoreRequest{}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		badRequest(w, "invalid request body")
+		return
+	}
+
+	if valid, msg := helper.ValidateLocale(body, requestLocale(r)); !valid {
+		badRequest(w, msg)
+		return
+	}
+
+	if err := s.StoreUseCase.CreateStore(r.Context(), body); err != nil {
+		internalServerError(w)
+		return
+	}
+
+	resp, _ := json.Marshal(commonResponse{
+		Message: "resource has successfully created",
+	})
+
+	responseOK(w, resp)
+}
+
+func (s *StoreHandler) UpdateStore(w http.ResponseWriter, r *http.Request) {
+	storeID, _ := strconv.Atoi(chi.URLParam(r, "storeID"))
+	body := entity.StoreRequest{}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		badRequest(w, "invalid request body")
+		return
+	}
+
+	if valid, msg := helper.ValidateLocale(body, requestLocale(r)); !valid {
+		badRequest(w, msg)
+		return
+	}
+
+	if err := s.StoreUseCase.UpdateStore(r.Context(), storeID, body); err != nil {
+		if err == sql.ErrNoRows {
+			notFound(w)
+			return
+		}
+		internalServerError(w)
+		return
+	}
+
+	resp, _ := json.Marshal(commonResponse{
+		Message: "resource has successfully updated",
+	})
+
+	responseOK(w, resp)
+}
+
+func (s *StoreHandler) DeleteStore(w http.ResponseWriter, r *http.Request) {
+	storeID, _ := strconv.Atoi(chi.URLParam(r, "storeID"))
+
+	if err := s.StoreUseCase.DeleteStore(r.Context(), storeID); err != nil {
+		internalServerError(w)
+		return
+	}
+
+	resp, _ := json.Marshal(commonResponse{
+		Message: "resource has successfully deleted",
+	})
+
+	responseOK(w, resp)
+}
+
+func (s *StoreHandler) AssignStoreStaff(w http.ResponseWriter, r *http.Request) {
+	storeID, _ := strconv.Atoi(chi.URLParam(r, "storeID"))
+	userID := chi.URLParam(r, "userID")
+
+	if err := s.StoreUseCase.AssignStaff(r.Context(), storeID, userID); err != nil {
+		if err == sql.ErrNoRows {
+			notFound(w)
+			return
+		}
+		internalServerError(w)
+		return
+	}
+
+	resp, _ := json.Marshal(commonResponse{
+		Message: "resource has successfully created",
+	})
+
+	responseOK(w, resp)
+}
+
+func (s *StoreHandler) RemoveStoreStaff(w http.ResponseWriter, r *http.Request) {
+	storeID, _ := strconv.Atoi(chi.URLParam(r, "storeID"))
+	userID := chi.URLParam(r, "userID")
+
+	if err := s.StoreUseCase.RemoveStaff(r.Context(), storeID, userID); err != nil {
+		internalServerError(w)
+		return
+	}
+
+	resp, _ := json.Marshal(commonResponse{
+		Message: "resource has successfully deleted",
+	})
+
+	responseOK(w, resp)
+}
+
+func (s *StoreHandler) SaveStoreProduct(w http.ResponseWriter, r *http.Request) {
+	storeID, _ := strconv.Atoi(chi.URLParam(r, "storeID"))
+	productID, _ := strconv.Atoi(chi.URLParam(r, "productID"))
+	body := entity.StoreItemRequest{}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		badRequest(w, "invalid request body")
+		return
+	}
+
+	if valid, msg := helper.ValidateLocale(body, requestLocale(r)); !valid {
+		badRequest(w, msg)
+		return
+	}
+
+	if err := s.StoreUseCase.SaveStoreProduct(r.Context(), storeID, productID, body); err != nil {
+		internalServerError(w)
+		return
+	}
+
+	resp, _ := json.Marshal(commonResponse{
+		Message: "resource has successfully updated",
+	})
+
+	responseOK(w, resp)
+}
+
+func (s *StoreHandler) DeleteStoreProduct(w http.ResponseWriter, r *http.Request) {
+	storeID, _ := strconv.Atoi(chi.URLParam(r, "storeID"))
+	productID, _ := strconv.Atoi(chi.URLParam(r, "productID"))
+
+	if err := s.StoreUseCase.DeleteStoreProduct(r.Context(), storeID, productID); err != nil {
+		internalServerError(w)
+		return
+	}
+
+	resp, _ := json.Marshal(commonResponse{
+		Message: "resource has successfully deleted",
+	})
+
+	responseOK(w, resp)
+}
+
+func (s *StoreHandler) SaveStoreTopping(w http.ResponseWriter, r *http.Request) {
+	storeID, _ := strconv.Atoi(chi.URLParam(r, "storeID"))
+	toppingID, _ := strconv.Atoi(chi.URLParam(r, "toppingID"))
+	body := entity.StoreItemRequest{}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		badRequest(w, "invalid request body")
+		return
+	}
+
+	if valid, msg := helper.ValidateLocale(body, requestLocale(r)); !valid {
+		badRequest(w, msg)
+		return
+	}
+
+	if err := s.StoreUseCase.SaveStoreTopping(r.Context(), storeID, toppingID, body); err != nil {
+		internalServerError(w)
+		return
+	}
+
+	resp, _ := json.Marshal(commonResponse{
+		Message: "resource has successfully updated",
+	})
+
+	responseOK(w, resp)
+}
+
+func (s *StoreHandler) DeleteStoreTopping(w http.ResponseWriter, r *http.Request) {
+	storeID, _ := strconv.Atoi(chi.URLParam(r, "storeID"))
+	toppingID, _ := strconv.Atoi(chi.URLParam(r, "toppingID"))
+
+	if err := s.StoreUseCase.DeleteStoreTopping(r.Context(), storeID, toppingID); err != nil {
+		internalServerError(w)
+		return
+	}
+
+	resp, _ := json.Marshal(commonResponse{
+		Message: "resource has successfully deleted",
+	})
+
+	responseOK(w, resp)
+}
@@ -3,7 +3,11 @@ package handler
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"io"
+	"log"
 	"net/http"
+	"strconv"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/yosepalexsander/waysbucks-api/entity"
@@ -21,10 +25,41 @@ func NewTransactionHandler(u usecase.TransactionUseCase) TransactionHandler {
 	return TransactionHandler{u}
 }
 
+// TransactionScope only lets admins and staff of the store of the
+// transactionID url param through. Other transactions are reported as not
+// found.
+func (s *TransactionHandler) TransactionScope(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := r.Context().Value(middleware.TokenCtxKey).(*helper.MyClaims)
+		if !ok {
+			forbidden(w)
+			return
+		}
+
+		allowed, err := s.TransactionUseCase.CanManageTransaction(r.Context(), chi.URLParam(r, "transactionID"), claims.UserID, claims.IsAdmin)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				notFound(w)
+				return
+			}
+			internalServerError(w)
+			return
+		}
+
+		if !allowed {
+			notFound(w)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
 func (s *TransactionHandler) CreateTransaction(w http.ResponseWriter, r *http.Request) {
 	type ResponsePayload struct {
-		Token       string `json:"token"`
-		RedirectURL string `json:"redirect_url"`
+		TransactionId string `json:"transaction_id"`
+		Token         string `json:"token"`
+		RedirectURL   string `json:"redirect_url"`
 	}
 	type response struct {
 		commonResponse
@@ -47,26 +82,120 @@ func (s *TransactionHandler) CreateTransaction(w http.ResponseWriter, r *http.Re
 	}
 
 	body.UserId = claims.UserID
-	if valid, msg := helper.Validate(body); !valid {
+	if valid, msg := helper.ValidateLocale(body, requestLocale(r)); !valid {
 		badRequest(w, msg)
+		return
 	}
 
 	createdTransaction, err := s.TransactionUseCase.MakeTransaction(ctx, body)
 	if err != nil {
-		internalServerError(w)
+		var changed *usecase.CartChangedError
+		if errors.As(err, &changed) {
+			cartChanged(w, changed)
+			return
+		}
+
+		switch {
+		case err == usecase.ErrStoreUnavailable, err == usecase.ErrStoreClosed, err == usecase.ErrTotalMismatch, err == usecase.ErrInvalidQty,
+			err == usecase.ErrAddressNotFound, err == usecase.ErrAddressNotLocated, err == usecase.ErrOutOfDeliveryRange:
+			badRequest(w, err.Error())
+		case errors.Is(err, usecase.ErrPaymentGateway) && createdTransaction != nil:
+			// the order is stored, a retry with the same key must not place it again
+			keepIdempotencyKey(w)
+			resp, _ := json.Marshal(response{
+				commonResponse: commonResponse{
+					Message: "error: payment gateway unavailable",
+				},
+				Payload: ResponsePayload{TransactionId: createdTransaction.Id},
+			})
+			w.Header().Add("Content-Type", "application/json; charset=utf-8")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write(resp)
+		case errors.Is(err, usecase.ErrPaymentGateway):
+			serviceUnavailable(w, "error: payment gateway unavailable")
+		default:
+			internalServerError(w)
+		}
 		return
 	}
 
-	snapRes := thirdparty.CreateTransaction(createdTransaction)
-
 	resp, _ := json.Marshal(response{
 		commonResponse: commonResponse{
 			Message: "resources has successfully created",
 		},
 		Payload: ResponsePayload{
-			Token:       snapRes.Token,
-			RedirectURL: snapRes.RedirectURL,
+			TransactionId: createdTransaction.Id,
+			Token:         createdTransaction.Payment.Token,
+			RedirectURL:   createdTransaction.Payment.RedirectURL,
+		},
+	})
+
+	responseOK(w, resp)
+}
+
+// QuoteDelivery prices the delivery before checkout. The address is the
+// address_id query param or the latitude and longitude ones, and subtotal
+// decides whether the delivery is free.
+func (s *TransactionHandler) QuoteDelivery(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		commonResponse
+		Payload *entity.DeliveryQuote `json:"payload"`
+	}
+
+	ctx := r.Context()
+	claims, ok := ctx.Value(middleware.TokenCtxKey).(*helper.MyClaims)
+
+	if !ok {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		return
+	}
+
+	query := r.URL.Query()
+	req := entity.DeliveryQuoteRequest{AddressId: query.Get("address_id"), UserId: claims.UserID}
+
+	var err error
+	if req.StoreId, err = strconv.Atoi(query.Get("store_id")); err != nil {
+		badRequest(w, "store_id is required")
+		return
+	}
+
+	if value := query.Get("subtotal"); value != "" {
+		if req.Subtotal, err = strconv.Atoi(value); err != nil || req.Subtotal < 0 {
+			badRequest(w, "invalid subtotal")
+			return
+		}
+	}
+
+	for name, coordinate := range map[string]**float64{"latitude": &req.Latitude, "longitude": &req.Longitude} {
+		value := query.Get(name)
+		if value == "" {
+			continue
+		}
+
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			badRequest(w, "invalid "+name)
+			return
+		}
+		*coordinate = &f
+	}
+
+	quote, err := s.TransactionUseCase.QuoteDelivery(ctx, req)
+	if err != nil {
+		switch err {
+		case usecase.ErrStoreUnavailable, usecase.ErrAddressNotFound, usecase.ErrAddressNotLocated, usecase.ErrOutOfDeliveryRange:
+			badRequest(w, err.Error())
+		default:
+			internalServerError(w)
+		}
+		return
+	}
+
+	resp, _ := json.Marshal(response{
+		commonResponse: commonResponse{
+			Message: "resource has successfully get",
 		},
+		Payload: quote,
 	})
 
 	responseOK(w, resp)
@@ -79,14 +208,38 @@ func (s *TransactionHandler) FindTransactions(w http.ResponseWriter, r *http.Req
 	}
 
 	ctx := r.Context()
-	_, ok := ctx.Value(middleware.TokenCtxKey).(*helper.MyClaims)
+	claims, ok := ctx.Value(middleware.TokenCtxKey).(*helper.MyClaims)
 
 	if !ok {
 		w.WriteHeader(http.StatusUnprocessableEntity)
 		return
 	}
 
-	transactions, err := s.TransactionUseCase.FindTransactions(ctx)
+	transactions, err := s.TransactionUseCase.FindTransactions(ctx, claims.UserID, claims.IsAdmin, requestLocale(r))
+	if err != nil {
+		internalServerError(w)
+		return
+	}
+
+	resp, _ := json.Marshal(response{
+		commonResponse: commonResponse{
+			Message: "resources has successfully get",
+		},
+		Payload: transactions,
+	})
+
+	responseOK(w, resp)
+}
+
+func (s *TransactionHandler) FindStoreTransactions(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		commonResponse
+		Payload []entity.Transaction `json:"payload"`
+	}
+
+	storeID, _ := strconv.Atoi(chi.URLParam(r, "storeID"))
+
+	transactions, err := s.TransactionUseCase.FindStoreTransactions(r.Context(), storeID, requestLocale(r))
 	if err != nil {
 		internalServerError(w)
 		return
@@ -116,7 +269,7 @@ func (s *TransactionHandler) GetUserTransactions(w http.ResponseWriter, r *http.
 		return
 	}
 
-	transactions, err := s.TransactionUseCase.GetUserTransactions(ctx, claims.UserID)
+	transactions, err := s.TransactionUseCase.GetUserTransactions(ctx, claims.UserID, requestLocale(r))
 	if err != nil {
 		switch err {
 		case thirdparty.ErrServiceUnavailable:
@@ -146,9 +299,16 @@ func (s *TransactionHandler) GetTransaction(w http.ResponseWriter, r *http.Reque
 	}
 
 	ctx := r.Context()
+	claims, ok := ctx.Value(middleware.TokenCtxKey).(*helper.MyClaims)
+
+	if !ok {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		return
+	}
+
 	transactionID := chi.URLParam(r, "transactionID")
 
-	transaction, err := s.TransactionUseCase.GetDetailTransaction(ctx, transactionID)
+	transaction, err := s.TransactionUseCase.GetTransaction(ctx, transactionID, claims.UserID, claims.IsAdmin, requestLocale(r))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			notFound(w)
@@ -168,18 +328,41 @@ func (s *TransactionHandler) GetTransaction(w http.ResponseWriter, r *http.Reque
 	responseOK(w, resp)
 }
 
-func (s *TransactionHandler) UpdateTransaction(w http.ResponseWriter, r *http.Request) {
-	transactionID := chi.URLParam(r, "transactionID")
+// AdvanceStoreTransaction lets store staff move an order of their store to
+// preparing, ready or completed
+func (s *TransactionHandler) AdvanceStoreTransaction(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	claims, ok := ctx.Value(middleware.TokenCtxKey).(*helper.MyClaims)
 
-	data := make(map[string]interface{})
+	if !ok {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		return
+	}
 
-	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+	storeID, _ := strconv.Atoi(chi.URLParam(r, "storeID"))
+	transactionID := chi.URLParam(r, "transactionID")
+
+	body := entity.TransactionStatusRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 		badRequest(w, "invalid request")
 		return
 	}
 
-	if err := s.TransactionUseCase.UpdateTransaction(r.Context(), transactionID, data); err != nil {
-		internalServerError(w)
+	if valid, msg := helper.ValidateLocale(body, requestLocale(r)); !valid {
+		badRequest(w, msg)
+		return
+	}
+
+	actor := entity.TransactionActor{Kind: entity.ActorStaff, UserId: claims.UserID}
+	if err := s.TransactionUseCase.AdvanceStoreTransaction(ctx, storeID, transactionID, body.Status, actor, body.Note); err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			notFound(w)
+		case usecase.ErrInvalidTransition:
+			badRequest(w, err.Error())
+		default:
+			internalServerError(w)
+		}
 		return
 	}
 
@@ -190,39 +373,205 @@ func (s *TransactionHandler) UpdateTransaction(w http.ResponseWriter, r *http.Re
 	responseOK(w, resp)
 }
 
-// Catch notification from midtrans request POST after
-func (s *TransactionHandler) PaymentNotification(w http.ResponseWriter, r *http.Request) {
+// CancelTransaction lets the customer cancel an order that is not being
+// prepared yet. A paid order is refunded in full.
+func (s *TransactionHandler) CancelTransaction(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
+	claims, ok := ctx.Value(middleware.TokenCtxKey).(*helper.MyClaims)
 
-	transaction, err := thirdparty.ParseTransactionResponse(r.Body)
+	if !ok {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		return
+	}
+
+	// the reason is optional
+	body := entity.CancelRequest{}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			badRequest(w, "invalid request")
+			return
+		}
+	}
+
+	if err := s.TransactionUseCase.CancelTransaction(ctx, chi.URLParam(r, "transactionID"), claims.UserID, body.Reason); err != nil {
+		refundError(w, err)
+		return
+	}
+
+	resp, _ := json.Marshal(commonResponse{
+		Message: "resource has successfully updated",
+	})
+
+	responseOK(w, resp)
+}
+
+func (s *TransactionHandler) RefundTransaction(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		commonResponse
+		Payload *entity.Refund `json:"payload"`
+	}
+
+	ctx := r.Context()
+	claims, ok := ctx.Value(middleware.TokenCtxKey).(*helper.MyClaims)
+
+	if !ok {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		return
+	}
+
+	body := entity.RefundRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		badRequest(w, "invalid request")
+		return
+	}
+
+	if valid, msg := helper.ValidateLocale(body, requestLocale(r)); !valid {
+		badRequest(w, msg)
+		return
+	}
+
+	actor := entity.TransactionActor{Kind: entity.ActorStaff, UserId: claims.UserID}
+	refund, err := s.TransactionUseCase.RefundTransaction(ctx, chi.URLParam(r, "transactionID"), body, actor)
 	if err != nil {
-		internalServerError(w)
+		refundError(w, err)
 		return
 	}
 
-	updateStatus := func(status string) {
-		data := make(map[string]interface{})
-		data["status"] = status
+	resp, _ := json.Marshal(response{
+		commonResponse: commonResponse{
+			Message: "resource has successfully created",
+		},
+		Payload: refund,
+	})
 
-		if err := s.TransactionUseCase.UpdateTransaction(ctx, transaction.TransactionID, data); err != nil {
-			internalServerError(w)
+	responseOK(w, resp)
+}
+
+// RetryRefund sends a failed refund to the payment gateway again
+func (s *TransactionHandler) RetryRefund(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		commonResponse
+		Payload *entity.Refund `json:"payload"`
+	}
+
+	refundID, _ := strconv.Atoi(chi.URLParam(r, "refundID"))
+
+	refund, err := s.TransactionUseCase.RetryRefund(r.Context(), chi.URLParam(r, "transactionID"), refundID)
+	if err != nil {
+		refundError(w, err)
+		return
+	}
+
+	resp, _ := json.Marshal(response{
+		commonResponse: commonResponse{
+			Message: "resource has successfully updated",
+		},
+		Payload: refund,
+	})
+
+	responseOK(w, resp)
+}
+
+func refundError(w http.ResponseWriter, err error) {
+	switch {
+	case err == sql.ErrNoRows:
+		notFound(w)
+	case err == usecase.ErrNotCancellable, err == usecase.ErrNotRefundable, err == usecase.ErrOrderRefunded, err == usecase.ErrRefundNotRetryable:
+		badRequest(w, err.Error())
+	case errors.Is(err, usecase.ErrPaymentGateway):
+		serviceUnavailable(w, "error: payment gateway rejected the refund, retry it later")
+	default:
+		internalServerError(w)
+	}
+}
+
+// PaymentNotification applies a notification of midtrans. Forged
+// notifications are rejected before they are stored.
+func (s *TransactionHandler) PaymentNotification(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		badRequest(w, "invalid request")
+		return
+	}
+
+	notification, err := s.TransactionUseCase.ParsePaymentNotification(body)
+	if err != nil {
+		if err == thirdparty.ErrInvalidSignature {
+			forbidden(w)
 			return
 		}
+		badRequest(w, "invalid request")
+		return
+	}
 
+	err = s.TransactionUseCase.HandlePaymentNotification(ctx, *notification)
+	switch {
+	// notifications that arrive after a later status was applied are
+	// acknowledged, and refunds the gateway rejected stay failed for a retry
+	case err == nil, err == usecase.ErrInvalidTransition:
+		w.WriteHeader(http.StatusOK)
+	case errors.Is(err, usecase.ErrPaymentGateway):
+		log.Printf("payment notification of %s: %v", notification.TransactionId, err)
 		w.WriteHeader(http.StatusOK)
+	case err == usecase.ErrAmountMismatch:
+		badRequest(w, err.Error())
+	case err == sql.ErrNoRows:
+		notFound(w)
+	default:
+		internalServerError(w)
 	}
+}
 
-	if transaction.TransactionStatus == "capture" {
-		if transaction.FraudStatus == "challange" {
-			updateStatus("pending")
-		} else if transaction.FraudStatus == "accept" {
-			updateStatus("success")
+func (s *TransactionHandler) FindPaymentNotifications(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		commonResponse
+		Payload []entity.PaymentNotification `json:"payload"`
+	}
+
+	notifications, err := s.TransactionUseCase.FindPaymentNotifications(r.Context(), chi.URLParam(r, "transactionID"))
+	if err != nil {
+		internalServerError(w)
+		return
+	}
+
+	resp, _ := json.Marshal(response{
+		commonResponse: commonResponse{
+			Message: "resources has successfully get",
+		},
+		Payload: notifications,
+	})
+
+	responseOK(w, resp)
+}
+
+// ReplayPaymentNotification applies a stored notification again. The payload
+// shows the outcome in processed_at and error.
+func (s *TransactionHandler) ReplayPaymentNotification(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		commonResponse
+		Payload *entity.PaymentNotification `json:"payload"`
+	}
+
+	notificationID, _ := strconv.Atoi(chi.URLParam(r, "notificationID"))
+
+	notification, err := s.TransactionUseCase.ReplayPaymentNotification(r.Context(), chi.URLParam(r, "transactionID"), notificationID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			notFound(w)
+			return
 		}
-	} else if transaction.TransactionStatus == "settlement" {
-		updateStatus("success")
-	} else if transaction.TransactionStatus == "cancel" || transaction.TransactionStatus == "deny" || transaction.TransactionStatus == "expire" {
-		updateStatus("failure")
-	} else if transaction.TransactionStatus == "pending" {
-		updateStatus("pending")
+		internalServerError(w)
+		return
 	}
+
+	resp, _ := json.Marshal(response{
+		commonResponse: commonResponse{
+			Message: "resource has successfully updated",
+		},
+		Payload: notification,
+	})
+
+	responseOK(w, resp)
 }
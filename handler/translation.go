@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/yosepalexsander/waysbucks-api/entity"
+	"github.com/yosepalexsander/waysbucks-api/helper"
+	"github.com/yosepalexsander/waysbucks-api/usecase"
+)
+
+func (s *ProductHandler) FindProductTranslations(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		commonResponse
+		Payload []entity.Translation `json:"payload"`
+	}
+
+	productID, _ := strconv.Atoi(chi.URLParam(r, "productID"))
+
+	translations, err := s.ProductUseCase.FindProductTranslations(r.Context(), productID)
+	if err != nil {
+		internalServerError(w)
+		return
+	}
+
+	resp, _ := json.Marshal(response{
+		commonResponse: commonResponse{
+			Message: "resource has successfully get",
+		},
+		Payload: translations,
+	})
+
+	responseOK(w, resp)
+}
+
+func (s *ProductHandler) SaveProductTranslation(w http.ResponseWriter, r *http.Request) {
+	productID, _ := strconv.Atoi(chi.URLParam(r, "productID"))
+	locale := chi.URLParam(r, "locale")
+
+	var body entity.TranslationRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		badRequest(w, "invalid request body")
+		return
+	}
+
+	if valid, msg := helper.ValidateLocale(body, requestLocale(r)); !valid {
+		badRequest(w, msg)
+		return
+	}
+
+	if err := s.ProductUseCase.SaveProductTranslation(r.Context(), productID, locale, body); err != nil {
+		switch err {
+		case usecase.ErrUnsupportedLocale:
+			badRequest(w, err.Error())
+		case sql.ErrNoRows:
+			notFound(w)
+		default:
+			internalServerError(w)
+		}
+		return
+	}
+
+	resp, _ := json.Marshal(commonResponse{
+		Message: "resource has successfully updated",
+	})
+
+	responseOK(w, resp)
+}
+
+func (s *ProductHandler) DeleteProductTranslation(w http.ResponseWriter, r *http.Request) {
+	productID, _ := strconv.Atoi(chi.URLParam(r, "productID"))
+
+	if err := s.ProductUseCase.DeleteProductTranslation(r.Context(), productID, chi.URLParam(r, "locale")); err != nil {
+		internalServerError(w)
+		return
+	}
+
+	resp, _ := json.Marshal(commonResponse{
+		Message: "resource has successfully deleted",
+	})
+
+	responseOK(w, resp)
+}
+
+func (s *ProductHandler) FindToppingTranslations(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		commonResponse
+		Payload []entity.Translation `json:"payload"`
+	}
+
+	toppingID, _ := strconv.Atoi(chi.URLParam(r, "toppingID"))
+
+	translations, err := s.ProductUseCase.FindToppingTranslations(r.Context(), toppingID)
+	if err != nil {
+		internalServerError(w)
+		return
+	}
+
+	resp, _ := json.Marshal(response{
+		commonResponse: commonResponse{
+			Message: "resource has successfully get",
+		},
+		Payload: translations,
+	})
+
+	responseOK(w, resp)
+}
+
+func (s *ProductHandler) SaveToppingTranslation(w http.ResponseWriter, r *http.Request) {
+	toppingID, _ := strconv.Atoi(chi.URLParam(r, "toppingID"))
+	locale := chi.URLParam(r, "locale")
+
+	var body entity.TranslationRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		badRequest(w, "invalid request body")
+		return
+	}
+
+	if valid, msg := helper.ValidateLocale(body, requestLocale(r)); !valid {
+		badRequest(w, msg)
+		return
+	}
+
+	if err := s.ProductUseCase.SaveToppingTranslation(r.Context(), toppingID, locale, body); err != nil {
+		switch err {
+		case usecase.ErrUnsupportedLocale:
+			badRequest(w, err.Error())
+		case sql.ErrNoRows:
+			notFound(w)
+		default:
+			internalServerError(w)
+		}
+		return
+	}
+
+	resp, _ := json.Marshal(commonResponse{
+		Message: "resource has successfully updated",
+	})
+
+	responseOK(w, resp)
+}
+
+func (s *ProductHandler) DeleteToppingTranslation(w http.ResponseWriter, r *http.Request) {
+	toppingID, _ := strconv.Atoi(chi.URLParam(r, "toppingID"))
+
+	if err := s.ProductUseCase.DeleteToppingTranslation(r.Context(), toppingID, chi.URLParam(r, "locale")); err != nil {
+		internalServerError(w)
+		return
+	}
+
+	resp, _ := json.Marshal(commonResponse{
+		Message: "resource has successfully deleted",
+	})
+
+	responseOK(w, resp)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"log"
 	"net/http"
 	"time"
 
@@ -19,10 +20,11 @@ import (
 
 type UserHandler struct {
 	UserUseCase usecase.UserUseCase
+	cart        usecase.CartUseCase
 }
 
-func NewUserHandler(u usecase.UserUseCase) UserHandler {
-	return UserHandler{u}
+func NewUserHandler(u usecase.UserUseCase, cart usecase.CartUseCase) UserHandler {
+	return UserHandler{u, cart}
 }
 
 func (s *UserHandler) GetUsers(w http.ResponseWriter, r *http.Request) {
@@ -146,7 +148,7 @@ func (s *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	isValid, msg := helper.Validate(body)
+	isValid, msg := helper.ValidateLocale(body, requestLocale(r))
 	if !isValid {
 		badRequest(w, msg)
 		return
@@ -177,6 +179,7 @@ func (s *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
 			Name:  user.Name,
 			Email: user.Email,
 			Token: tokenString,
+			Cart:  s.mergeGuestCart(r, user.Id),
 		},
 	}
 	resBody, _ := json.Marshal(responseStruct)
@@ -204,7 +207,7 @@ func (s *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	isValid, msg := helper.Validate(body)
+	isValid, msg := helper.ValidateLocale(body, requestLocale(r))
 	if !isValid {
 		badRequest(w, msg)
 		return
@@ -229,6 +232,7 @@ func (s *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 			Name:  user.Name,
 			Email: user.Email,
 			Token: tokenString,
+			Cart:  s.mergeGuestCart(r, user.Id),
 		},
 	}
 	resBody, _ := json.Marshal(responseStruct)
@@ -249,7 +253,7 @@ func (s *UserHandler) LoginOrRegisterWithGoogle(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	isValid, msg := helper.Validate(body)
+	isValid, msg := helper.ValidateLocale(body, requestLocale(r))
 	if !isValid {
 		badRequest(w, msg)
 		return
@@ -295,6 +299,7 @@ func (s *UserHandler) LoginOrRegisterWithGoogle(w http.ResponseWriter, r *http.R
 			Name:  user.Name,
 			Email: user.Email,
 			Token: tokenString,
+			Cart:  s.mergeGuestCart(r, user.Id),
 		},
 	}
 	resBody, _ := json.Marshal(responseStruct)
@@ -302,6 +307,24 @@ func (s *UserHandler) LoginOrRegisterWithGoogle(w http.ResponseWriter, r *http.R
 	responseOK(w, resBody)
 }
 
+// mergeGuestCart moves the guest cart of the cart token into the user cart.
+// A missing or invalid cart token never fails the login, and a failed merge
+// is reported in the response so the client keeps its cart token.
+func (s *UserHandler) mergeGuestCart(r *http.Request, userID string) *entity.CartMerge {
+	guestID, err := helper.VerifyGuestToken(r.Header.Get(middleware.CartTokenHeader))
+	if err != nil {
+		return nil
+	}
+
+	merge, err := s.cart.MergeGuestCart(r.Context(), guestID, userID)
+	if err != nil {
+		log.Printf("merge guest cart: %v", err)
+		return &entity.CartMerge{Error: "failed to merge the guest cart"}
+	}
+
+	return merge
+}
+
 func VerifyTokenID(idToken string) (*TokenInfo, error) {
 	authService, err := oauth2.NewService(context.TODO(), option.WithHTTPClient(http.DefaultClient))
 	if err != nil {
@@ -338,9 +361,10 @@ type (
 		Payload AuthResponsePayload `json:"payload"`
 	}
 	AuthResponsePayload struct {
-		Name  string `json:"name"`
-		Email string `json:"email"`
-		Token string `json:"token"`
+		Name  string            `json:"name"`
+		Email string            `json:"email"`
+		Token string            `json:"token"`
+		Cart  *entity.CartMerge `json:"cart,omitempty"`
 	}
 )
 
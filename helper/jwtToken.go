@@ -32,13 +32,68 @@ func GenerateToken(id string, isAdmin bool) (string, error) {
 	return tokenString, nil
 }
 
+// VerifyToken parses a user token. Cart tokens of guests are rejected since
+// they carry no user.
 func VerifyToken(tokenString string) (*jwt.Token, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &MyClaims{}, func(token *jwt.Token) (interface{}, error) {
+	claims := &MyClaims{}
+
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, jwt.ErrSignatureInvalid
+		}
 		return []byte(config.JWT_SECRET), nil
 	})
 	if err != nil {
 		return nil, err
 	}
 
+	if claims.Audience == guestCartAudience || claims.UserID == "" {
+		return nil, jwt.ErrSignatureInvalid
+	}
+
 	return token, nil
 }
+
+// guestCartAudience keeps cart tokens and user tokens from being accepted in
+// place of each other
+const guestCartAudience = "guest-cart"
+
+type GuestClaims struct {
+	GuestID string
+	jwt.StandardClaims
+}
+
+// GenerateGuestToken signs a cart token for an anonymous visitor
+func GenerateGuestToken(guestID string) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, GuestClaims{
+		GuestID: guestID,
+		StandardClaims: jwt.StandardClaims{
+			Audience:  guestCartAudience,
+			ExpiresAt: time.Now().Add(time.Hour * 24 * 30).Unix(),
+			Issuer:    "Waysbucks",
+		},
+	})
+
+	return token.SignedString([]byte(config.JWT_SECRET))
+}
+
+// VerifyGuestToken returns the guest id of a valid cart token
+func VerifyGuestToken(tokenString string) (string, error) {
+	claims := &GuestClaims{}
+
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, jwt.ErrSignatureInvalid
+		}
+		return []byte(config.JWT_SECRET), nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if !token.Valid || !claims.VerifyAudience(guestCartAudience, true) || claims.GuestID == "" {
+		return "", jwt.ErrSignatureInvalid
+	}
+
+	return claims.GuestID, nil
+}
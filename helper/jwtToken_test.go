@@ -0,0 +1,39 @@
+package helper
+
+import (
+	"testing"
+
+	"github.com/yosepalexsander/waysbucks-api/config"
+)
+
+func TestVerifyGuestToken(t *testing.T) {
+	config.JWT_SECRET = "secret"
+
+	token, err := GenerateGuestToken("guest-1")
+	if err != nil {
+		t.Fatalf("GenerateGuestToken() error = %v", err)
+	}
+
+	guestID, err := VerifyGuestToken(token)
+	if err != nil || guestID != "guest-1" {
+		t.Errorf("VerifyGuestToken() = %q, %v, want guest-1", guestID, err)
+	}
+
+	userToken, _ := GenerateToken("user-1", false)
+	if _, err := VerifyGuestToken(userToken); err == nil {
+		t.Error("VerifyGuestToken() accepted a user token")
+	}
+
+	if _, err := VerifyToken(token); err == nil {
+		t.Error("VerifyToken() accepted a guest token")
+	}
+
+	if _, err := VerifyToken(userToken); err != nil {
+		t.Errorf("VerifyToken() user token error = %v", err)
+	}
+
+	config.JWT_SECRET = "rotated"
+	if _, err := VerifyGuestToken(token); err == nil {
+		t.Error("VerifyGuestToken() accepted a token signed with another secret")
+	}
+}
@@ -0,0 +1,78 @@
+package helper
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	LocaleEnglish    = "en"
+	LocaleIndonesian = "id"
+	DefaultLocale    = LocaleEnglish
+)
+
+var SupportedLocales = []string{LocaleEnglish, LocaleIndonesian}
+
+func IsSupportedLocale(locale string) bool {
+	for _, l := range SupportedLocales {
+		if l == locale {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NegotiateLocale picks the locale of a request. The lang query param wins over
+// the Accept-Language header and DefaultLocale is used when neither of them
+// names a supported locale.
+func NegotiateLocale(lang string, acceptLanguage string) string {
+	if locale := baseLanguage(lang); IsSupportedLocale(locale) {
+		return locale
+	}
+
+	type weighted struct {
+		locale string
+		q      float64
+	}
+
+	candidates := []weighted{}
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		q := 1.0
+
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if parsed, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+
+		if locale := baseLanguage(fields[0]); IsSupportedLocale(locale) && q > 0 {
+			candidates = append(candidates, weighted{locale, q})
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].q > candidates[j].q
+	})
+
+	if len(candidates) > 0 {
+		return candidates[0].locale
+	}
+
+	return DefaultLocale
+}
+
+// baseLanguage returns the lowercased language of a tag, e.g. "id" for "id-ID"
+func baseLanguage(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(tag, "-_"); i >= 0 {
+		tag = tag[:i]
+	}
+
+	return tag
+}
@@ -0,0 +1,24 @@
+package helper
+
+import "testing"
+
+func TestNegotiateLocale(t *testing.T) {
+	tests := []struct {
+		lang           string
+		acceptLanguage string
+		want           string
+	}{
+		{"", "", DefaultLocale},
+		{"id", "en-US,en;q=0.9", LocaleIndonesian},
+		{"fr", "id-ID,id;q=0.9", LocaleIndonesian},
+		{"", "fr-FR,en;q=0.5,id;q=0.8", LocaleIndonesian},
+		{"", "id;q=0,en;q=0.1", LocaleEnglish},
+		{"", "ja,fr", DefaultLocale},
+	}
+
+	for _, tt := range tests {
+		if got := NegotiateLocale(tt.lang, tt.acceptLanguage); got != tt.want {
+			t.Errorf("NegotiateLocale(%q, %q) = %q, want %q", tt.lang, tt.acceptLanguage, got, tt.want)
+		}
+	}
+}
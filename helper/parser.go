@@ -3,10 +3,22 @@ package helper
 import (
 	"fmt"
 	"regexp"
+	"strconv"
+	"strings"
+	"time"
 )
 
 var nameRegex = regexp.MustCompile(`\A[\[\]]*([^\[\]]+)\]*`)
 var clauseRegex = regexp.MustCompile(`\[([^\[\]]+)\]`)
+var allergenRegex = regexp.MustCompile(`^[a-z]+$`)
+
+// sortClauses maps the values accepted by the "sort" query param to order by clauses
+var sortClauses = map[string]string{
+	"rating":     "rating DESC, review_count DESC",
+	"newest":     "created_at DESC",
+	"price_asc":  "price ASC",
+	"price_desc": "price DESC",
+}
 
 func QueryParamsToSqlClauses(queries map[string][]string) ([]string, string) {
 	if len(queries) < 1 {
@@ -24,6 +36,24 @@ func QueryParamsToSqlClauses(queries map[string][]string) ([]string, string) {
 			continue
 		}
 
+		if k == "exclude_allergen" {
+			for _, allergens := range v {
+				for _, allergen := range strings.Split(allergens, ",") {
+					if allergenRegex.MatchString(allergen) {
+						whereClauses = append(whereClauses, fmt.Sprintf("NOT ('%s' = ANY(allergens))", allergen))
+					}
+				}
+			}
+			continue
+		}
+
+		if k == "sort" {
+			if clause, ok := sortClauses[value]; ok {
+				orderByClause = clause
+			}
+			continue
+		}
+
 		field := string(nameRegex.Find([]byte(k)))
 		clauses := clauseRegex.FindStringSubmatch(k)
 
@@ -42,3 +72,25 @@ func QueryParamsToSqlClauses(queries map[string][]string) ([]string, string) {
 
 	return whereClauses, orderByClause
 }
+
+// ParseDuration parses a duration such as "36h" and falls back when value is
+// empty or invalid
+func ParseDuration(value string, fallback time.Duration) time.Duration {
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return fallback
+	}
+
+	return d
+}
+
+// ParseInt parses a non negative number and falls back when value is empty
+// or invalid
+func ParseInt(value string, fallback int) int {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return fallback
+	}
+
+	return n
+}
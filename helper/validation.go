@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/id"
 	ut "github.com/go-playground/universal-translator"
 	"github.com/go-playground/validator/v10"
 )
@@ -15,16 +16,43 @@ var (
 	ErrorInvalidFileExtension error = errors.New("invalid file extension")
 )
 
+// validationMessages holds the validation error messages of every supported locale
+var validationMessages = map[string]map[string]string{
+	LocaleEnglish: {
+		"email":            "{0} must be a valid email",
+		"min":              "{0} must be at least {1} char length",
+		"max":              "{0} must be max {1} char length",
+		"required":         "{0} is a required field",
+		"required_without": "{0} is a required field",
+		"oneof":            "{0} must be one of [{1}]",
+	},
+	LocaleIndonesian: {
+		"email":            "{0} harus berupa email yang valid",
+		"min":              "{0} minimal {1} karakter",
+		"max":              "{0} maksimal {1} karakter",
+		"required":         "{0} wajib diisi",
+		"required_without": "{0} wajib diisi",
+		"oneof":            "{0} harus salah satu dari [{1}]",
+	},
+}
+
 func Validate(value interface{}) (bool, string) {
+	return ValidateLocale(value, DefaultLocale)
+}
+
+// ValidateLocale works like Validate with the error message in the given locale
+func ValidateLocale(value interface{}, locale string) (bool, string) {
+	if !IsSupportedLocale(locale) {
+		locale = DefaultLocale
+	}
+
 	v := validator.New()
-	english := en.New()
-	uni := ut.New(english, english)
-	trans, _ := uni.GetTranslator("en")
-
-	addTranslation(v, trans, "email", "{0} must be a valid email")
-	addTranslation(v, trans, "min", "{0} must be at least {1} char length")
-	addTranslation(v, trans, "max", "{0} must be max {1} char length")
-	addTranslation(v, trans, "required", "{0} is a required field")
+	uni := ut.New(en.New(), en.New(), id.New())
+	trans, _ := uni.GetTranslator(locale)
+
+	for tag, msg := range validationMessages[locale] {
+		addTranslation(v, trans, tag, msg)
+	}
 
 	v.RegisterTagNameFunc(func(fld reflect.StructField) string {
 		name := strings.SplitN(fld.Tag.Get("json"), ",", 2)[0]
@@ -1,14 +1,35 @@
 package interactor
 
 import (
+	"context"
+	"log"
+
 	"github.com/jmoiron/sqlx"
+	"github.com/yosepalexsander/waysbucks-api/config"
+	"github.com/yosepalexsander/waysbucks-api/entity"
 	"github.com/yosepalexsander/waysbucks-api/handler"
+	"github.com/yosepalexsander/waysbucks-api/helper"
 	"github.com/yosepalexsander/waysbucks-api/persistance"
+	"github.com/yosepalexsander/waysbucks-api/thirdparty"
 	"github.com/yosepalexsander/waysbucks-api/usecase"
 )
 
 type Interactor struct {
-	DB *sqlx.DB
+	DB      *sqlx.DB
+	Payment usecase.PaymentProvider
+	product *usecase.ProductUseCase
+}
+
+// NewPaymentProvider returns the provider named by PAYMENT_PROVIDER. Midtrans
+// is the default and uses production when MIDTRANS_ENV is production. The
+// fake provider keeps payments in memory for local development.
+func NewPaymentProvider() usecase.PaymentProvider {
+	if config.PAYMENT_PROVIDER == "fake" {
+		log.Println("using the fake payment provider, payments are not charged")
+		return thirdparty.NewFakePaymentProvider()
+	}
+
+	return thirdparty.NewMidtransProvider(config.MIDTRANS_SERVER_KEY, config.MIDTRANS_ENV == "production")
 }
 
 type AppHandler struct {
@@ -17,6 +38,14 @@ type AppHandler struct {
 	handler.ProductHandler
 	handler.CartHandler
 	handler.TransactionHandler
+	handler.ReviewHandler
+	handler.FavoriteHandler
+	handler.RevisionHandler
+	handler.RecommendationHandler
+	handler.StoreHandler
+	handler.AbandonedCartHandler
+	handler.PaymentReconcileHandler
+	handler.IdempotencyHandler
 }
 
 func (i *Interactor) NewAppHandler() *AppHandler {
@@ -26,13 +55,22 @@ func (i *Interactor) NewAppHandler() *AppHandler {
 	appHandler.ProductHandler = i.NewProductHandler()
 	appHandler.CartHandler = i.NewCartHandler()
 	appHandler.TransactionHandler = i.NewTransasctionHandler()
+	appHandler.ReviewHandler = i.NewReviewHandler()
+	appHandler.FavoriteHandler = i.NewFavoriteHandler()
+	appHandler.RevisionHandler = i.NewRevisionHandler()
+	appHandler.RecommendationHandler = i.NewRecommendationHandler()
+	appHandler.StoreHandler = i.NewStoreHandler()
+	appHandler.AbandonedCartHandler = i.NewAbandonedCartHandler()
+	appHandler.PaymentReconcileHandler = i.NewPaymentReconcileHandler()
+	appHandler.IdempotencyHandler = i.NewIdempotencyHandler()
 	return appHandler
 }
 
 func (i *Interactor) NewUserHandler() handler.UserHandler {
-	return handler.NewUserHandler(usecase.NewUserUseCase(
-		persistance.NewUserRepository(i.DB),
-	))
+	return handler.NewUserHandler(
+		usecase.NewUserUseCase(persistance.NewUserRepository(i.DB)),
+		i.newCartUseCase(),
+	)
 }
 
 func (i *Interactor) NewAddressHandler() handler.AddressHandler {
@@ -44,18 +82,145 @@ func (i *Interactor) NewAddressHandler() handler.AddressHandler {
 }
 
 func (i *Interactor) NewProductHandler() handler.ProductHandler {
-	return handler.NewProductHandler(usecase.NewProductUseCase(
-		persistance.NewProductRepository(i.DB),
-	))
+	return handler.NewProductHandler(i.newProductUseCase())
+}
+
+// newProductUseCase always returns the same use case so every handler shares
+// its catalog cache
+func (i *Interactor) newProductUseCase() usecase.ProductUseCase {
+	if i.product == nil {
+		product := usecase.NewProductUseCase(
+			persistance.NewProductRepository(i.DB),
+			persistance.NewTranslationRepository(i.DB),
+		)
+		i.product = &product
+	}
+
+	return *i.product
 }
 
 func (i *Interactor) NewCartHandler() handler.CartHandler {
-	return handler.NewCartHandler(usecase.NewCartUseCase(persistance.NewCartRepository(i.DB)))
+	return handler.NewCartHandler(i.newCartUseCase())
+}
+
+func (i *Interactor) newCartUseCase() usecase.CartUseCase {
+	return usecase.NewCartUseCase(
+		persistance.NewCartRepository(i.DB),
+		persistance.NewProductRepository(i.DB),
+		persistance.NewStoreRepository(i.DB),
+		persistance.NewTranslationRepository(i.DB),
+	)
 }
 
 func (i *Interactor) NewTransasctionHandler() handler.TransactionHandler {
-	return handler.NewTransactionHandler(
-		usecase.NewTransactionUseCase(
-			persistance.NewTransactionRepository(i.DB),
-		))
+	return handler.NewTransactionHandler(i.newTransactionUseCase())
+}
+
+func (i *Interactor) newTransactionUseCase() usecase.TransactionUseCase {
+	return usecase.NewTransactionUseCase(
+		persistance.NewTransactionRepository(i.DB),
+		persistance.NewProductRepository(i.DB),
+		persistance.NewStoreRepository(i.DB),
+		persistance.NewAddressRepository(i.DB),
+		persistance.NewTranslationRepository(i.DB),
+		i.Payment,
+		deliveryRules(),
+	)
+}
+
+func deliveryRules() entity.DeliveryRules {
+	return entity.DeliveryRules{
+		BaseFee:       helper.ParseInt(config.DELIVERY_BASE_FEE, usecase.DefaultDeliveryBaseFee),
+		PerKmFee:      helper.ParseInt(config.DELIVERY_FEE_PER_KM, usecase.DefaultDeliveryPerKmFee),
+		FreeAbove:     helper.ParseInt(config.DELIVERY_FREE_ABOVE, usecase.DefaultDeliveryFreeAbove),
+		MaxDistanceKm: helper.ParseInt(config.DELIVERY_MAX_DISTANCE_KM, usecase.DefaultDeliveryMaxDistanceKm),
+	}
+}
+
+func (i *Interactor) NewPaymentReconcileHandler() handler.PaymentReconcileHandler {
+	return handler.NewPaymentReconcileHandler(i.newPaymentReconcileUseCase())
+}
+
+func (i *Interactor) newPaymentReconcileUseCase() usecase.PaymentReconcileUseCase {
+	return usecase.NewPaymentReconcileUseCase(
+		i.newTransactionUseCase(),
+		helper.ParseDuration(config.PAYMENT_STALE_AFTER, usecase.DefaultPaymentStaleAfter),
+		helper.ParseDuration(config.PAYMENT_DEADLINE, usecase.DefaultPaymentDeadline),
+	)
+}
+
+func (i *Interactor) NewReviewHandler() handler.ReviewHandler {
+	return handler.NewReviewHandler(usecase.NewReviewUseCase(
+		persistance.NewReviewRepository(i.DB),
+	))
+}
+
+func (i *Interactor) NewFavoriteHandler() handler.FavoriteHandler {
+	return handler.NewFavoriteHandler(usecase.NewFavoriteUseCase(
+		persistance.NewFavoriteRepository(i.DB),
+		i.newCartUseCase(),
+	))
+}
+
+func (i *Interactor) NewRevisionHandler() handler.RevisionHandler {
+	return handler.NewRevisionHandler(usecase.NewRevisionUseCase(
+		persistance.NewRevisionRepository(i.DB),
+	))
+}
+
+func (i *Interactor) NewRecommendationHandler() handler.RecommendationHandler {
+	return handler.NewRecommendationHandler(i.newRecommendationUseCase())
+}
+
+func (i *Interactor) newRecommendationUseCase() usecase.RecommendationUseCase {
+	return usecase.NewRecommendationUseCase(
+		persistance.NewRecommendationRepository(i.DB),
+		persistance.NewCartRepository(i.DB),
+	)
+}
+
+func (i *Interactor) NewStoreHandler() handler.StoreHandler {
+	return handler.NewStoreHandler(usecase.NewStoreUseCase(
+		persistance.NewStoreRepository(i.DB),
+		i.newProductUseCase(),
+	))
+}
+
+func (i *Interactor) NewAbandonedCartHandler() handler.AbandonedCartHandler {
+	return handler.NewAbandonedCartHandler(i.newAbandonedCartUseCase())
+}
+
+func (i *Interactor) newAbandonedCartUseCase() usecase.AbandonedCartUseCase {
+	return usecase.NewAbandonedCartUseCase(
+		persistance.NewCartRepository(i.DB),
+		thirdparty.LogNotifier{},
+		helper.ParseDuration(config.CART_REMINDER_DELAY, usecase.DefaultCartReminderDelay),
+		helper.ParseDuration(config.CART_RETENTION, usecase.DefaultCartRetention),
+	)
+}
+
+func (i *Interactor) NewIdempotencyHandler() handler.IdempotencyHandler {
+	return handler.NewIdempotencyHandler(i.newIdempotencyUseCase())
+}
+
+func (i *Interactor) newIdempotencyUseCase() usecase.IdempotencyUseCase {
+	return usecase.NewIdempotencyUseCase(
+		persistance.NewIdempotencyRepository(i.DB),
+		helper.ParseDuration(config.IDEMPOTENCY_KEY_RETENTION, usecase.DefaultIdempotencyKeyRetention),
+	)
+}
+
+// StartBackgroundJobs runs the periodic jobs until ctx is done
+func (i *Interactor) StartBackgroundJobs(ctx context.Context) {
+	recommendation := i.newRecommendationUseCase()
+	go recommendation.RunRefresher(ctx, usecase.RecommendationRefreshInterval)
+
+	abandonedCart := i.newAbandonedCartUseCase()
+	go abandonedCart.RunReminder(ctx, usecase.AbandonedCartCheckInterval)
+
+	paymentReconcile := i.newPaymentReconcileUseCase()
+	go paymentReconcile.RunReconciler(ctx, usecase.PaymentReconcileInterval)
+
+	idempotency := i.newIdempotencyUseCase()
+	go idempotency.RunPurger(ctx, usecase.IdempotencyPurgeInterval)
 }
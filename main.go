@@ -23,9 +23,13 @@ import (
 func main() {
 	var dbStore db.DBStore
 	db.Connect(&dbStore)
-	interactor := interactor.Interactor{DB: dbStore.DB}
+	interactor := interactor.Interactor{DB: dbStore.DB, Payment: interactor.NewPaymentProvider()}
 	appHandler := interactor.NewAppHandler()
 
+	jobCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	interactor.StartBackgroundJobs(jobCtx)
+
 	r := chi.NewRouter()
 	r.Use(middleware.RequestID)
 	r.Use(cors.New(cors.Options{
@@ -14,6 +14,11 @@ type contextKey struct {
 }
 
 var TokenCtxKey = &contextKey{name: "tokenPayload"}
+var LocaleCtxKey = &contextKey{name: "locale"}
+var GuestCtxKey = &contextKey{name: "guestID"}
+
+// CartTokenHeader carries the signed cart token of a guest
+const CartTokenHeader = "X-Cart-Token"
 
 func Authentication(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -51,6 +56,28 @@ func Authentication(next http.Handler) http.Handler {
 	})
 }
 
+// CartAuthentication authenticates signed in users like Authentication and
+// lets guests through with a valid cart token instead.
+func CartAuthentication(next http.Handler) http.Handler {
+	authenticated := Authentication(next)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			authenticated.ServeHTTP(w, r)
+			return
+		}
+
+		guestID, err := helper.VerifyGuestToken(r.Header.Get(CartTokenHeader))
+		if err != nil {
+			http.Error(w, "cart token is not valid", http.StatusUnauthorized)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), GuestCtxKey, guestID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
 func AdminOnly(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		claims, ok := r.Context().Value(TokenCtxKey).(*helper.MyClaims)
@@ -61,3 +88,17 @@ func AdminOnly(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// Locale negotiates the content language from the lang query param or the
+// Accept-Language header and stores it in the request context.
+func Locale(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		locale := helper.NegotiateLocale(r.URL.Query().Get("lang"), r.Header.Get("Accept-Language"))
+
+		w.Header().Set("Content-Language", locale)
+		w.Header().Add("Vary", "Accept-Language")
+
+		ctx := context.WithValue(r.Context(), LocaleCtxKey, locale)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
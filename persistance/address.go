@@ -63,7 +63,7 @@ func (storage *addressRepo) FindAllUserAddresses(ctx context.Context, userID str
 
 func (storage *addressRepo) FindAddress(ctx context.Context, id string) (*entity.Address, error) {
 	sql, _, _ := sq.
-		Select("id", "user_id", "name", "phone", "address", "city", "postal_code").
+		Select("id", "user_id", "name", "phone", "address", "city", "postal_code", "longitude", "latitude").
 		From("user_address").Where("id=$1").ToSql()
 
 	var address entity.Address
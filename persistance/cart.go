@@ -3,6 +3,7 @@ package persistance
 import (
 	"context"
 	dbSql "database/sql"
+	"time"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/jmoiron/sqlx"
@@ -11,67 +12,213 @@ import (
 	"github.com/yosepalexsander/waysbucks-api/repository"
 )
 
+// cartDB runs the cart statements on the database or inside a database
+// transaction
+type cartDB interface {
+	sqlx.ExtContext
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+}
+
 type cartRepo struct {
-	db *sqlx.DB
+	db cartDB
+	// conn begins the database transactions of ExecTx
+	conn *sqlx.DB
 }
 
 func NewCartRepository(db *sqlx.DB) repository.CartRepository {
-	return &cartRepo{db}
+	return &cartRepo{db, db}
 }
 
-func (storage *cartRepo) FindCarts(ctx context.Context, userID string) ([]entity.Cart, error) {
-	sql, _, _ := sq.Select("id", "product_id", "topping_id", "price", "qty").From("carts").Where("user_id=$1").OrderByClause("id DESC").ToSql()
-	productSql, _, _ := sq.Select("id", "name", "image", "price").From("products").Where("id=$1").ToSql()
-	toppingSql, _, _ := sq.Select("id", "name").From("toppings").Where("id IN $1").ToSql()
+// cartTx runs the statements of cartRepo inside a database transaction
+type cartTx struct {
+	cartRepo
+	tx *sqlx.Tx
+}
 
-	carts := []entity.Cart{}
+func (storage *cartRepo) TxBegin(ctx context.Context) (repository.CartTransactioner, error) {
+	tx, err := storage.conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cartTx{cartRepo{db: tx}, tx}, nil
+}
+
+func (storage *cartRepo) ExecTx(ctx context.Context, fn func(repository.CartTransactioner) error) error {
+	tx, err := storage.TxBegin(ctx)
+	if err != nil {
+		return err
+	}
 
-	rows, err := storage.db.QueryxContext(ctx, sql, userID)
+	err = fn(tx)
 	if err != nil {
-		if err == dbSql.ErrNoRows {
-			return carts, nil
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return rbErr
 		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (ct *cartTx) Rollback() error {
+	return ct.tx.Rollback()
+}
+
+func (ct *cartTx) Commit() error {
+	return ct.tx.Commit()
+}
+
+var cartLineColumns = []string{"id", "COALESCE(user_id, '')", "COALESCE(guest_id, '')", "store_id", "product_id", "variant_id", "topping_id", "price", "price_breakdown", "qty"}
+
+func scanCartLine(row *sqlx.Row) (*entity.Cart, error) {
+	var cart entity.Cart
+	err := row.Scan(&cart.Id, &cart.UserId, &cart.GuestId, &cart.StoreId, &cart.ProductId, &cart.VariantId, pq.Array(&cart.ToppingIds), &cart.Price, &cart.Breakdown, &cart.Qty)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cart, nil
+}
+
+// cartOwnerColumn returns the column and the value identifying the cart of
+// the owner
+func cartOwnerColumn(owner entity.CartOwner) (string, string) {
+	if owner.IsGuest() {
+		return "guest_id", owner.GuestId
+	}
+
+	return "user_id", owner.UserId
+}
 
+// nullableOwnerID stores an empty id as NULL
+func nullableOwnerID(id string) *string {
+	if id == "" {
+		return nil
+	}
+
+	return &id
+}
+
+// FindCarts loads the lines with their product and variant in one statement
+// and the toppings of every line in a second one, whatever the cart size
+func (storage *cartRepo) FindCarts(ctx context.Context, owner entity.CartOwner) ([]entity.Cart, error) {
+	column, ownerID := cartOwnerColumn(owner)
+	sql, _, _ := sq.Select("c.id", "c.store_id", "c.product_id", "c.variant_id", "c.topping_id", "c.price", "c.price_breakdown", "c.qty",
+		"COALESCE(v.name, '')", "COALESCE(v.calories, 0)", "COALESCE(v.sugar, 0)", "COALESCE(v.caffeine, 0)", "COALESCE(v.fat, 0)", "v.allergens",
+		"COALESCE(p.name, '')", "COALESCE(p.image, '')", "COALESCE(p.price, 0)", "COALESCE(p.calories, 0)", "COALESCE(p.sugar, 0)",
+		"COALESCE(p.caffeine, 0)", "COALESCE(p.fat, 0)", "p.allergens").
+		From("carts AS c").LeftJoin("product_variants AS v ON v.id = c.variant_id").LeftJoin("products AS p ON p.id = c.product_id").
+		Where("c." + column + "=$1").OrderByClause("c.id DESC").ToSql()
+
+	rows, err := storage.db.QueryxContext(ctx, sql, ownerID)
+	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
+	carts := []entity.Cart{}
+	toppingIDs := []int64{}
 	for rows.Next() {
 		var cart entity.Cart
-		err = rows.Scan(&cart.Id, &cart.ProductId, pq.Array(&cart.ToppingIds), &cart.Price, &cart.Qty)
-		_ = storage.db.QueryRowxContext(ctx, productSql, cart.ProductId).StructScan(&cart.Product)
-
-		if len(cart.ToppingIds) < 1 {
-			cart.Topping = make([]entity.CartTopping, 0)
-		} else {
-			rows, err := storage.db.QueryxContext(ctx, toppingSql, pq.Array(cart.ToppingIds))
-			if err != nil {
-				return nil, err
-			}
+		var variant entity.CartVariant
+		product := &cart.Product
+		err = rows.Scan(&cart.Id, &cart.StoreId, &cart.ProductId, &cart.VariantId, pq.Array(&cart.ToppingIds), &cart.Price, &cart.Breakdown, &cart.Qty,
+			&variant.Name, &variant.Calories, &variant.Sugar, &variant.Caffeine, &variant.Fat, &variant.Allergens,
+			&product.Name, &product.Image, &product.Price, &product.Calories, &product.Sugar, &product.Caffeine, &product.Fat, &product.Allergens)
+		if err != nil {
+			return nil, err
+		}
+
+		cart.UserId, cart.GuestId = owner.UserId, owner.GuestId
+		// a deleted product keeps the line with its id so validation can report it
+		product.Id = cart.ProductId
+
+		if cart.VariantId != nil && variant.Name != "" {
+			variant.Id = *cart.VariantId
+			cart.Variant = &variant
+		}
 
-			for rows.Next() {
-				var topping entity.CartTopping
+		toppingIDs = append(toppingIDs, cart.ToppingIds...)
+		carts = append(carts, cart)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-				err = rows.StructScan(&topping)
-				if err != nil {
-					return nil, err
-				}
+	toppings, err := storage.findCartToppings(ctx, toppingIDs)
+	if err != nil {
+		return nil, err
+	}
 
-				cart.Topping = append(cart.Topping, topping)
+	for i := range carts {
+		carts[i].Topping = make([]entity.CartTopping, 0, len(carts[i].ToppingIds))
+		for _, id := range carts[i].ToppingIds {
+			if topping, ok := toppings[id]; ok {
+				carts[i].Topping = append(carts[i].Topping, topping)
 			}
 		}
 
-		carts = append(carts, cart)
+		carts[i].ComputeNutrition()
 	}
 
 	return carts, nil
 }
 
+// findCartToppings loads the given toppings by id in a single statement
+func (storage *cartRepo) findCartToppings(ctx context.Context, ids []int64) (map[int64]entity.CartTopping, error) {
+	toppings := map[int64]entity.CartTopping{}
+	if len(ids) == 0 {
+		return toppings, nil
+	}
+
+	sql, _, _ := sq.Select("id", "name", "calories", "sugar", "caffeine", "fat", "allergens").From("toppings").Where("id = ANY($1)").ToSql()
+
+	rows, err := storage.db.QueryxContext(ctx, sql, pq.Array(entity.NormalizeToppingIds(ids)))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var topping entity.CartTopping
+		if err := rows.StructScan(&topping); err != nil {
+			return nil, err
+		}
+
+		toppings[int64(topping.Id)] = topping
+	}
+
+	return toppings, rows.Err()
+}
+
+func (storage *cartRepo) FindCart(ctx context.Context, id int, owner entity.CartOwner) (*entity.Cart, error) {
+	column, ownerID := cartOwnerColumn(owner)
+	sql, _, _ := sq.Select(cartLineColumns...).From("carts").Where("id=$1 AND " + column + "=$2").ToSql()
+
+	return scanCartLine(storage.db.QueryRowxContext(ctx, sql, id, ownerID))
+}
+
+// FindMatchingCart returns another line of the same cart with the same store,
+// product, variant and set of toppings
+func (storage *cartRepo) FindMatchingCart(ctx context.Context, cart entity.Cart) (*entity.Cart, error) {
+	column, ownerID := cartOwnerColumn(cart.Owner())
+	sql, _, _ := sq.Select(cartLineColumns...).From("carts").
+		Where(column + "=$1 AND product_id=$2 AND variant_id IS NOT DISTINCT FROM $3 AND store_id IS NOT DISTINCT FROM $4").
+		Where("ARRAY(SELECT DISTINCT unnest(COALESCE(topping_id, '{}')) ORDER BY 1) = $5::INT[] AND id <> $6").
+		OrderByClause("id ASC").Limit(1).ToSql()
+
+	return scanCartLine(storage.db.QueryRowxContext(ctx, sql,
+		ownerID, cart.ProductId, cart.VariantId, cart.StoreId, pq.Array(entity.NormalizeToppingIds(cart.ToppingIds)), cart.Id))
+}
+
 func (storage *cartRepo) SaveCart(ctx context.Context, cart entity.Cart) error {
 	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
 
 	sql, args, _ := psql.Insert("carts").
-		Columns("user_id", "product_id", "price", "qty", "topping_id").
-		Values(cart.UserId, cart.ProductId, cart.Price, cart.Qty, pq.Array(cart.ToppingIds)).ToSql()
+		Columns("user_id", "guest_id", "store_id", "product_id", "variant_id", "price", "price_breakdown", "qty", "topping_id").
+		Values(nullableOwnerID(cart.UserId), nullableOwnerID(cart.GuestId), cart.StoreId, cart.ProductId, cart.VariantId, cart.Price, cart.Breakdown, cart.Qty, pq.Array(cart.ToppingIds)).ToSql()
 
 	_, err := storage.db.ExecContext(ctx, sql, args...)
 	if err != nil {
@@ -81,23 +228,36 @@ func (storage *cartRepo) SaveCart(ctx context.Context, cart entity.Cart) error {
 	return nil
 }
 
-func (storage *cartRepo) UpdateCart(ctx context.Context, id int, userID string, data map[string]interface{}) error {
+// UpdateCart saves the quantity, toppings and price of the line
+func (storage *cartRepo) UpdateCart(ctx context.Context, cart entity.Cart) error {
+	column, ownerID := cartOwnerColumn(cart.Owner())
 	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
 
-	sql, args, _ := psql.Update("carts").SetMap(data).Where(sq.Eq{"id": id, "user_id": userID}).ToSql()
+	sql, args, _ := psql.Update("carts").
+		Set("qty", cart.Qty).
+		Set("topping_id", pq.Array(cart.ToppingIds)).
+		Set("price", cart.Price).
+		Set("price_breakdown", cart.Breakdown).
+		Set("updated_at", sq.Expr("CURRENT_TIMESTAMP")).
+		Where(sq.Eq{"id": cart.Id, column: ownerID}).ToSql()
 
-	_, err := storage.db.ExecContext(ctx, sql, args...)
+	res, err := storage.db.ExecContext(ctx, sql, args...)
 	if err != nil {
 		return err
 	}
 
+	if count, _ := res.RowsAffected(); count == 0 {
+		return dbSql.ErrNoRows
+	}
+
 	return nil
 }
 
-func (storage *cartRepo) DeleteCart(ctx context.Context, id int, userID string) error {
+func (storage *cartRepo) DeleteCart(ctx context.Context, id int, owner entity.CartOwner) error {
+	column, ownerID := cartOwnerColumn(owner)
 	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
 
-	sql, args, _ := psql.Delete("carts").Where(sq.Eq{"id": id, "user_id": userID}).ToSql()
+	sql, args, _ := psql.Delete("carts").Where(sq.Eq{"id": id, column: ownerID}).ToSql()
 
 	_, err := storage.db.ExecContext(ctx, sql, args...)
 
@@ -107,3 +267,49 @@ func (storage *cartRepo) DeleteCart(ctx context.Context, id int, userID string)
 
 	return nil
 }
+
+// FindAbandonedCarts returns the carts whose lines were all last changed
+// before the given time, oldest first
+func (storage *cartRepo) FindAbandonedCarts(ctx context.Context, before time.Time) ([]entity.AbandonedCart, error) {
+	sql, _, _ := sq.Select("COALESCE(c.user_id, '') AS user_id", "COALESCE(c.guest_id, '') AS guest_id",
+		"COALESCE(u.name, '') AS name", "COALESCE(u.email, '') AS email", "COUNT(*) AS lines", "SUM(c.price) AS value",
+		"MAX(c.updated_at) AS last_activity", "MAX(c.reminded_at) AS reminded_at").
+		From("carts AS c").LeftJoin("users AS u ON u.id = c.user_id").
+		GroupBy("c.user_id", "c.guest_id", "u.name", "u.email").
+		Having("MAX(c.updated_at) < $1").OrderBy("last_activity ASC").ToSql()
+
+	carts := []entity.AbandonedCart{}
+	if err := storage.db.SelectContext(ctx, &carts, sql, before); err != nil {
+		return nil, err
+	}
+
+	return carts, nil
+}
+
+func (storage *cartRepo) MarkCartReminded(ctx context.Context, userID string, at time.Time) error {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+
+	sql, args, _ := psql.Update("carts").Set("reminded_at", at).Where(sq.Eq{"user_id": userID}).ToSql()
+
+	_, err := storage.db.ExecContext(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// PurgeCarts deletes the whole carts that were last changed before the given
+// time and returns the number of deleted lines
+func (storage *cartRepo) PurgeCarts(ctx context.Context, before time.Time) (int64, error) {
+	sql := `DELETE FROM carts WHERE COALESCE(user_id, guest_id) IN (
+		SELECT COALESCE(user_id, guest_id) FROM carts GROUP BY 1 HAVING MAX(updated_at) < $1
+	)`
+
+	res, err := storage.db.ExecContext(ctx, sql, before)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
@@ -0,0 +1,62 @@
+package persistance
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"testing"
+
+	"github.com/yosepalexsander/waysbucks-api/entity"
+)
+
+func fakeCartRows(lines int) func(query string) fakeResult {
+	return func(query string) fakeResult {
+		if queryFrom(query, "toppings") {
+			return fakeResult{
+				columns: []string{"id", "name", "calories", "sugar", "caffeine", "fat", "allergens"},
+				rows: [][]driver.Value{
+					{int64(1), "Boba", int64(100), 10.0, 0.0, 1.0, "{soy}"},
+					{int64(2), "Jelly", int64(50), 5.0, 0.0, 0.0, "{}"},
+				},
+			}
+		}
+
+		result := fakeResult{columns: make([]string, 22)}
+		for i := 0; i < lines; i++ {
+			result.rows = append(result.rows, []driver.Value{
+				int64(i + 1), nil, int64(1), nil, "{1,2}", int64(34000), nil, int64(1),
+				"", int64(0), 0.0, 0.0, 0.0, nil,
+				"Latte", "latte.png", int64(30000), int64(150), 12.5, 80.0, 4.2, "{milk}",
+			})
+		}
+		return result
+	}
+}
+
+func BenchmarkFindCarts(b *testing.B) {
+	owner := entity.CartOwner{UserId: "user"}
+
+	for _, lines := range []int{1, 10, 100} {
+		b.Run(fmt.Sprintf("lines=%d", lines), func(b *testing.B) {
+			fake, db := newFakeDB(fakeCartRows(lines))
+			repo := NewCartRepository(db)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				carts, err := repo.FindCarts(context.Background(), owner)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if len(carts) != lines || len(carts[0].Topping) != 2 {
+					b.Fatalf("FindCarts() returned %d lines with %d toppings", len(carts), len(carts[0].Topping))
+				}
+			}
+
+			perOp := float64(fake.Queries()) / float64(b.N)
+			if perOp != 2 {
+				b.Fatalf("FindCarts() ran %v queries per call, want 2", perOp)
+			}
+			b.ReportMetric(perOp, "queries/op")
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package persistance
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync/atomic"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// fakeResult is the canned answer of the fake database to a statement
+type fakeResult struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+// fakeDB is a database/sql driver that answers every statement with
+// respond and counts the round trips made to it
+type fakeDB struct {
+	queries int64
+	respond func(query string) fakeResult
+}
+
+func newFakeDB(respond func(query string) fakeResult) (*fakeDB, *sqlx.DB) {
+	f := &fakeDB{respond: respond}
+	return f, sqlx.NewDb(sql.OpenDB(f), "postgres")
+}
+
+func (f *fakeDB) Queries() int64 {
+	return atomic.LoadInt64(&f.queries)
+}
+
+func (f *fakeDB) Connect(ctx context.Context) (driver.Conn, error) {
+	return &fakeConn{f}, nil
+}
+
+func (f *fakeDB) Driver() driver.Driver {
+	return nil
+}
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	atomic.AddInt64(&c.db.queries, 1)
+	result := c.db.respond(query)
+	return &fakeRows{result: result}, nil
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("fake database does not prepare statements")
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("fake database has no transactions")
+}
+
+type fakeRows struct {
+	result fakeResult
+	next   int
+}
+
+func (r *fakeRows) Columns() []string {
+	return r.result.columns
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.result.rows) {
+		return io.EOF
+	}
+
+	copy(dest, r.result.rows[r.next])
+	r.next++
+	return nil
+}
+
+// queryFrom reports whether the statement reads from the given table first
+func queryFrom(query string, table string) bool {
+	return strings.Contains(query, "FROM "+table+" ")
+}
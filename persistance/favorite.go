@@ -0,0 +1,145 @@
+package persistance
+
+import (
+	"context"
+	"errors"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/yosepalexsander/waysbucks-api/entity"
+	"github.com/yosepalexsander/waysbucks-api/repository"
+)
+
+type favoriteRepo struct {
+	db *sqlx.DB
+}
+
+func NewFavoriteRepository(db *sqlx.DB) repository.FavoriteRepository {
+	return &favoriteRepo{db}
+}
+
+func (storage *favoriteRepo) FindFavoriteProducts(ctx context.Context, userID string) ([]entity.Product, error) {
+	sql, _, _ := sq.Select("p.id", "p.name", "p.description", "p.image", "p.price", "p.is_available", "p.created_at", "p.updated_at").
+		From("user_favorites AS f").
+		Join("products AS p ON p.id = f.product_id").
+		Where("f.user_id=$1").
+		OrderByClause("f.created_at DESC").ToSql()
+
+	products := []entity.Product{}
+
+	rows, err := storage.db.QueryxContext(ctx, sql, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var product entity.Product
+		if err := rows.StructScan(&product); err != nil {
+			return nil, err
+		}
+
+		products = append(products, product)
+	}
+
+	return products, nil
+}
+
+func (storage *favoriteRepo) SaveFavorite(ctx context.Context, userID string, productID int) error {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	sql, args, _ := psql.Insert("user_favorites").
+		Columns("user_id", "product_id").
+		Values(userID, productID).
+		Suffix("ON CONFLICT DO NOTHING").ToSql()
+
+	_, err := storage.db.ExecContext(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (storage *favoriteRepo) DeleteFavorite(ctx context.Context, userID string, productID int) error {
+	sql, _, _ := sq.Delete("user_favorites").Where("user_id=$1 AND product_id=$2").ToSql()
+
+	_, err := storage.db.ExecContext(ctx, sql, userID, productID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (storage *favoriteRepo) FindCustomizations(ctx context.Context, userID string) ([]entity.Customization, error) {
+	sql, _, _ := sq.Select("c.id", "c.name", "c.product_id", "c.variant_id", "c.topping_id", "c.qty", "p.name", "p.image", "p.price").
+		From("saved_customizations AS c").
+		Join("products AS p ON p.id = c.product_id").
+		Where("c.user_id=$1").
+		OrderByClause("c.id DESC").ToSql()
+
+	customizations := []entity.Customization{}
+
+	rows, err := storage.db.QueryxContext(ctx, sql, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		c := entity.Customization{UserId: userID, Product: &entity.CartProduct{}}
+		if err := rows.Scan(&c.Id, &c.Name, &c.ProductId, &c.VariantId, pq.Array(&c.ToppingIds), &c.Qty, &c.Product.Name, &c.Product.Image, &c.Product.Price); err != nil {
+			return nil, err
+		}
+
+		c.Product.Id = c.ProductId
+		customizations = append(customizations, c)
+	}
+
+	return customizations, nil
+}
+
+func (storage *favoriteRepo) FindCustomization(ctx context.Context, id int, userID string) (*entity.Customization, error) {
+	sql, _, _ := sq.Select("id", "user_id", "name", "product_id", "variant_id", "topping_id", "qty").
+		From("saved_customizations").
+		Where("id=$1 AND user_id=$2").ToSql()
+
+	var c entity.Customization
+	err := storage.db.QueryRowxContext(ctx, sql, id, userID).
+		Scan(&c.Id, &c.UserId, &c.Name, &c.ProductId, &c.VariantId, pq.Array(&c.ToppingIds), &c.Qty)
+	if err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+func (storage *favoriteRepo) SaveCustomization(ctx context.Context, c entity.Customization) error {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	sql, args, _ := psql.Insert("saved_customizations").
+		Columns("user_id", "name", "product_id", "variant_id", "topping_id", "qty").
+		Values(c.UserId, c.Name, c.ProductId, c.VariantId, pq.Array(c.ToppingIds), c.Qty).ToSql()
+
+	_, err := storage.db.ExecContext(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (storage *favoriteRepo) DeleteCustomization(ctx context.Context, id int, userID string) error {
+	sql, _, _ := sq.Delete("saved_customizations").Where("id=$1 AND user_id=$2").ToSql()
+
+	result, err := storage.db.ExecContext(ctx, sql, id, userID)
+	if err != nil {
+		return err
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return errors.New("no rows affected")
+	}
+
+	return nil
+}
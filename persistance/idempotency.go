@@ -0,0 +1,79 @@
+package persistance
+
+import (
+	"context"
+	dbSql "database/sql"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/yosepalexsander/waysbucks-api/entity"
+	"github.com/yosepalexsander/waysbucks-api/repository"
+)
+
+type idempotencyRepo struct {
+	db *sqlx.DB
+}
+
+func NewIdempotencyRepository(db *sqlx.DB) repository.IdempotencyRepository {
+	return &idempotencyRepo{db}
+}
+
+func (storage *idempotencyRepo) CreateIdempotencyKey(ctx context.Context, key entity.IdempotencyKey) (*entity.IdempotencyKey, bool, error) {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	sql, args, _ := psql.Insert("idempotency_keys").Columns("owner", "idempotency_key", "request_hash").
+		Values(key.Owner, key.Key, key.RequestHash).
+		Suffix("ON CONFLICT (owner, idempotency_key) DO NOTHING RETURNING created_at").ToSql()
+
+	err := storage.db.QueryRowContext(ctx, sql, args...).Scan(&key.CreatedAt)
+	if err == nil {
+		return &key, true, nil
+	}
+	if err != dbSql.ErrNoRows {
+		return nil, false, err
+	}
+
+	sql, _, _ = sq.Select("owner", "idempotency_key", "request_hash", "COALESCE(status_code, 0) AS status_code", "content_type",
+		"COALESCE(response_body, '') AS response_body", "created_at", "completed_at").
+		From("idempotency_keys").Where("owner=$1 AND idempotency_key=$2").ToSql()
+
+	var stored entity.IdempotencyKey
+	if err := storage.db.QueryRowxContext(ctx, sql, key.Owner, key.Key).StructScan(&stored); err != nil {
+		return nil, false, err
+	}
+
+	return &stored, false, nil
+}
+
+func (storage *idempotencyRepo) CompleteIdempotencyKey(ctx context.Context, key entity.IdempotencyKey) error {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	sql, args, _ := psql.Update("idempotency_keys").
+		Set("status_code", key.StatusCode).
+		Set("content_type", key.ContentType).
+		Set("response_body", key.ResponseBody).
+		Set("completed_at", key.CompletedAt).
+		Where(sq.Eq{"owner": key.Owner, "idempotency_key": key.Key}).ToSql()
+
+	_, err := storage.db.ExecContext(ctx, sql, args...)
+	return err
+}
+
+// DeleteIdempotencyKey frees a key whose request did not complete. A stored
+// response is never deleted before the purge.
+func (storage *idempotencyRepo) DeleteIdempotencyKey(ctx context.Context, owner string, key string) error {
+	sql, _, _ := sq.Delete("idempotency_keys").Where("owner=$1 AND idempotency_key=$2 AND completed_at IS NULL").ToSql()
+
+	_, err := storage.db.ExecContext(ctx, sql, owner, key)
+	return err
+}
+
+func (storage *idempotencyRepo) PurgeIdempotencyKeys(ctx context.Context, before time.Time) (int64, error) {
+	sql, _, _ := sq.Delete("idempotency_keys").Where("created_at < $1").ToSql()
+
+	res, err := storage.db.ExecContext(ctx, sql, before)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
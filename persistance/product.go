@@ -6,10 +6,15 @@ import (
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/yosepalexsander/waysbucks-api/entity"
 	"github.com/yosepalexsander/waysbucks-api/repository"
 )
 
+// productRatingJoin aggregates the visible reviews of every product so the
+// average rating and review count can be selected next to product columns.
+const productRatingJoin = "(SELECT product_id, ROUND(AVG(rating), 2) AS rating, COUNT(*) AS review_count FROM product_reviews WHERE is_hidden = false GROUP BY product_id) AS r ON r.product_id = p.id"
+
 type productRepo struct {
 	db *sqlx.DB
 }
@@ -19,8 +24,9 @@ func NewProductRepository(db *sqlx.DB) repository.ProductRepository {
 }
 
 func (storage *productRepo) FindProducts(ctx context.Context, whereClauses []string, orderClause string) ([]entity.Product, error) {
-	sq := sq.Select("id", "name", "description", "image", "price", "is_available", "created_at", "updated_at").
-		From("products")
+	sq := sq.Select("id", "name", "description", "image", "price", "is_available", "calories", "sugar", "caffeine", "fat", "allergens", "COALESCE(rating, 0) AS rating", "COALESCE(review_count, 0) AS review_count", "created_at", "updated_at").
+		From("products AS p").
+		LeftJoin(productRatingJoin)
 
 	for _, v := range whereClauses {
 		sq = sq.Where(v)
@@ -56,8 +62,9 @@ func (storage *productRepo) FindProducts(ctx context.Context, whereClauses []str
 
 func (storage *productRepo) FindProduct(ctx context.Context, id int) (*entity.Product, error) {
 	sql, _, _ := sq.
-		Select("id", "name", "description", "image", "price", "is_available").
-		From("products").
+		Select("id", "name", "description", "image", "price", "is_available", "calories", "sugar", "caffeine", "fat", "allergens", "COALESCE(rating, 0) AS rating", "COALESCE(review_count, 0) AS review_count").
+		From("products AS p").
+		LeftJoin(productRatingJoin).
 		Where("id=$1").ToSql()
 
 	var product entity.Product
@@ -72,8 +79,9 @@ func (storage *productRepo) FindProduct(ctx context.Context, id int) (*entity.Pr
 func (storage *productRepo) SaveProduct(ctx context.Context, product entity.Product) error {
 	sql, args, _ := sq.StatementBuilder.PlaceholderFormat(sq.Dollar).
 		Insert("products").
-		Columns("name", "description", "image", "price", "is_available").
-		Values(product.Name, product.Description, product.Image, product.Price, product.IsAvailable).ToSql()
+		Columns("name", "description", "image", "price", "is_available", "calories", "sugar", "caffeine", "fat", "allergens").
+		Values(product.Name, product.Description, product.Image, product.Price, product.IsAvailable,
+			product.Calories, product.Sugar, product.Caffeine, product.Fat, product.Allergens).ToSql()
 
 	_, err := storage.db.ExecContext(ctx, sql, args...)
 
@@ -86,7 +94,7 @@ func (storage *productRepo) SaveProduct(ctx context.Context, product entity.Prod
 
 func (storage *productRepo) UpdateProduct(ctx context.Context, id int, newProduct map[string]interface{}) error {
 	sql, args, _ := sq.StatementBuilder.PlaceholderFormat(sq.Dollar).
-		Update("products").SetMap(newProduct).
+		Update("products").SetMap(toColumnValues(newProduct)).
 		Where(sq.Eq{"id": id}).ToSql()
 
 	_, err := storage.db.ExecContext(ctx, sql, args...)
@@ -112,7 +120,7 @@ func (storage *productRepo) DeleteProduct(ctx context.Context, id int) error {
 
 func (s *productRepo) FindToppings(ctx context.Context) ([]entity.ProductTopping, error) {
 	sql, _, _ := sq.
-		Select("id", "name", "image", "price", "is_available").
+		Select("id", "name", "image", "price", "is_available", "calories", "sugar", "caffeine", "fat", "allergens", "updated_at").
 		From("toppings").OrderByClause("created_at DESC").ToSql()
 
 	toppings := []entity.ProductTopping{}
@@ -135,7 +143,7 @@ func (s *productRepo) FindToppings(ctx context.Context) ([]entity.ProductTopping
 
 func (s *productRepo) FindTopping(ctx context.Context, id int) (*entity.ProductTopping, error) {
 	sql, _, _ := sq.
-		Select("id", "name", "image", "price", "is_available").
+		Select("id", "name", "image", "price", "is_available", "calories", "sugar", "caffeine", "fat", "allergens").
 		From("toppings").Where("id=$1").ToSql()
 
 	var topping entity.ProductTopping
@@ -148,11 +156,40 @@ func (s *productRepo) FindTopping(ctx context.Context, id int) (*entity.ProductT
 	return &topping, nil
 }
 
+func (s *productRepo) FindToppingsByIds(ctx context.Context, ids []int64) ([]entity.ProductTopping, error) {
+	sql, _, _ := sq.
+		Select("id", "name", "image", "price", "is_available", "calories", "sugar", "caffeine", "fat", "allergens").
+		From("toppings").Where("id = ANY($1)").ToSql()
+
+	toppings := []entity.ProductTopping{}
+	if len(ids) == 0 {
+		return toppings, nil
+	}
+
+	rows, err := s.db.QueryxContext(ctx, sql, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var topping entity.ProductTopping
+		if err := rows.StructScan(&topping); err != nil {
+			return nil, err
+		}
+
+		toppings = append(toppings, topping)
+	}
+
+	return toppings, nil
+}
+
 func (s *productRepo) SaveTopping(ctx context.Context, topping entity.ProductTopping) error {
 	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
 	sql, args, _ := psql.Insert("toppings").
-		Columns("name", "image", "price", "is_available").
-		Values(topping.Name, topping.Image, topping.Price, topping.IsAvailable).ToSql()
+		Columns("name", "image", "price", "is_available", "calories", "sugar", "caffeine", "fat", "allergens").
+		Values(topping.Name, topping.Image, topping.Price, topping.IsAvailable,
+			topping.Calories, topping.Sugar, topping.Caffeine, topping.Fat, topping.Allergens).ToSql()
 
 	_, err := s.db.ExecContext(ctx, sql, args...)
 	if err != nil {
@@ -164,7 +201,7 @@ func (s *productRepo) SaveTopping(ctx context.Context, topping entity.ProductTop
 
 func (s *productRepo) UpdateTopping(ctx context.Context, id int, newData map[string]interface{}) error {
 	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
-	sql, args, _ := psql.Update("toppings").SetMap(newData).Where(sq.Eq{"id": id}).ToSql()
+	sql, args, _ := psql.Update("toppings").SetMap(toColumnValues(newData)).Where(sq.Eq{"id": id}).ToSql()
 
 	_, err := s.db.ExecContext(ctx, sql, args...)
 	if err != nil {
@@ -184,3 +221,176 @@ func (s *productRepo) DeleteTopping(ctx context.Context, id int) error {
 
 	return nil
 }
+
+func (s *productRepo) FindProductVariants(ctx context.Context, productID int) ([]entity.ProductVariant, error) {
+	sql, _, _ := sq.
+		Select("id", "product_id", "name", "price", "is_available", "calories", "sugar", "caffeine", "fat", "allergens").
+		From("product_variants").Where("product_id=$1").OrderByClause("price ASC, id ASC").ToSql()
+
+	variants := []entity.ProductVariant{}
+
+	rows, err := s.db.QueryxContext(ctx, sql, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var variant entity.ProductVariant
+		if err := rows.StructScan(&variant); err != nil {
+			return nil, err
+		}
+
+		variants = append(variants, variant)
+	}
+
+	return variants, nil
+}
+
+func (s *productRepo) FindVariant(ctx context.Context, id int) (*entity.ProductVariant, error) {
+	sql, _, _ := sq.
+		Select("id", "product_id", "name", "price", "is_available", "calories", "sugar", "caffeine", "fat", "allergens").
+		From("product_variants").Where("id=$1").ToSql()
+
+	var variant entity.ProductVariant
+	if err := s.db.QueryRowxContext(ctx, sql, id).StructScan(&variant); err != nil {
+		return nil, err
+	}
+
+	return &variant, nil
+}
+
+func (s *productRepo) SaveVariant(ctx context.Context, variant entity.ProductVariant) error {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	sql, args, _ := psql.Insert("product_variants").
+		Columns("product_id", "name", "price", "is_available", "calories", "sugar", "caffeine", "fat", "allergens").
+		Values(variant.ProductId, variant.Name, variant.Price, variant.IsAvailable,
+			variant.Calories, variant.Sugar, variant.Caffeine, variant.Fat, variant.Allergens).ToSql()
+
+	_, err := s.db.ExecContext(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *productRepo) DeleteVariant(ctx context.Context, id int, productID int) error {
+	sql, _, _ := sq.Delete("product_variants").Where("id=$1 AND product_id=$2").ToSql()
+
+	_, err := s.db.ExecContext(ctx, sql, id, productID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+type productTx struct {
+	db *sqlx.Tx
+}
+
+func (s *productRepo) TxBegin(ctx context.Context) (repository.ProductTransactioner, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	return &productTx{tx}, err
+}
+
+func (s *productRepo) ExecTx(ctx context.Context, fn func(repository.ProductTransactioner) error) error {
+	tx, err := s.TxBegin(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = fn(tx)
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return rbErr
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (ptx *productTx) SaveProduct(ctx context.Context, product entity.Product) error {
+	sql, args, _ := sq.StatementBuilder.PlaceholderFormat(sq.Dollar).
+		Insert("products").
+		Columns("name", "description", "image", "price", "is_available", "calories", "sugar", "caffeine", "fat", "allergens").
+		Values(product.Name, product.Description, product.Image, product.Price, product.IsAvailable,
+			product.Calories, product.Sugar, product.Caffeine, product.Fat, product.Allergens).ToSql()
+
+	_, err := ptx.db.ExecContext(ctx, sql, args...)
+	return err
+}
+
+func (ptx *productTx) UpdateProduct(ctx context.Context, id int, newProduct map[string]interface{}) error {
+	sql, args, _ := sq.StatementBuilder.PlaceholderFormat(sq.Dollar).
+		Update("products").SetMap(toColumnValues(newProduct)).
+		Where(sq.Eq{"id": id}).ToSql()
+
+	_, err := ptx.db.ExecContext(ctx, sql, args...)
+	return err
+}
+
+func (ptx *productTx) SaveTopping(ctx context.Context, topping entity.ProductTopping) error {
+	sql, args, _ := sq.StatementBuilder.PlaceholderFormat(sq.Dollar).
+		Insert("toppings").
+		Columns("name", "image", "price", "is_available", "calories", "sugar", "caffeine", "fat", "allergens").
+		Values(topping.Name, topping.Image, topping.Price, topping.IsAvailable,
+			topping.Calories, topping.Sugar, topping.Caffeine, topping.Fat, topping.Allergens).ToSql()
+
+	_, err := ptx.db.ExecContext(ctx, sql, args...)
+	return err
+}
+
+func (ptx *productTx) UpdateTopping(ctx context.Context, id int, newData map[string]interface{}) error {
+	sql, args, _ := sq.StatementBuilder.PlaceholderFormat(sq.Dollar).
+		Update("toppings").SetMap(toColumnValues(newData)).
+		Where(sq.Eq{"id": id}).ToSql()
+
+	_, err := ptx.db.ExecContext(ctx, sql, args...)
+	return err
+}
+
+func (ptx *productTx) Rollback() error {
+	return ptx.db.Rollback()
+}
+
+func (ptx *productTx) Commit() error {
+	return ptx.db.Commit()
+}
+
+// nutritionColumns are the only keys of the nested nutrition object that are
+// written as columns
+var nutritionColumns = []string{"calories", "sugar", "caffeine", "fat"}
+
+// toColumnValues converts a decoded json update body into column values. The
+// nested nutrition object is flattened into its columns and arrays, like
+// allergens, are converted into values the postgres driver is able to write.
+func toColumnValues(data map[string]interface{}) map[string]interface{} {
+	if nutrition, ok := data["nutrition"].(map[string]interface{}); ok {
+		delete(data, "nutrition")
+		for _, column := range nutritionColumns {
+			if v, ok := nutrition[column]; ok {
+				data[column] = v
+			}
+		}
+	}
+
+	for k, v := range data {
+		values, ok := v.([]interface{})
+		if !ok {
+			continue
+		}
+
+		array := make(pq.StringArray, 0, len(values))
+		for _, value := range values {
+			if str, ok := value.(string); ok {
+				array = append(array, str)
+			}
+		}
+		data[k] = array
+	}
+
+	return data
+}
@@ -0,0 +1,99 @@
+package persistance
+
+import (
+	"context"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/yosepalexsander/waysbucks-api/entity"
+)
+
+func (s *productRepo) FindProductImages(ctx context.Context, productID int) ([]entity.ProductImage, error) {
+	sql, _, _ := sq.Select("id", "product_id", "image", "alt_text", "sort_order", "is_primary").
+		From("product_images").Where("product_id=$1").
+		OrderByClause("is_primary DESC, sort_order ASC, id ASC").ToSql()
+
+	images := []entity.ProductImage{}
+	if err := s.db.SelectContext(ctx, &images, sql, productID); err != nil {
+		return nil, err
+	}
+
+	return images, nil
+}
+
+// SaveProductImage appends the image at the end of the product gallery
+func (s *productRepo) SaveProductImage(ctx context.Context, image entity.ProductImage) (int, error) {
+	sql := `INSERT INTO product_images (product_id, image, alt_text, sort_order)
+		SELECT $1, $2, $3, COALESCE(MAX(sort_order) + 1, 0) FROM product_images WHERE product_id = $1
+		RETURNING id`
+
+	var id int
+	if err := s.db.QueryRowxContext(ctx, sql, image.ProductId, image.Image, image.AltText).Scan(&id); err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// SetPrimaryProductImage marks the image as the only primary image of the
+// product and uses it as the product image.
+func (s *productRepo) SetPrimaryProductImage(ctx context.Context, productID int, imageID int) error {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	resetSql, resetArgs, _ := psql.Update("product_images").Set("is_primary", false).
+		Where(sq.Eq{"product_id": productID, "is_primary": true}).ToSql()
+	if _, err := tx.ExecContext(ctx, resetSql, resetArgs...); err != nil {
+		return err
+	}
+
+	var image string
+	primarySql, primaryArgs, _ := psql.Update("product_images").Set("is_primary", true).
+		Where(sq.Eq{"id": imageID, "product_id": productID}).Suffix("RETURNING image").ToSql()
+	if err := tx.QueryRowxContext(ctx, primarySql, primaryArgs...).Scan(&image); err != nil {
+		return err
+	}
+
+	productSql, productArgs, _ := psql.Update("products").Set("image", image).Where(sq.Eq{"id": productID}).ToSql()
+	if _, err := tx.ExecContext(ctx, productSql, productArgs...); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ReorderProductImages sets the sort order of the images to their position in imageIDs
+func (s *productRepo) ReorderProductImages(ctx context.Context, productID int, imageIDs []int) error {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for i, id := range imageIDs {
+		sql, args, _ := psql.Update("product_images").Set("sort_order", i).
+			Where(sq.Eq{"id": id, "product_id": productID}).ToSql()
+		if _, err := tx.ExecContext(ctx, sql, args...); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *productRepo) DeleteProductImage(ctx context.Context, id int, productID int) error {
+	sql, _, _ := sq.Delete("product_images").Where("id=$1 AND product_id=$2").ToSql()
+
+	_, err := s.db.ExecContext(ctx, sql, id, productID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
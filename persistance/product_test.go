@@ -0,0 +1,32 @@
+package persistance
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestToColumnValues(t *testing.T) {
+	data := map[string]interface{}{
+		"name": "Ice Latte",
+		"nutrition": map[string]interface{}{
+			"calories":         float64(120),
+			"fat":              float64(2.5),
+			"price = 0; --":    float64(1),
+			"unknown_nutrient": float64(3),
+		},
+		"allergens": []interface{}{"milk", "soy"},
+	}
+
+	want := map[string]interface{}{
+		"name":      "Ice Latte",
+		"calories":  float64(120),
+		"fat":       float64(2.5),
+		"allergens": pq.StringArray{"milk", "soy"},
+	}
+
+	if got := toColumnValues(data); !reflect.DeepEqual(got, want) {
+		t.Errorf("toColumnValues() = %v, want %v", got, want)
+	}
+}
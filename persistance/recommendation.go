@@ -0,0 +1,120 @@
+package persistance
+
+import (
+	"context"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/yosepalexsander/waysbucks-api/entity"
+	"github.com/yosepalexsander/waysbucks-api/repository"
+)
+
+type recommendationRepo struct {
+	db *sqlx.DB
+}
+
+func NewRecommendationRepository(db *sqlx.DB) repository.RecommendationRepository {
+	return &recommendationRepo{db}
+}
+
+// productAffinitySql counts how many paid transactions contain both products.
+// Confidence is the share of transactions with product_id that also contain
+// related_product_id.
+const productAffinitySql = `WITH paid AS (
+	SELECT DISTINCT o.transaction_id, o.product_id FROM orders AS o
+	JOIN transactions AS t ON t.id = o.transaction_id
+	WHERE t.status = ANY($2)
+), base AS (
+	SELECT product_id, COUNT(*) AS total FROM paid GROUP BY product_id
+)
+INSERT INTO product_affinities (product_id, related_product_id, support, confidence)
+SELECT a.product_id, b.product_id, COUNT(*), COUNT(*)::NUMERIC / base.total
+FROM paid AS a
+JOIN paid AS b ON b.transaction_id = a.transaction_id AND b.product_id <> a.product_id
+JOIN base ON base.product_id = a.product_id
+GROUP BY a.product_id, b.product_id, base.total
+HAVING COUNT(*) >= $1`
+
+// toppingAffinitySql counts how many paid orders of a product came with the
+// topping. Confidence is the share of the product orders having the topping.
+const toppingAffinitySql = `WITH paid AS (
+	SELECT o.id, o.product_id, o.topping_id FROM orders AS o
+	JOIN transactions AS t ON t.id = o.transaction_id
+	WHERE t.status = ANY($2)
+), base AS (
+	SELECT product_id, COUNT(*) AS total FROM paid GROUP BY product_id
+)
+INSERT INTO topping_affinities (product_id, topping_id, support, confidence)
+SELECT p.product_id, tp.id, COUNT(*), COUNT(*)::NUMERIC / base.total
+FROM paid AS p
+JOIN toppings AS tp ON tp.id = ANY(p.topping_id)
+JOIN base ON base.product_id = p.product_id
+GROUP BY p.product_id, tp.id, base.total
+HAVING COUNT(*) >= $1`
+
+func (storage *recommendationRepo) FindRelatedProducts(ctx context.Context, productIDs []int64, limit int) ([]entity.RecommendedProduct, error) {
+	sql, _, _ := sq.Select("p.id", "p.name", "p.image", "p.price", "SUM(a.confidence) AS score").
+		From("product_affinities AS a").
+		Join("products AS p ON p.id = a.related_product_id").
+		Where("a.product_id = ANY($1) AND NOT (a.related_product_id = ANY($1)) AND p.is_available").
+		GroupBy("p.id").
+		OrderByClause("score DESC, p.id ASC").
+		Suffix("LIMIT $2").ToSql()
+
+	products := []entity.RecommendedProduct{}
+	if len(productIDs) == 0 {
+		return products, nil
+	}
+
+	if err := storage.db.SelectContext(ctx, &products, sql, pq.Array(productIDs), limit); err != nil {
+		return nil, err
+	}
+
+	return products, nil
+}
+
+func (storage *recommendationRepo) FindRelatedToppings(ctx context.Context, productIDs []int64, limit int) ([]entity.RecommendedTopping, error) {
+	sql, _, _ := sq.Select("t.id", "t.name", "t.image", "t.price", "SUM(a.confidence) AS score").
+		From("topping_affinities AS a").
+		Join("toppings AS t ON t.id = a.topping_id").
+		Where("a.product_id = ANY($1) AND t.is_available").
+		GroupBy("t.id").
+		OrderByClause("score DESC, t.id ASC").
+		Suffix("LIMIT $2").ToSql()
+
+	toppings := []entity.RecommendedTopping{}
+	if len(productIDs) == 0 {
+		return toppings, nil
+	}
+
+	if err := storage.db.SelectContext(ctx, &toppings, sql, pq.Array(productIDs), limit); err != nil {
+		return nil, err
+	}
+
+	return toppings, nil
+}
+
+// RefreshAffinities recomputes every affinity from the order history in one
+// transaction so readers never see a half refreshed table.
+func (storage *recommendationRepo) RefreshAffinities(ctx context.Context, minSupport int) error {
+	tx, err := storage.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, sql := range []string{"DELETE FROM product_affinities", "DELETE FROM topping_affinities"} {
+		if _, err := tx.ExecContext(ctx, sql); err != nil {
+			return err
+		}
+	}
+
+	for _, sql := range []string{productAffinitySql, toppingAffinitySql} {
+		if _, err := tx.ExecContext(ctx, sql, minSupport, pq.Array(entity.PaidStatuses)); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
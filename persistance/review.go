@@ -0,0 +1,142 @@
+package persistance
+
+import (
+	"context"
+	dbSql "database/sql"
+	"errors"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/yosepalexsander/waysbucks-api/entity"
+	"github.com/yosepalexsander/waysbucks-api/repository"
+)
+
+type reviewRepo struct {
+	db *sqlx.DB
+}
+
+func NewReviewRepository(db *sqlx.DB) repository.ReviewRepository {
+	return &reviewRepo{db}
+}
+
+func (storage *reviewRepo) FindProductReviews(ctx context.Context, productID int, includeHidden bool) ([]entity.Review, error) {
+	builder := sq.Select("r.id", "r.product_id", "r.user_id", "u.name AS user_name", "r.rating", "r.comment", "r.is_hidden", "r.is_flagged", "r.created_at", "r.updated_at").
+		From("product_reviews AS r").
+		Join("users AS u ON u.id = r.user_id").
+		Where("r.product_id = $1")
+
+	if !includeHidden {
+		builder = builder.Where("r.is_hidden = false")
+	}
+
+	sql, _, _ := builder.OrderByClause("r.created_at DESC").ToSql()
+
+	return storage.findReviews(ctx, sql, productID)
+}
+
+func (storage *reviewRepo) FindFlaggedReviews(ctx context.Context) ([]entity.Review, error) {
+	sql, _, _ := sq.Select("r.id", "r.product_id", "r.user_id", "u.name AS user_name", "r.rating", "r.comment", "r.is_hidden", "r.is_flagged", "r.created_at", "r.updated_at").
+		From("product_reviews AS r").
+		Join("users AS u ON u.id = r.user_id").
+		Where("r.is_flagged = true").
+		OrderByClause("r.updated_at DESC").ToSql()
+
+	return storage.findReviews(ctx, sql)
+}
+
+func (storage *reviewRepo) findReviews(ctx context.Context, sql string, args ...interface{}) ([]entity.Review, error) {
+	reviews := []entity.Review{}
+
+	rows, err := storage.db.QueryxContext(ctx, sql, args...)
+	if err != nil {
+		if err == dbSql.ErrNoRows {
+			return reviews, nil
+		}
+
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var review entity.Review
+		if err := rows.StructScan(&review); err != nil {
+			return nil, err
+		}
+
+		reviews = append(reviews, review)
+	}
+
+	return reviews, nil
+}
+
+func (storage *reviewRepo) FindReview(ctx context.Context, id int) (*entity.Review, error) {
+	sql, _, _ := sq.Select("r.id", "r.product_id", "r.user_id", "u.name AS user_name", "r.rating", "r.comment", "r.is_hidden", "r.is_flagged", "r.created_at", "r.updated_at").
+		From("product_reviews AS r").
+		Join("users AS u ON u.id = r.user_id").
+		Where("r.id = $1").ToSql()
+
+	var review entity.Review
+	if err := storage.db.QueryRowxContext(ctx, sql, id).StructScan(&review); err != nil {
+		return nil, err
+	}
+
+	return &review, nil
+}
+
+func (storage *reviewRepo) HasPurchasedProduct(ctx context.Context, userID string, productID int) (bool, error) {
+	sql, _, _ := sq.Select("EXISTS (SELECT 1 FROM transactions AS t JOIN orders AS o ON o.transaction_id = t.id WHERE t.user_id = $1 AND o.product_id = $2 AND t.status = ANY($3))").ToSql()
+
+	var purchased bool
+	if err := storage.db.QueryRowxContext(ctx, sql, userID, productID, pq.Array(entity.PaidStatuses)).Scan(&purchased); err != nil {
+		return false, err
+	}
+
+	return purchased, nil
+}
+
+func (storage *reviewRepo) SaveReview(ctx context.Context, review entity.Review) error {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	sql, args, _ := psql.Insert("product_reviews").
+		Columns("product_id", "user_id", "rating", "comment").
+		Values(review.ProductId, review.UserId, review.Rating, review.Comment).
+		Suffix("ON CONFLICT (product_id, user_id) DO UPDATE SET rating = EXCLUDED.rating, comment = EXCLUDED.comment").ToSql()
+
+	_, err := storage.db.ExecContext(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (storage *reviewRepo) UpdateReview(ctx context.Context, id int, data map[string]interface{}) error {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	sql, args, _ := psql.Update("product_reviews").SetMap(data).Where(sq.Eq{"id": id}).ToSql()
+
+	result, err := storage.db.ExecContext(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return dbSql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (storage *reviewRepo) DeleteReview(ctx context.Context, id int, userID string) error {
+	sql, _, _ := sq.Delete("product_reviews").Where("id=$1 AND user_id=$2").ToSql()
+
+	result, err := storage.db.ExecContext(ctx, sql, id, userID)
+	if err != nil {
+		return err
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return errors.New("no rows affected")
+	}
+
+	return nil
+}
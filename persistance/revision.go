@@ -0,0 +1,77 @@
+package persistance
+
+import (
+	"context"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/yosepalexsander/waysbucks-api/entity"
+	"github.com/yosepalexsander/waysbucks-api/repository"
+)
+
+type revisionRepo struct {
+	db *sqlx.DB
+}
+
+func NewRevisionRepository(db *sqlx.DB) repository.RevisionRepository {
+	return &revisionRepo{db}
+}
+
+func (storage *revisionRepo) FindProductRevisions(ctx context.Context, productID int) ([]entity.ProductRevision, error) {
+	sql, _, _ := sq.Select("id", "product_id", "name", "description", "image", "price", "is_available", "is_deleted", "effective_at").
+		From("product_revisions").Where("product_id=$1").
+		OrderByClause("effective_at DESC, id DESC").ToSql()
+
+	revisions := []entity.ProductRevision{}
+	if err := storage.db.SelectContext(ctx, &revisions, sql, productID); err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+func (storage *revisionRepo) FindToppingRevisions(ctx context.Context, toppingID int) ([]entity.ToppingRevision, error) {
+	sql, _, _ := sq.Select("id", "topping_id", "name", "image", "price", "is_available", "is_deleted", "effective_at").
+		From("topping_revisions").Where("topping_id=$1").
+		OrderByClause("effective_at DESC, id DESC").ToSql()
+
+	revisions := []entity.ToppingRevision{}
+	if err := storage.db.SelectContext(ctx, &revisions, sql, toppingID); err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+// FindProductsAt returns the latest revision of every product that existed at the given time
+func (storage *revisionRepo) FindProductsAt(ctx context.Context, at time.Time) ([]entity.ProductRevision, error) {
+	latest := sq.Select("DISTINCT ON (product_id) id", "product_id", "name", "description", "image", "price", "is_available", "is_deleted", "effective_at").
+		From("product_revisions").Where("effective_at <= $1").
+		OrderByClause("product_id, effective_at DESC, id DESC")
+
+	sql, _, _ := sq.Select("*").FromSelect(latest, "r").Where("NOT r.is_deleted").OrderByClause("r.product_id").ToSql()
+
+	revisions := []entity.ProductRevision{}
+	if err := storage.db.SelectContext(ctx, &revisions, sql, at); err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+// FindToppingsAt returns the latest revision of every topping that existed at the given time
+func (storage *revisionRepo) FindToppingsAt(ctx context.Context, at time.Time) ([]entity.ToppingRevision, error) {
+	latest := sq.Select("DISTINCT ON (topping_id) id", "topping_id", "name", "image", "price", "is_available", "is_deleted", "effective_at").
+		From("topping_revisions").Where("effective_at <= $1").
+		OrderByClause("topping_id, effective_at DESC, id DESC")
+
+	sql, _, _ := sq.Select("*").FromSelect(latest, "r").Where("NOT r.is_deleted").OrderByClause("r.topping_id").ToSql()
+
+	revisions := []entity.ToppingRevision{}
+	if err := storage.db.SelectContext(ctx, &revisions, sql, at); err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
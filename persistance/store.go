@@ -0,0 +1,204 @@
+package persistance
+
+import (
+	"context"
+	dbSql "database/sql"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/yosepalexsander/waysbucks-api/entity"
+	"github.com/yosepalexsander/waysbucks-api/repository"
+)
+
+type storeRepo struct {
+	db *sqlx.DB
+}
+
+func NewStoreRepository(db *sqlx.DB) repository.StoreRepository {
+	return &storeRepo{db}
+}
+
+func (storage *storeRepo) FindStores(ctx context.Context) ([]entity.Store, error) {
+	sql, _, _ := sq.Select("id", "name", "address", "city", "longitude", "latitude", "timezone", "opening_hours", "is_active").
+		From("stores").OrderByClause("id ASC").ToSql()
+
+	stores := []entity.Store{}
+	if err := storage.db.SelectContext(ctx, &stores, sql); err != nil {
+		return nil, err
+	}
+
+	return stores, nil
+}
+
+func (storage *storeRepo) FindStore(ctx context.Context, id int) (*entity.Store, error) {
+	sql, _, _ := sq.Select("id", "name", "address", "city", "longitude", "latitude", "timezone", "opening_hours", "is_active").
+		From("stores").Where("id=$1").ToSql()
+
+	var store entity.Store
+	if err := storage.db.QueryRowxContext(ctx, sql, id).StructScan(&store); err != nil {
+		return nil, err
+	}
+
+	return &store, nil
+}
+
+func (storage *storeRepo) FindStaffStoreIDs(ctx context.Context, userID string) ([]int64, error) {
+	sql, _, _ := sq.Select("store_id").From("store_staff").Where("user_id=$1").OrderByClause("store_id").ToSql()
+
+	ids := []int64{}
+	if err := storage.db.SelectContext(ctx, &ids, sql, userID); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+func (storage *storeRepo) FindStoreProducts(ctx context.Context, storeID int) ([]entity.StoreItem, error) {
+	sql, _, _ := sq.Select("store_id", "product_id AS item_id", "price", "is_available").
+		From("store_products").Where("store_id=$1").ToSql()
+
+	items := []entity.StoreItem{}
+	if err := storage.db.SelectContext(ctx, &items, sql, storeID); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+func (storage *storeRepo) FindStoreToppings(ctx context.Context, storeID int) ([]entity.StoreItem, error) {
+	sql, _, _ := sq.Select("store_id", "topping_id AS item_id", "price", "is_available").
+		From("store_toppings").Where("store_id=$1").ToSql()
+
+	items := []entity.StoreItem{}
+	if err := storage.db.SelectContext(ctx, &items, sql, storeID); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+func (storage *storeRepo) SaveStore(ctx context.Context, store entity.Store) error {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	sql, args, _ := psql.Insert("stores").
+		Columns("name", "address", "city", "longitude", "latitude", "timezone", "opening_hours", "is_active").
+		Values(store.Name, store.Address, store.City, store.Longitude, store.Latitude, store.Timezone, store.OpeningHours, store.IsActive).ToSql()
+
+	_, err := storage.db.ExecContext(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (storage *storeRepo) UpdateStore(ctx context.Context, id int, store entity.Store) error {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	sql, args, _ := psql.Update("stores").
+		SetMap(map[string]interface{}{
+			"name":          store.Name,
+			"address":       store.Address,
+			"city":          store.City,
+			"longitude":     store.Longitude,
+			"latitude":      store.Latitude,
+			"timezone":      store.Timezone,
+			"opening_hours": store.OpeningHours,
+			"is_active":     store.IsActive,
+		}).
+		Where(sq.Eq{"id": id}).ToSql()
+
+	res, err := storage.db.ExecContext(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+
+	if count, _ := res.RowsAffected(); count == 0 {
+		return dbSql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (storage *storeRepo) DeleteStore(ctx context.Context, id int) error {
+	sql, _, _ := sq.Delete("stores").Where("id=$1").ToSql()
+
+	_, err := storage.db.ExecContext(ctx, sql, id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (storage *storeRepo) SaveStaff(ctx context.Context, storeID int, userID string) error {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	sql, args, _ := psql.Insert("store_staff").Columns("store_id", "user_id").
+		Values(storeID, userID).Suffix("ON CONFLICT DO NOTHING").ToSql()
+
+	_, err := storage.db.ExecContext(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (storage *storeRepo) DeleteStaff(ctx context.Context, storeID int, userID string) error {
+	sql, _, _ := sq.Delete("store_staff").Where("store_id=$1 AND user_id=$2").ToSql()
+
+	_, err := storage.db.ExecContext(ctx, sql, storeID, userID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (storage *storeRepo) SaveStoreProduct(ctx context.Context, item entity.StoreItem) error {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	sql, args, _ := psql.Insert("store_products").Columns("store_id", "product_id", "price", "is_available").
+		Values(item.StoreId, item.ItemId, item.Price, item.IsAvailable).
+		Suffix("ON CONFLICT (store_id, product_id) DO UPDATE SET price = EXCLUDED.price, is_available = EXCLUDED.is_available").ToSql()
+
+	_, err := storage.db.ExecContext(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (storage *storeRepo) DeleteStoreProduct(ctx context.Context, storeID int, productID int) error {
+	sql, _, _ := sq.Delete("store_products").Where("store_id=$1 AND product_id=$2").ToSql()
+
+	_, err := storage.db.ExecContext(ctx, sql, storeID, productID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (storage *storeRepo) SaveStoreTopping(ctx context.Context, item entity.StoreItem) error {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	sql, args, _ := psql.Insert("store_toppings").Columns("store_id", "topping_id", "price", "is_available").
+		Values(item.StoreId, item.ItemId, item.Price, item.IsAvailable).
+		Suffix("ON CONFLICT (store_id, topping_id) DO UPDATE SET price = EXCLUDED.price, is_available = EXCLUDED.is_available").ToSql()
+
+	_, err := storage.db.ExecContext(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (storage *storeRepo) DeleteStoreTopping(ctx context.Context, storeID int, toppingID int) error {
+	sql, _, _ := sq.Delete("store_toppings").Where("store_id=$1 AND topping_id=$2").ToSql()
+
+	_, err := storage.db.ExecContext(ctx, sql, storeID, toppingID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	dbSql "database/sql"
 	"encoding/json"
+	"time"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/jmoiron/sqlx"
@@ -21,140 +22,182 @@ type sqlConnTx struct {
 	db *sql.Tx
 }
 
+// queryer runs a query on the database or inside a database transaction
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*dbSql.Rows, error)
+}
+
 func NewTransactionRepository(db *sqlx.DB) repository.TransactionRepository {
 	return &transactionRepo{db}
 }
 
-func (storage *transactionRepo) FindTransactions(ctx context.Context) ([]entity.Transaction, error) {
-	sql, _, _ := sq.Select("t.id", "t.name", "t.address", "t.phone", "t.city", "t.postal_code", "t.total", "t.status",
-		"json_agg(json_build_object('id', o.id, 'name', p.name,'image', p.image, 'topping_id', o.topping_id, 'price', o.price, 'qty', o.qty) ORDER BY o.id) AS order").
-		From("transactions AS t, orders AS o, products AS p").Where("t.id = o.transaction_id AND o.product_id = p.id").GroupBy("t.id").
-		OrderByClause("t.created_at DESC").ToSql()
+// selectTransactions reads transactions with their orders, products,
+// variants and toppings aggregated as JSON, so any number of transactions is
+// loaded in a single statement
+func selectTransactions() sq.SelectBuilder {
+	return sq.Select("t.id", "COALESCE(t.user_id, '')", "t.name", "t.address", "t.phone", "t.city", "t.postal_code", "t.address_id", "t.longitude", "t.latitude", "t.total", "t.status", "t.store_id", "t.price_breakdown",
+		`json_agg(json_build_object('id', o.id, 'product_id', o.product_id, 'name', p.name, 'image', p.image, 'price', o.price, 'qty', o.qty,
+			'variant_id', o.variant_id, 'variant_name', pv.name, 'topping_ids', o.topping_id,
+			'toppings', COALESCE((SELECT json_agg(json_build_object('id', tp.id, 'name', tp.name) ORDER BY tp.id)
+				FROM toppings AS tp WHERE tp.id = ANY(o.topping_id)), '[]'::json)) ORDER BY o.id) AS order`).
+		From("transactions AS t").Join("orders AS o ON o.transaction_id = t.id").Join("products AS p ON p.id = o.product_id").
+		LeftJoin("product_variants AS pv ON pv.id = o.variant_id").GroupBy("t.id").
+		OrderByClause("t.created_at DESC")
+}
+
+func scanTransaction(row sq.RowScanner) (*entity.Transaction, error) {
+	var t entity.Transaction
+	var orderJSON []byte
+	if err := row.Scan(&t.Id, &t.UserId, &t.Name, &t.Address, &t.Phone, &t.City, &t.PostalCode, &t.AddressId, &t.Longitude, &t.Latitude, &t.Total, &t.Status, &t.StoreId, &t.Breakdown, &orderJSON); err != nil {
+		return nil, err
+	}
 
-	toppingSql, _, _ := sq.Select("id", "name").From("toppings").Where("id IN $1").ToSql()
+	if err := json.Unmarshal(orderJSON, &t.Orders); err != nil {
+		return nil, err
+	}
 
-	transactions := []entity.Transaction{}
+	if t.Breakdown != nil {
+		t.ServiceFee = t.Breakdown.Fee(entity.FeeService)
+		t.DeliveryFee = t.Breakdown.Fee(entity.FeeDelivery)
+	}
 
-	rows, err := storage.db.QueryxContext(ctx, sql)
-	if err != nil {
-		if err == dbSql.ErrNoRows {
-			return transactions, nil
-		}
+	return &t, nil
+}
 
+func (storage *transactionRepo) queryTransactions(ctx context.Context, sql string, args ...interface{}) ([]entity.Transaction, error) {
+	rows, err := storage.db.QueryxContext(ctx, sql, args...)
+	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
+	transactions := []entity.Transaction{}
 	for rows.Next() {
-		var t entity.Transaction
-		var orderJSON []byte
-		if err = rows.Scan(&t.Id, &t.Name, &t.Address, &t.Phone, &t.City, &t.PostalCode, &t.Total, &t.Status, &orderJSON); err != nil {
+		t, err := scanTransaction(rows)
+		if err != nil {
 			return nil, err
 		}
-		_ = json.Unmarshal(orderJSON, &t.Orders)
-		for i := range t.Orders {
-			rows, err := storage.db.QueryxContext(ctx, toppingSql, pq.Array(t.Orders[i].ToppingIds))
-			if err != nil {
-				return nil, err
-			}
-
-			for rows.Next() {
-				var topping entity.OrderTopping
-
-				err = rows.StructScan(&topping)
-				if err != nil {
-					return nil, err
-				}
-
-				t.Orders[i].Toppings = append(t.Orders[i].Toppings, topping)
-			}
-			t.Orders[i].ToppingIds = nil
-		}
-		transactions = append(transactions, t)
+
+		transactions = append(transactions, *t)
 	}
-	return transactions, nil
+
+	return transactions, rows.Err()
+}
+
+// FindTransactions returns the transactions of the given stores, or of every
+// store when storeIDs is nil
+func (storage *transactionRepo) FindTransactions(ctx context.Context, storeIDs []int64) ([]entity.Transaction, error) {
+	sql, _, _ := selectTransactions().Where("($1::INT[] IS NULL OR t.store_id = ANY($1))").ToSql()
+
+	return storage.queryTransactions(ctx, sql, pq.Array(storeIDs))
 }
 
 func (storage *transactionRepo) FindUserTransactions(ctx context.Context, userID string) ([]entity.Transaction, error) {
-	sql, _, _ := sq.Select("t.id", "t.name", "t.address", "t.phone", "t.city", "t.postal_code", "t.total", "t.status",
-		"json_agg(json_build_object('id', o.id, 'name', p.name,'image', p.image, 'topping_id', o.topping_id, 'price', o.price, 'qty', o.qty) ORDER BY o.id) AS order").
-		From("transactions AS t, orders AS o, products AS p").Where("t.id = o.transaction_id AND t.user_id = $1 AND o.product_id = p.id").GroupBy("t.id").
-		OrderByClause("t.created_at DESC").ToSql()
+	sql, _, _ := selectTransactions().Where("t.user_id = $1").ToSql()
 
-	toppingSql, _, _ := sq.Select("id", "name").From("toppings").Where("id=$1").ToSql()
+	return storage.queryTransactions(ctx, sql, userID)
+}
 
-	transactions := []entity.Transaction{}
+func (storage *transactionRepo) FindTransactionByID(ctx context.Context, id string) (*entity.Transaction, error) {
+	sql, _, _ := selectTransactions().Where("t.id = $1").ToSql()
 
-	rows, err := storage.db.QueryxContext(ctx, sql, userID)
-	if err != nil {
-		if err == dbSql.ErrNoRows {
-			return transactions, nil
-		}
+	return scanTransaction(storage.db.QueryRowxContext(ctx, sql, id))
+}
+
+func (storage *transactionRepo) FindTransactionEvents(ctx context.Context, transactionID string) ([]entity.TransactionEvent, error) {
+	sql, _, _ := sq.Select("id", "transaction_id", "from_status", "to_status", "actor", "actor_id", "note", "created_at").
+		From("transaction_events").Where("transaction_id = $1").OrderByClause("id ASC").ToSql()
+
+	events := []entity.TransactionEvent{}
+	if err := storage.db.SelectContext(ctx, &events, sql, transactionID); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+func (storage *transactionRepo) FindRefunds(ctx context.Context, transactionID string) ([]entity.Refund, error) {
+	return findRefunds(ctx, storage.db, transactionID)
+}
+
+func findRefunds(ctx context.Context, db queryer, transactionID string) ([]entity.Refund, error) {
+	sql, _, _ := sq.Select("id", "transaction_id", "refund_key", "amount", "order_id", "reason", "status", "actor_id", "created_at", "updated_at").
+		From("refunds").Where("transaction_id = $1").OrderByClause("id ASC").ToSql()
 
+	rows, err := db.QueryContext(ctx, sql, transactionID)
+	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
+	refunds := []entity.Refund{}
 	for rows.Next() {
-		var t entity.Transaction
-		var orderJSON []byte
-		if err = rows.Scan(&t.Id, &t.Name, &t.Address, &t.Phone, &t.City, &t.PostalCode, &t.Total, &t.Status, &orderJSON); err != nil {
+		var r entity.Refund
+		if err := rows.Scan(&r.Id, &r.TransactionId, &r.RefundKey, &r.Amount, pq.Array(&r.OrderIds), &r.Reason, &r.Status, &r.ActorId, &r.CreatedAt, &r.UpdatedAt); err != nil {
 			return nil, err
 		}
-		_ = json.Unmarshal(orderJSON, &t.Orders)
-		for i := range t.Orders {
-			for _, v := range t.Orders[i].ToppingIds {
-				var topping entity.OrderTopping
-				if err = storage.db.QueryRowxContext(ctx, toppingSql, v).Scan(&topping.Id, &topping.Name); err != nil {
-					return nil, err
-				}
-
-				t.Orders[i].Toppings = append(t.Orders[i].Toppings, topping)
-			}
-			t.Orders[i].ToppingIds = nil
-		}
-		transactions = append(transactions, t)
+
+		refunds = append(refunds, r)
 	}
-	return transactions, nil
+
+	return refunds, rows.Err()
 }
 
-func (storage *transactionRepo) FindTransactionByID(ctx context.Context, id string) (*entity.Transaction, error) {
-	sql, _, _ := sq.Select("t.id", "t.name", "t.address", "t.phone", "t.city", "t.postal_code", "t.total", "t.status",
-		"json_agg(json_build_object('id', o.id, 'name', p.name,'image', p.image, 'topping_id', o.topping_id, 'price', o.price, 'qty', o.qty) ORDER BY o.id) AS order").
-		From("transactions AS t, orders AS o, products AS p").Where("t.id = $1 AND t.id = o.transaction_id AND o.product_id = p.id").GroupBy("t.id").
-		OrderByClause("t.created_at DESC").ToSql()
-	toppingSql, _, _ := sq.Select("id", "name").From("toppings").Where("id=$1").ToSql()
+// FindPendingPayments returns the transactions pending payment since before,
+// oldest first
+func (storage *transactionRepo) FindPendingPayments(ctx context.Context, before time.Time) ([]entity.PendingPayment, error) {
+	sql, _, _ := sq.Select("id", "total", "created_at").From("transactions").
+		Where("status = $1 AND created_at < $2").OrderByClause("created_at ASC").ToSql()
 
-	var t entity.Transaction
-	var orderJSON []byte
-	row := storage.db.QueryRowxContext(ctx, sql, id)
-	if err := row.Scan(&t.Id, &t.Name, &t.Address, &t.Phone, &t.City, &t.PostalCode, &t.Total, &t.Status, &orderJSON); err != nil {
+	payments := []entity.PendingPayment{}
+	if err := storage.db.SelectContext(ctx, &payments, sql, entity.StatusPendingPayment, before); err != nil {
 		return nil, err
 	}
-	_ = json.Unmarshal(orderJSON, &t.Orders)
-	for i := range t.Orders {
-		for _, v := range t.Orders[i].ToppingIds {
-			var topping entity.OrderTopping
-			if err := storage.db.QueryRowxContext(ctx, toppingSql, v).Scan(&topping.Id, &topping.Name); err != nil {
-				return nil, err
-			}
 
-			t.Orders[i].Toppings = append(t.Orders[i].Toppings, topping)
-		}
-		t.Orders[i].ToppingIds = nil
-	}
-	return &t, nil
+	return payments, nil
 }
 
-func (storage *transactionRepo) UpdateTransaction(ctx context.Context, id string, data map[string]interface{}) error {
-	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+func paymentNotificationColumns() []string {
+	return []string{"id", "transaction_id", "status", "refund_keys", "gross_amount", "note", "dedupe_key", "payload", "processed_at", "error", "created_at"}
+}
+
+func scanPaymentNotification(row sq.RowScanner) (*entity.PaymentNotification, error) {
+	var n entity.PaymentNotification
+	var payload []byte
+	if err := row.Scan(&n.Id, &n.TransactionId, &n.Status, pq.Array(&n.RefundKeys), &n.GrossAmount, &n.Note, &n.DedupeKey, &payload, &n.ProcessedAt, &n.Error, &n.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	n.Payload = payload
+	return &n, nil
+}
 
-	sql, args, _ := psql.Update("transactions").SetMap(data).ToSql()
+func (storage *transactionRepo) FindPaymentNotifications(ctx context.Context, transactionID string) ([]entity.PaymentNotification, error) {
+	sql, _, _ := sq.Select(paymentNotificationColumns()...).From("payment_notifications").Where("transaction_id = $1").OrderByClause("id ASC").ToSql()
 
-	_, err := storage.db.ExecContext(ctx, sql, args...)
+	rows, err := storage.db.QueryContext(ctx, sql, transactionID)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	defer rows.Close()
 
-	return nil
+	notifications := []entity.PaymentNotification{}
+	for rows.Next() {
+		n, err := scanPaymentNotification(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		notifications = append(notifications, *n)
+	}
+
+	return notifications, rows.Err()
+}
+
+func (storage *transactionRepo) FindPaymentNotification(ctx context.Context, id int) (*entity.PaymentNotification, error) {
+	sql, _, _ := sq.Select(paymentNotificationColumns()...).From("payment_notifications").Where("id = $1").ToSql()
+
+	return scanPaymentNotification(storage.db.QueryRowContext(ctx, sql, id))
 }
 
 func (storage *transactionRepo) TxBegin(ctx context.Context) (repository.Transactioner, error) {
@@ -184,8 +227,8 @@ func (sct *sqlConnTx) CreateTransaction(ctx context.Context, tx entity.Transacti
 	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
 
 	var id string
-	sql, args, _ := psql.Insert("transactions").Columns("id", "user_id", "name", "address", "city", "postal_code", "phone", "total", "status").
-		Values(tx.Id, tx.UserId, tx.Name, tx.Address, tx.City, tx.PostalCode, tx.Phone, tx.Total, tx.Status).Suffix("RETURNING id").ToSql()
+	sql, args, _ := psql.Insert("transactions").Columns("id", "user_id", "store_id", "name", "address", "city", "postal_code", "phone", "address_id", "longitude", "latitude", "total", "price_breakdown", "status").
+		Values(tx.Id, tx.UserId, tx.StoreId, tx.Name, tx.Address, tx.City, tx.PostalCode, tx.Phone, tx.AddressId, tx.Longitude, tx.Latitude, tx.Total, tx.Breakdown, tx.Status).Suffix("RETURNING id").ToSql()
 
 	err := sct.db.QueryRowContext(ctx, sql, args...).Scan(&id)
 
@@ -198,18 +241,118 @@ func (sct *sqlConnTx) CreateTransaction(ctx context.Context, tx entity.Transacti
 func (sct *sqlConnTx) CreateOrder(ctx context.Context, order entity.Order) error {
 	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
 	var err error
-	sql, args, _ := psql.Insert("orders").Columns("transaction_id", "product_id", "topping_id", "price", "qty").
-		Values(order.TransactionId, order.ProductId, pq.Array(order.ToppingIds), order.Price, order.Qty).ToSql()
+	sql, args, _ := psql.Insert("orders").Columns("transaction_id", "product_id", "variant_id", "topping_id", "price", "qty").
+		Values(order.TransactionId, order.ProductId, order.VariantId, pq.Array(order.ToppingIds), order.Price, order.Qty).ToSql()
 
 	_, err = sct.db.ExecContext(ctx, sql, args...)
 	return err
 }
 
-func (sct *sqlConnTx) DeleteCart(ctx context.Context, productID int, userID string) error {
+// UpdateTransactionStatus moves the transaction from one status to another.
+// It returns ErrNoRows when the transaction is not in the from status anymore.
+func (sct *sqlConnTx) UpdateTransactionStatus(ctx context.Context, id string, from string, to string) error {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	sql, args, _ := psql.Update("transactions").
+		Set("status", to).
+		Set("updated_at", sq.Expr("CURRENT_TIMESTAMP")).
+		Where(sq.Eq{"id": id, "status": from}).ToSql()
+
+	res, err := sct.db.ExecContext(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+
+	if count, _ := res.RowsAffected(); count == 0 {
+		return dbSql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (sct *sqlConnTx) CreateTransactionEvent(ctx context.Context, event entity.TransactionEvent) error {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	sql, args, _ := psql.Insert("transaction_events").Columns("transaction_id", "from_status", "to_status", "actor", "actor_id", "note").
+		Values(event.TransactionId, event.FromStatus, event.ToStatus, event.Actor, event.ActorId, event.Note).ToSql()
+
+	_, err := sct.db.ExecContext(ctx, sql, args...)
+	return err
+}
+
+// LockTransaction locks the transaction row until the end of the database
+// transaction and returns its status
+func (sct *sqlConnTx) LockTransaction(ctx context.Context, id string) (string, error) {
+	sql, _, _ := sq.Select("status").From("transactions").Where("id = $1").Suffix("FOR UPDATE").ToSql()
+
+	var status string
+	err := sct.db.QueryRowContext(ctx, sql, id).Scan(&status)
+	return status, err
+}
+
+// FindRefunds reads the refunds inside the database transaction, so after
+// LockTransaction it sees every refund committed before
+func (sct *sqlConnTx) FindRefunds(ctx context.Context, transactionID string) ([]entity.Refund, error) {
+	return findRefunds(ctx, sct.db, transactionID)
+}
+
+func (sct *sqlConnTx) CreateRefund(ctx context.Context, refund entity.Refund) (int, error) {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	sql, args, _ := psql.Insert("refunds").Columns("transaction_id", "refund_key", "amount", "order_id", "reason", "status", "actor_id").
+		Values(refund.TransactionId, refund.RefundKey, refund.Amount, pq.Array(refund.OrderIds), refund.Reason, refund.Status, refund.ActorId).
+		Suffix("RETURNING id").ToSql()
+
+	var id int
+	if err := sct.db.QueryRowContext(ctx, sql, args...).Scan(&id); err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// UpdateRefundStatus sets the status of a refund. A succeeded refund keeps
+// its status so a late failure report cannot undo it.
+func (sct *sqlConnTx) UpdateRefundStatus(ctx context.Context, transactionID string, refundKey string, status string) error {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	sql, args, _ := psql.Update("refunds").
+		Set("status", status).
+		Set("updated_at", sq.Expr("CURRENT_TIMESTAMP")).
+		Where(sq.Eq{"transaction_id": transactionID, "refund_key": refundKey}).
+		Where(sq.NotEq{"status": entity.RefundSucceeded}).ToSql()
+
+	_, err := sct.db.ExecContext(ctx, sql, args...)
+	return err
+}
+
+// SavePaymentNotification stores the notification once per dedupe key. A
+// repeated delivery gets the id and processing state of the stored one.
+func (sct *sqlConnTx) SavePaymentNotification(ctx context.Context, n *entity.PaymentNotification) error {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	sql, args, _ := psql.Insert("payment_notifications").Columns("transaction_id", "status", "refund_keys", "gross_amount", "note", "dedupe_key", "payload").
+		Values(n.TransactionId, n.Status, pq.Array(n.RefundKeys), n.GrossAmount, n.Note, n.DedupeKey, string(n.Payload)).
+		Suffix("ON CONFLICT (dedupe_key) DO UPDATE SET dedupe_key = EXCLUDED.dedupe_key RETURNING id, processed_at, error, created_at").ToSql()
+
+	return sct.db.QueryRowContext(ctx, sql, args...).Scan(&n.Id, &n.ProcessedAt, &n.Error, &n.CreatedAt)
+}
+
+func (sct *sqlConnTx) MarkPaymentNotification(ctx context.Context, id int, processedAt *time.Time, errMsg string) error {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	sql, args, _ := psql.Update("payment_notifications").
+		Set("processed_at", processedAt).
+		Set("error", errMsg).
+		Where(sq.Eq{"id": id}).ToSql()
+
+	_, err := sct.db.ExecContext(ctx, sql, args...)
+	return err
+}
+
+// DeleteOrderedCart deletes the cart lines of the user with the product,
+// variant and set of toppings of the order
+func (sct *sqlConnTx) DeleteOrderedCart(ctx context.Context, order entity.Order, userID string) error {
 	var err error
-	sql, _, _ := sq.Delete("carts").Where("product_id=$1 AND user_id=$2").ToSql()
+	sql, _, _ := sq.Delete("carts").
+		Where("user_id=$1 AND product_id=$2 AND variant_id IS NOT DISTINCT FROM $3").
+		Where("ARRAY(SELECT DISTINCT unnest(COALESCE(topping_id, '{}')) ORDER BY 1) = $4::INT[]").ToSql()
 
-	_, err = sct.db.ExecContext(ctx, sql, productID, userID)
+	_, err = sct.db.ExecContext(ctx, sql, userID, order.ProductId, order.VariantId, pq.Array(entity.NormalizeToppingIds(order.ToppingIds)))
 	return err
 }
 
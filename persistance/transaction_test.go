@@ -0,0 +1,108 @@
+package persistance
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func fakeTransactionRows(transactions int) func(query string) fakeResult {
+	order := `[{"id": 1, "product_id": 1, "name": "Latte", "image": "latte.png", "price": 34000, "qty": 1,
+			"variant_id": 1, "variant_name": "Large", "topping_ids": [1], "toppings": [{"id": 1, "name": "Boba"}]},
+		{"id": 2, "product_id": 2, "name": "Mocha", "image": "mocha.png", "price": 32000, "qty": 1,
+			"variant_id": null, "variant_name": null, "topping_ids": null, "toppings": []}]`
+
+	breakdown := `{"subtotal": 66000, "fees": [{"code": "service", "name": "Service Fee", "amount": 5000}], "total": 71000}`
+
+	return func(query string) fakeResult {
+		result := fakeResult{columns: make([]string, 15)}
+		for i := 0; i < transactions; i++ {
+			result.rows = append(result.rows, []driver.Value{
+				fmt.Sprintf("ORDER-%d", i), "user", "Budi", "Jl. Kemang", "0812", "Jakarta", int64(12730), nil, nil, nil, int64(71000), "paid", int64(1), breakdown, order,
+			})
+		}
+		return result
+	}
+}
+
+func TestFindTransactionByIDOrderLines(t *testing.T) {
+	var query string
+	respond := fakeTransactionRows(1)
+	_, db := newFakeDB(func(q string) fakeResult {
+		query = q
+		return respond(q)
+	})
+
+	transaction, err := NewTransactionRepository(db).FindTransactionByID(context.Background(), "ORDER-0")
+	if err != nil {
+		t.Fatalf("FindTransactionByID() error = %v", err)
+	}
+
+	if !strings.Contains(query, "LEFT JOIN product_variants") {
+		t.Errorf("query does not load the variants: %s", query)
+	}
+
+	large := transaction.Orders[0]
+	if large.VariantId == nil || *large.VariantId != 1 || large.VariantName != "Large" || fmt.Sprint(large.ToppingIds) != "[1]" {
+		t.Errorf("order with a variant = %+v", large)
+	}
+
+	plain := transaction.Orders[1]
+	if plain.VariantId != nil || plain.VariantName != "" || plain.ToppingIds != nil {
+		t.Errorf("order without a variant = %+v", plain)
+	}
+}
+
+func BenchmarkFindTransactions(b *testing.B) {
+	ctx := context.Background()
+
+	for _, transactions := range []int{1, 10, 100} {
+		fake, db := newFakeDB(fakeTransactionRows(transactions))
+		repo := NewTransactionRepository(db)
+
+		finders := map[string]func() (int, error){
+			"FindTransactions": func() (int, error) {
+				t, err := repo.FindTransactions(ctx, nil)
+				return len(t), err
+			},
+			"FindUserTransactions": func() (int, error) {
+				t, err := repo.FindUserTransactions(ctx, "user")
+				return len(t), err
+			},
+			"FindTransactionByID": func() (int, error) {
+				t, err := repo.FindTransactionByID(ctx, "ORDER-0")
+				if err != nil {
+					return 0, err
+				}
+				if len(t.Orders) != 2 || len(t.Orders[0].Toppings) != 1 || t.ServiceFee != 5000 {
+					return 0, fmt.Errorf("orders not loaded: %+v", t.Orders)
+				}
+				return transactions, nil
+			},
+		}
+
+		for name, find := range finders {
+			b.Run(fmt.Sprintf("%s/transactions=%d", name, transactions), func(b *testing.B) {
+				start := fake.Queries()
+
+				for i := 0; i < b.N; i++ {
+					n, err := find()
+					if err != nil {
+						b.Fatal(err)
+					}
+					if n != transactions {
+						b.Fatalf("%s returned %d transactions, want %d", name, n, transactions)
+					}
+				}
+
+				perOp := float64(fake.Queries()-start) / float64(b.N)
+				if perOp != 1 {
+					b.Fatalf("%s ran %v queries per call, want 1", name, perOp)
+				}
+				b.ReportMetric(perOp, "queries/op")
+			})
+		}
+	}
+}
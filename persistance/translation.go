@@ -0,0 +1,118 @@
+package persistance
+
+import (
+	"context"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/yosepalexsander/waysbucks-api/entity"
+	"github.com/yosepalexsander/waysbucks-api/repository"
+)
+
+type translationRepo struct {
+	db *sqlx.DB
+}
+
+func NewTranslationRepository(db *sqlx.DB) repository.TranslationRepository {
+	return &translationRepo{db}
+}
+
+func (storage *translationRepo) FindProductTranslations(ctx context.Context, productID int) ([]entity.Translation, error) {
+	sql, _, _ := sq.Select("product_id AS id", "locale", "name", "description").
+		From("product_translations").Where("product_id=$1").OrderByClause("locale").ToSql()
+
+	translations := []entity.Translation{}
+	if err := storage.db.SelectContext(ctx, &translations, sql, productID); err != nil {
+		return nil, err
+	}
+
+	return translations, nil
+}
+
+func (storage *translationRepo) FindToppingTranslations(ctx context.Context, toppingID int) ([]entity.Translation, error) {
+	sql, _, _ := sq.Select("topping_id AS id", "locale", "name").
+		From("topping_translations").Where("topping_id=$1").OrderByClause("locale").ToSql()
+
+	translations := []entity.Translation{}
+	if err := storage.db.SelectContext(ctx, &translations, sql, toppingID); err != nil {
+		return nil, err
+	}
+
+	return translations, nil
+}
+
+func (storage *translationRepo) FindProductTranslationsByLocale(ctx context.Context, locale string) ([]entity.Translation, error) {
+	sql, _, _ := sq.Select("product_id AS id", "locale", "name", "description").
+		From("product_translations").Where("locale=$1").ToSql()
+
+	translations := []entity.Translation{}
+	if err := storage.db.SelectContext(ctx, &translations, sql, locale); err != nil {
+		return nil, err
+	}
+
+	return translations, nil
+}
+
+func (storage *translationRepo) FindToppingTranslationsByLocale(ctx context.Context, locale string) ([]entity.Translation, error) {
+	sql, _, _ := sq.Select("topping_id AS id", "locale", "name").
+		From("topping_translations").Where("locale=$1").ToSql()
+
+	translations := []entity.Translation{}
+	if err := storage.db.SelectContext(ctx, &translations, sql, locale); err != nil {
+		return nil, err
+	}
+
+	return translations, nil
+}
+
+func (storage *translationRepo) SaveProductTranslation(ctx context.Context, productID int, t entity.Translation) error {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	sql, args, _ := psql.Insert("product_translations").
+		Columns("product_id", "locale", "name", "description").
+		Values(productID, t.Locale, t.Name, t.Description).
+		Suffix("ON CONFLICT (product_id, locale) DO UPDATE SET name = EXCLUDED.name, description = EXCLUDED.description, updated_at = CURRENT_TIMESTAMP").ToSql()
+
+	_, err := storage.db.ExecContext(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (storage *translationRepo) SaveToppingTranslation(ctx context.Context, toppingID int, t entity.Translation) error {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	sql, args, _ := psql.Insert("topping_translations").
+		Columns("topping_id", "locale", "name").
+		Values(toppingID, t.Locale, t.Name).
+		Suffix("ON CONFLICT (topping_id, locale) DO UPDATE SET name = EXCLUDED.name, updated_at = CURRENT_TIMESTAMP").ToSql()
+
+	_, err := storage.db.ExecContext(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (storage *translationRepo) DeleteProductTranslation(ctx context.Context, productID int, locale string) error {
+	sql, _, _ := sq.Delete("product_translations").Where("product_id=$1 AND locale=$2").ToSql()
+
+	_, err := storage.db.ExecContext(ctx, sql, productID, locale)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (storage *translationRepo) DeleteToppingTranslation(ctx context.Context, toppingID int, locale string) error {
+	sql, _, _ := sq.Delete("topping_translations").Where("topping_id=$1 AND locale=$2").ToSql()
+
+	_, err := storage.db.ExecContext(ctx, sql, toppingID, locale)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
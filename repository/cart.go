@@ -2,13 +2,34 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/yosepalexsander/waysbucks-api/entity"
 )
 
 type CartRepository interface {
-	FindCarts(ctx context.Context, userID string) ([]entity.Cart, error)
+	FindCarts(ctx context.Context, owner entity.CartOwner) ([]entity.Cart, error)
+	FindCart(ctx context.Context, id int, owner entity.CartOwner) (*entity.Cart, error)
+	FindMatchingCart(ctx context.Context, cart entity.Cart) (*entity.Cart, error)
 	SaveCart(ctx context.Context, cart entity.Cart) error
-	UpdateCart(ctx context.Context, id int, userID string, data map[string]interface{}) error
-	DeleteCart(ctx context.Context, id int, userID string) error
+	UpdateCart(ctx context.Context, cart entity.Cart) error
+	DeleteCart(ctx context.Context, id int, owner entity.CartOwner) error
+	FindAbandonedCarts(ctx context.Context, before time.Time) ([]entity.AbandonedCart, error)
+	MarkCartReminded(ctx context.Context, userID string, at time.Time) error
+	PurgeCarts(ctx context.Context, before time.Time) (int64, error)
+	CartTx
+}
+
+type CartTx interface {
+	ExecTx(ctx context.Context, fn func(CartTransactioner) error) error
+}
+
+type CartTransactioner interface {
+	FindCarts(ctx context.Context, owner entity.CartOwner) ([]entity.Cart, error)
+	FindMatchingCart(ctx context.Context, cart entity.Cart) (*entity.Cart, error)
+	SaveCart(ctx context.Context, cart entity.Cart) error
+	UpdateCart(ctx context.Context, cart entity.Cart) error
+	DeleteCart(ctx context.Context, id int, owner entity.CartOwner) error
+	Rollback() error
+	Commit() error
 }
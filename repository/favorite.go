@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/yosepalexsander/waysbucks-api/entity"
+)
+
+type FavoriteRepository interface {
+	FavoriteFinder
+	FavoriteMutator
+}
+
+type FavoriteFinder interface {
+	FindFavoriteProducts(ctx context.Context, userID string) ([]entity.Product, error)
+	FindCustomizations(ctx context.Context, userID string) ([]entity.Customization, error)
+	FindCustomization(ctx context.Context, id int, userID string) (*entity.Customization, error)
+}
+
+type FavoriteMutator interface {
+	SaveFavorite(ctx context.Context, userID string, productID int) error
+	DeleteFavorite(ctx context.Context, userID string, productID int) error
+	SaveCustomization(ctx context.Context, customization entity.Customization) error
+	DeleteCustomization(ctx context.Context, id int, userID string) error
+}
@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/yosepalexsander/waysbucks-api/entity"
+)
+
+type IdempotencyRepository interface {
+	// CreateIdempotencyKey stores the key unless the owner used it already,
+	// in which case the stored key is returned with created false
+	CreateIdempotencyKey(ctx context.Context, key entity.IdempotencyKey) (stored *entity.IdempotencyKey, created bool, err error)
+	CompleteIdempotencyKey(ctx context.Context, key entity.IdempotencyKey) error
+	DeleteIdempotencyKey(ctx context.Context, owner string, key string) error
+	PurgeIdempotencyKeys(ctx context.Context, before time.Time) (int64, error)
+}
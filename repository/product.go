@@ -10,6 +10,7 @@ type ProductRepository interface {
 	ProductFinder
 	ProductMutator
 	ProductRemover
+	ProductTx
 }
 
 type ProductFinder interface {
@@ -17,6 +18,10 @@ type ProductFinder interface {
 	FindProduct(ctx context.Context, id int) (*entity.Product, error)
 	FindToppings(ctx context.Context) ([]entity.ProductTopping, error)
 	FindTopping(ctx context.Context, id int) (*entity.ProductTopping, error)
+	FindToppingsByIds(ctx context.Context, ids []int64) ([]entity.ProductTopping, error)
+	FindProductVariants(ctx context.Context, productID int) ([]entity.ProductVariant, error)
+	FindVariant(ctx context.Context, id int) (*entity.ProductVariant, error)
+	FindProductImages(ctx context.Context, productID int) ([]entity.ProductImage, error)
 }
 
 type ProductMutator interface {
@@ -24,9 +29,28 @@ type ProductMutator interface {
 	UpdateProduct(ctx context.Context, id int, newProduct map[string]interface{}) error
 	SaveTopping(ctx context.Context, topping entity.ProductTopping) error
 	UpdateTopping(ctx context.Context, id int, newData map[string]interface{}) error
+	SaveVariant(ctx context.Context, variant entity.ProductVariant) error
+	SaveProductImage(ctx context.Context, image entity.ProductImage) (int, error)
+	SetPrimaryProductImage(ctx context.Context, productID int, imageID int) error
+	ReorderProductImages(ctx context.Context, productID int, imageIDs []int) error
 }
 
 type ProductRemover interface {
 	DeleteProduct(ctx context.Context, id int) error
 	DeleteTopping(ctx context.Context, id int) error
+	DeleteVariant(ctx context.Context, id int, productID int) error
+	DeleteProductImage(ctx context.Context, id int, productID int) error
+}
+
+type ProductTx interface {
+	ExecTx(ctx context.Context, fn func(ProductTransactioner) error) error
+}
+
+type ProductTransactioner interface {
+	SaveProduct(ctx context.Context, product entity.Product) error
+	UpdateProduct(ctx context.Context, id int, newProduct map[string]interface{}) error
+	SaveTopping(ctx context.Context, topping entity.ProductTopping) error
+	UpdateTopping(ctx context.Context, id int, newData map[string]interface{}) error
+	Rollback() error
+	Commit() error
 }
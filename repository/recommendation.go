@@ -0,0 +1,13 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/yosepalexsander/waysbucks-api/entity"
+)
+
+type RecommendationRepository interface {
+	FindRelatedProducts(ctx context.Context, productIDs []int64, limit int) ([]entity.RecommendedProduct, error)
+	FindRelatedToppings(ctx context.Context, productIDs []int64, limit int) ([]entity.RecommendedTopping, error)
+	RefreshAffinities(ctx context.Context, minSupport int) error
+}
@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/yosepalexsander/waysbucks-api/entity"
+)
+
+type ReviewRepository interface {
+	ReviewFinder
+	ReviewMutator
+}
+
+type ReviewFinder interface {
+	FindProductReviews(ctx context.Context, productID int, includeHidden bool) ([]entity.Review, error)
+	FindFlaggedReviews(ctx context.Context) ([]entity.Review, error)
+	FindReview(ctx context.Context, id int) (*entity.Review, error)
+	HasPurchasedProduct(ctx context.Context, userID string, productID int) (bool, error)
+}
+
+type ReviewMutator interface {
+	SaveReview(ctx context.Context, review entity.Review) error
+	UpdateReview(ctx context.Context, id int, data map[string]interface{}) error
+	DeleteReview(ctx context.Context, id int, userID string) error
+}
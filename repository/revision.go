@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/yosepalexsander/waysbucks-api/entity"
+)
+
+type RevisionRepository interface {
+	FindProductRevisions(ctx context.Context, productID int) ([]entity.ProductRevision, error)
+	FindToppingRevisions(ctx context.Context, toppingID int) ([]entity.ToppingRevision, error)
+	FindProductsAt(ctx context.Context, at time.Time) ([]entity.ProductRevision, error)
+	FindToppingsAt(ctx context.Context, at time.Time) ([]entity.ToppingRevision, error)
+}
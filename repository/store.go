@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/yosepalexsander/waysbucks-api/entity"
+)
+
+type StoreRepository interface {
+	StoreFinder
+	StoreMutator
+}
+
+type StoreFinder interface {
+	FindStores(ctx context.Context) ([]entity.Store, error)
+	FindStore(ctx context.Context, id int) (*entity.Store, error)
+	FindStaffStoreIDs(ctx context.Context, userID string) ([]int64, error)
+	FindStoreProducts(ctx context.Context, storeID int) ([]entity.StoreItem, error)
+	FindStoreToppings(ctx context.Context, storeID int) ([]entity.StoreItem, error)
+}
+
+type StoreMutator interface {
+	SaveStore(ctx context.Context, store entity.Store) error
+	UpdateStore(ctx context.Context, id int, store entity.Store) error
+	DeleteStore(ctx context.Context, id int) error
+	SaveStaff(ctx context.Context, storeID int, userID string) error
+	DeleteStaff(ctx context.Context, storeID int, userID string) error
+	SaveStoreProduct(ctx context.Context, item entity.StoreItem) error
+	DeleteStoreProduct(ctx context.Context, storeID int, productID int) error
+	SaveStoreTopping(ctx context.Context, item entity.StoreItem) error
+	DeleteStoreTopping(ctx context.Context, storeID int, toppingID int) error
+}
@@ -2,24 +2,25 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/yosepalexsander/waysbucks-api/entity"
 )
 
 type TransactionRepository interface {
 	TransactionFinder
-	TransactionMutator
 	TransactionTx
 }
 
 type TransactionFinder interface {
-	FindTransactions(ctx context.Context) ([]entity.Transaction, error)
+	FindTransactions(ctx context.Context, storeIDs []int64) ([]entity.Transaction, error)
 	FindUserTransactions(ctx context.Context, userID string) ([]entity.Transaction, error)
 	FindTransactionByID(ctx context.Context, id string) (*entity.Transaction, error)
-}
-
-type TransactionMutator interface {
-	UpdateTransaction(ctx context.Context, id string, data map[string]interface{}) error
+	FindTransactionEvents(ctx context.Context, transactionID string) ([]entity.TransactionEvent, error)
+	FindRefunds(ctx context.Context, transactionID string) ([]entity.Refund, error)
+	FindPendingPayments(ctx context.Context, before time.Time) ([]entity.PendingPayment, error)
+	FindPaymentNotifications(ctx context.Context, transactionID string) ([]entity.PaymentNotification, error)
+	FindPaymentNotification(ctx context.Context, id int) (*entity.PaymentNotification, error)
 }
 
 type TransactionTx interface {
@@ -27,9 +28,17 @@ type TransactionTx interface {
 }
 
 type Transactioner interface {
-	DeleteCart(ctx context.Context, productID int, userID string) error
+	DeleteOrderedCart(ctx context.Context, order entity.Order, userID string) error
 	CreateOrder(ctx context.Context, order entity.Order) error
 	CreateTransaction(ctx context.Context, tx entity.Transaction) (string, error)
+	UpdateTransactionStatus(ctx context.Context, id string, from string, to string) error
+	CreateTransactionEvent(ctx context.Context, event entity.TransactionEvent) error
+	LockTransaction(ctx context.Context, id string) (string, error)
+	FindRefunds(ctx context.Context, transactionID string) ([]entity.Refund, error)
+	CreateRefund(ctx context.Context, refund entity.Refund) (int, error)
+	UpdateRefundStatus(ctx context.Context, transactionID string, refundKey string, status string) error
+	SavePaymentNotification(ctx context.Context, n *entity.PaymentNotification) error
+	MarkPaymentNotification(ctx context.Context, id int, processedAt *time.Time, errMsg string) error
 	Rollback() error
 	Commit() error
 }
@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/yosepalexsander/waysbucks-api/entity"
+)
+
+type TranslationRepository interface {
+	TranslationFinder
+	TranslationMutator
+}
+
+type TranslationFinder interface {
+	FindProductTranslations(ctx context.Context, productID int) ([]entity.Translation, error)
+	FindToppingTranslations(ctx context.Context, toppingID int) ([]entity.Translation, error)
+	FindProductTranslationsByLocale(ctx context.Context, locale string) ([]entity.Translation, error)
+	FindToppingTranslationsByLocale(ctx context.Context, locale string) ([]entity.Translation, error)
+}
+
+type TranslationMutator interface {
+	SaveProductTranslation(ctx context.Context, productID int, translation entity.Translation) error
+	SaveToppingTranslation(ctx context.Context, toppingID int, translation entity.Translation) error
+	DeleteProductTranslation(ctx context.Context, productID int, locale string) error
+	DeleteToppingTranslation(ctx context.Context, toppingID int, locale string) error
+}
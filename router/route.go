@@ -1,6 +1,7 @@
 package router
 
 import (
+	"expvar"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
@@ -11,6 +12,8 @@ import (
 
 func NewRouter(r *chi.Mux, h *interactor.AppHandler) {
 	r.Route("/api/v1", func(r chi.Router) {
+		r.Use(customMiddleware.Locale)
+
 		r.Route("/auth", func(r chi.Router) {
 			r.Post("/register", h.Register)
 			r.Post("/login", h.Login)
@@ -28,6 +31,8 @@ func NewRouter(r *chi.Mux, h *interactor.AppHandler) {
 			})
 		})
 
+		r.With(customMiddleware.Authentication, customMiddleware.AdminOnly).Handle("/metrics", expvar.Handler())
+
 		r.Route("/users", func(r chi.Router) {
 			r.Use(customMiddleware.AdminOnly)
 			r.Get("/", h.GetUsers)
@@ -36,7 +41,7 @@ func NewRouter(r *chi.Mux, h *interactor.AppHandler) {
 		r.Route("/address", func(r chi.Router) {
 			r.Use(customMiddleware.Authentication)
 			r.Get("/", h.FindUserAddresses)
-			r.Post("/", h.CreateAddress)
+			r.With(h.Idempotent).Post("/", h.CreateAddress)
 			r.Put("/{addressID}", h.UpdateAddress)
 			r.Delete("/{addressID}", h.DeleteAddress)
 		})
@@ -44,13 +49,55 @@ func NewRouter(r *chi.Mux, h *interactor.AppHandler) {
 		r.Route("/products", func(r chi.Router) {
 			r.Get("/", h.FindProducts)
 			r.Get("/{productID}", h.GetProduct)
+			r.Get("/{productID}/reviews", h.FindProductReviews)
+			r.With(customMiddleware.Authentication, h.Idempotent).Post("/{productID}/reviews", h.CreateReview)
+			r.Get("/{productID}/variants", h.FindProductVariants)
+			r.Get("/{productID}/recommendations", h.FindProductRecommendations)
 
 			r.Group(func(r chi.Router) {
 				r.Use(customMiddleware.Authentication)
 				r.Use(customMiddleware.AdminOnly)
-				r.Post("/", h.CreateProduct)
+				r.With(h.Idempotent).Post("/", h.CreateProduct)
 				r.Put("/{productID}", h.UpdateProduct)
 				r.Delete("/{productID}", h.DeleteProduct)
+				r.Get("/{productID}/history", h.FindProductHistory)
+				r.Get("/{productID}/translations", h.FindProductTranslations)
+				r.Put("/{productID}/translations/{locale}", h.SaveProductTranslation)
+				r.Delete("/{productID}/translations/{locale}", h.DeleteProductTranslation)
+				r.With(h.Idempotent).Post("/{productID}/variants", h.CreateProductVariant)
+				r.Delete("/{productID}/variants/{variantID}", h.DeleteProductVariant)
+				r.Get("/{productID}/images", h.FindProductImages)
+				r.Post("/{productID}/images", h.AddProductImage)
+				r.Put("/{productID}/images/order", h.ReorderProductImages)
+				r.Put("/{productID}/images/{imageID}/primary", h.SetPrimaryProductImage)
+				r.Delete("/{productID}/images/{imageID}", h.RemoveProductImage)
+			})
+		})
+
+		r.Route("/stores", func(r chi.Router) {
+			r.Get("/", h.FindStores)
+			r.Get("/{storeID}", h.GetStore)
+			r.Get("/{storeID}/menu", h.GetStoreMenu)
+
+			r.Group(func(r chi.Router) {
+				r.Use(customMiddleware.Authentication)
+				r.Use(customMiddleware.AdminOnly)
+				r.With(h.Idempotent).Post("/", h.CreateStore)
+				r.With(h.StoreScope).Put("/{storeID}", h.UpdateStore)
+				r.With(h.StoreScope).Delete("/{storeID}", h.DeleteStore)
+				r.With(h.StoreScope).Put("/{storeID}/staff/{userID}", h.AssignStoreStaff)
+				r.With(h.StoreScope).Delete("/{storeID}/staff/{userID}", h.RemoveStoreStaff)
+			})
+
+			r.Group(func(r chi.Router) {
+				r.Use(customMiddleware.Authentication)
+				r.Use(h.StoreScope)
+				r.Get("/{storeID}/transactions", h.FindStoreTransactions)
+				r.Put("/{storeID}/transactions/{transactionID}/status", h.AdvanceStoreTransaction)
+				r.Put("/{storeID}/products/{productID}", h.SaveStoreProduct)
+				r.Delete("/{storeID}/products/{productID}", h.DeleteStoreProduct)
+				r.Put("/{storeID}/toppings/{toppingID}", h.SaveStoreTopping)
+				r.Delete("/{storeID}/toppings/{toppingID}", h.DeleteStoreTopping)
 			})
 		})
 
@@ -60,25 +107,82 @@ func NewRouter(r *chi.Mux, h *interactor.AppHandler) {
 			r.Group(func(r chi.Router) {
 				r.Use(customMiddleware.Authentication)
 				r.Use(customMiddleware.AdminOnly)
-				r.Post("/", h.CreateTopping)
+				r.With(h.Idempotent).Post("/", h.CreateTopping)
+				r.Get("/{toppingID}/history", h.FindToppingHistory)
+				r.Get("/{toppingID}/translations", h.FindToppingTranslations)
+				r.Put("/{toppingID}/translations/{locale}", h.SaveToppingTranslation)
+				r.Delete("/{toppingID}/translations/{locale}", h.DeleteToppingTranslation)
 				r.Put("/{toppingID}", h.UpdateTopping)
 				r.Delete("/{toppingID}", h.DeleteTopping)
 			})
 		})
 
-		r.Route("/carts", func(r chi.Router) {
+		r.Route("/catalog", func(r chi.Router) {
+			r.Use(customMiddleware.Authentication)
+			r.Use(customMiddleware.AdminOnly)
+			r.Get("/products/export", h.ExportProducts)
+			r.Post("/products/import", h.ImportProducts)
+			r.Get("/toppings/export", h.ExportToppings)
+			r.Post("/toppings/import", h.ImportToppings)
+			r.Get("/snapshot", h.GetCatalogSnapshot)
+			r.Get("/diff", h.DiffCatalog)
+		})
+
+		r.Route("/reviews", func(r chi.Router) {
 			r.Use(customMiddleware.Authentication)
-			r.Get("/", h.FindCarts)
-			r.Post("/", h.CreateCart)
-			r.Put("/{cartID}", h.UpdateCart)
-			r.Delete("/{cartID}", h.DeleteCart)
+			r.Delete("/{reviewID}", h.DeleteReview)
+
+			r.Group(func(r chi.Router) {
+				r.Use(customMiddleware.AdminOnly)
+				r.Get("/flagged", h.FindFlaggedReviews)
+				r.Put("/{reviewID}/moderation", h.ModerateReview)
+			})
+		})
+
+		r.Route("/favorites", func(r chi.Router) {
+			r.Use(customMiddleware.Authentication)
+			r.Get("/", h.FindFavorites)
+			r.Put("/{productID}", h.AddFavorite)
+			r.Delete("/{productID}", h.RemoveFavorite)
+		})
+
+		r.Route("/customizations", func(r chi.Router) {
+			r.Use(customMiddleware.Authentication)
+			r.Get("/", h.FindCustomizations)
+			r.With(h.Idempotent).Post("/", h.CreateCustomization)
+			r.Delete("/{customizationID}", h.DeleteCustomization)
+			r.With(h.Idempotent).Post("/{customizationID}/cart", h.AddCustomizationToCart)
+		})
+
+		r.Route("/carts", func(r chi.Router) {
+			r.Post("/guest", h.CreateGuestCart)
+			r.With(customMiddleware.Authentication, customMiddleware.AdminOnly, h.AllStoresScope).Get("/abandoned", h.FindAbandonedCarts)
+
+			r.Group(func(r chi.Router) {
+				r.Use(customMiddleware.CartAuthentication)
+				r.Get("/", h.FindCarts)
+				r.With(h.Idempotent).Post("/", h.CreateCart)
+				r.Get("/recommendations", h.FindCartRecommendations)
+				r.Post("/validate", h.ValidateCart)
+				r.Put("/{cartID}/qty", h.UpdateCartQty)
+				r.Put("/{cartID}/toppings", h.UpdateCartToppings)
+				r.Delete("/{cartID}", h.DeleteCart)
+			})
 		})
 
 		r.Route("/transactions", func(r chi.Router) {
 			r.Use(customMiddleware.Authentication)
-			r.Post("/", h.CreateTransaction)
+			r.With(h.Idempotent).Post("/", h.CreateTransaction)
+			r.Get("/delivery-quote", h.QuoteDelivery)
 			r.Get("/{transactionID}", h.GetTransaction)
+			r.With(h.Idempotent).Post("/{transactionID}/cancel", h.CancelTransaction)
 			r.With(customMiddleware.AdminOnly).Get("/", h.FindTransactions)
+			r.With(customMiddleware.AdminOnly, h.AllStoresScope).Get("/reconciliation", h.FindPaymentMismatches)
+			r.With(customMiddleware.AdminOnly, h.AllStoresScope).Post("/reconciliation", h.ReconcilePayments)
+			r.With(customMiddleware.AdminOnly, h.TransactionScope, h.Idempotent).Post("/{transactionID}/refunds", h.RefundTransaction)
+			r.With(customMiddleware.AdminOnly, h.TransactionScope, h.Idempotent).Post("/{transactionID}/refunds/{refundID}/retry", h.RetryRefund)
+			r.With(customMiddleware.AdminOnly, h.TransactionScope).Get("/{transactionID}/notifications", h.FindPaymentNotifications)
+			r.With(customMiddleware.AdminOnly, h.TransactionScope).Post("/{transactionID}/notifications/{notificationID}/replay", h.ReplayPaymentNotification)
 		})
 
 		r.With(customMiddleware.Authentication).Get("/user-transactions", h.GetUserTransactions)
@@ -0,0 +1,176 @@
+package thirdparty
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/midtrans/midtrans-go/coreapi"
+	"github.com/yosepalexsander/waysbucks-api/entity"
+)
+
+const fakeServerKey = "fake-server-key"
+
+// FakePaymentProvider keeps payments in memory and speaks the midtrans
+// notification format. Tests and local development drive it with SetStatus
+// and Notification instead of paying at a real gateway.
+type FakePaymentProvider struct {
+	mu        sync.Mutex
+	payments  map[string]*fakePayment
+	refunds   []entity.Refund
+	refundErr error
+}
+
+type fakePayment struct {
+	status     string
+	amount     int
+	refunded   int
+	refundKeys []string
+}
+
+func NewFakePaymentProvider() *FakePaymentProvider {
+	return &FakePaymentProvider{payments: make(map[string]*fakePayment)}
+}
+
+func (p *FakePaymentProvider) CreateCharge(ctx context.Context, t *entity.Transaction) (*entity.PaymentCharge, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.payments[t.Id] = &fakePayment{status: "pending", amount: t.Total}
+
+	return &entity.PaymentCharge{Token: "fake-" + t.Id, RedirectURL: "https://fake-payment.local/" + t.Id}, nil
+}
+
+func (p *FakePaymentProvider) GetStatus(ctx context.Context, transactionID string) (*entity.PaymentUpdate, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.payments[transactionID]; !ok {
+		return nil, ErrPaymentNotFound
+	}
+
+	update := NewPaymentUpdate(p.response(transactionID))
+	return &update, nil
+}
+
+func (p *FakePaymentProvider) CancelPayment(ctx context.Context, transactionID string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	payment, ok := p.payments[transactionID]
+	if !ok {
+		return nil
+	}
+
+	if payment.status != "pending" {
+		return fmt.Errorf("fake cancel %s: payment is %s", transactionID, payment.status)
+	}
+
+	payment.status = "cancel"
+	return nil
+}
+
+// RefundPayment records the refund once per refund key. It fails while
+// FailRefunds is set.
+func (p *FakePaymentProvider) RefundPayment(ctx context.Context, refund entity.Refund) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.refundErr != nil {
+		return p.refundErr
+	}
+
+	payment, ok := p.payments[refund.TransactionId]
+	if !ok {
+		return ErrPaymentNotFound
+	}
+
+	for _, key := range payment.refundKeys {
+		if key == refund.RefundKey {
+			return nil
+		}
+	}
+
+	if payment.refunded+refund.Amount > payment.amount {
+		return errors.New("fake refund: amount exceeds the payment")
+	}
+
+	payment.refunded += refund.Amount
+	payment.refundKeys = append(payment.refundKeys, refund.RefundKey)
+	payment.status = "partial_refund"
+	if payment.refunded == payment.amount {
+		payment.status = "refund"
+	}
+
+	p.refunds = append(p.refunds, refund)
+	return nil
+}
+
+func (p *FakePaymentProvider) ParseNotification(body []byte) (*entity.PaymentNotification, error) {
+	return parseMidtransNotification(body, fakeServerKey)
+}
+
+// SetStatus moves the payment to a midtrans transaction status such as
+// settlement or expire. An unknown payment is created with an unknown amount.
+func (p *FakePaymentProvider) SetStatus(transactionID string, status string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	payment, ok := p.payments[transactionID]
+	if !ok {
+		payment = &fakePayment{}
+		p.payments[transactionID] = payment
+	}
+
+	payment.status = status
+}
+
+// FailRefunds makes every refund fail with err until it is called with nil
+func (p *FakePaymentProvider) FailRefunds(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.refundErr = err
+}
+
+// Refunds returns the refunds accepted so far
+func (p *FakePaymentProvider) Refunds() []entity.Refund {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]entity.Refund{}, p.refunds...)
+}
+
+// Notification returns the signed notification midtrans would send for the
+// current status of the payment
+func (p *FakePaymentProvider) Notification(transactionID string) []byte {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	body, _ := json.Marshal(p.response(transactionID))
+	return body
+}
+
+func (p *FakePaymentProvider) response(transactionID string) *coreapi.TransactionStatusResponse {
+	payment, ok := p.payments[transactionID]
+	if !ok {
+		payment = &fakePayment{}
+	}
+
+	resp := &coreapi.TransactionStatusResponse{
+		OrderID:           transactionID,
+		TransactionID:     "fake-" + transactionID,
+		StatusCode:        "200",
+		GrossAmount:       fmt.Sprintf("%d.00", payment.amount),
+		TransactionStatus: payment.status,
+		FraudStatus:       "accept",
+	}
+	for _, key := range payment.refundKeys {
+		resp.Refunds = append(resp.Refunds, coreapi.RefundDetails{RefundKey: key})
+	}
+	resp.SignatureKey = signature(resp.OrderID, resp.StatusCode, resp.GrossAmount, fakeServerKey)
+
+	return resp
+}
@@ -0,0 +1,17 @@
+package thirdparty
+
+import (
+	"context"
+	"log"
+
+	"github.com/yosepalexsander/waysbucks-api/entity"
+)
+
+// LogNotifier writes abandoned cart reminders to the log. It is used until an
+// email or push provider is configured.
+type LogNotifier struct{}
+
+func (LogNotifier) NotifyAbandonedCart(ctx context.Context, cart entity.AbandonedCart) error {
+	log.Printf("abandoned cart reminder: %s <%s> left %d items worth %d", cart.Name, cart.Email, cart.Lines, cart.Value)
+	return nil
+}
@@ -1,29 +1,122 @@
 package thirdparty
 
 import (
+	"context"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/hex"
 	"encoding/json"
-	"io"
+	"errors"
+	"fmt"
+	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/midtrans/midtrans-go"
 	"github.com/midtrans/midtrans-go/coreapi"
 	"github.com/midtrans/midtrans-go/snap"
-	"github.com/yosepalexsander/waysbucks-api/config"
 	"github.com/yosepalexsander/waysbucks-api/entity"
 )
 
-func setupGlobalMidtransConfig() {
-	midtrans.ServerKey = config.MIDTRANS_SERVER_KEY
-	midtrans.ClientKey = config.MIDTRANS_CLIENT_KEY
-	midtrans.Environment = midtrans.Sandbox
+var (
+	ErrInvalidSignature = errors.New("invalid notification signature")
+	ErrPaymentNotFound  = errors.New("payment not found at the payment provider")
+)
+
+// MidtransProvider charges, checks, cancels and refunds payments through
+// midtrans snap and core API
+type MidtransProvider struct {
+	serverKey string
+	snap      snap.Client
+	core      coreapi.Client
+}
+
+// NewMidtransProvider returns a provider for the sandbox, or for production
+// when production is true
+func NewMidtransProvider(serverKey string, production bool) *MidtransProvider {
+	env := midtrans.Sandbox
+	if production {
+		env = midtrans.Production
+	}
+
+	p := &MidtransProvider{serverKey: serverKey}
+	p.snap.New(serverKey, env)
+	p.core.New(serverKey, env)
+
+	return p
 }
 
-func CreateTransaction(t *entity.Transaction) *snap.Response {
-	setupGlobalMidtransConfig()
-	req := generateSnapReq(t)
+// CreateCharge opens a snap payment page for the transaction
+func (p *MidtransProvider) CreateCharge(ctx context.Context, t *entity.Transaction) (*entity.PaymentCharge, error) {
+	resp, err := p.snap.CreateTransaction(generateSnapReq(t))
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.PaymentCharge{Token: resp.Token, RedirectURL: resp.RedirectURL}, nil
+}
+
+// GetStatus asks midtrans the status of the payment of the transaction
+func (p *MidtransProvider) GetStatus(ctx context.Context, transactionID string) (*entity.PaymentUpdate, error) {
+	resp, err := p.core.CheckTransaction(transactionID)
+	if err != nil {
+		if err.StatusCode == http.StatusNotFound {
+			return nil, ErrPaymentNotFound
+		}
+		return nil, err
+	}
+
+	// midtrans answers some errors with a 200 and the code in the body
+	if resp.StatusCode == "404" {
+		return nil, ErrPaymentNotFound
+	}
+
+	update := NewPaymentUpdate(resp)
+	return &update, nil
+}
+
+// CancelPayment cancels a payment that is not settled yet. A transaction
+// unknown to midtrans has no payment to cancel.
+func (p *MidtransProvider) CancelPayment(ctx context.Context, transactionID string) error {
+	resp, err := p.core.CancelTransaction(transactionID)
+	if err != nil {
+		if err.StatusCode == http.StatusNotFound {
+			return nil
+		}
+		return err
+	}
+
+	switch resp.StatusCode {
+	case "200", "404":
+		return nil
+	}
+
+	return fmt.Errorf("midtrans cancel %s: %s %s", transactionID, resp.StatusCode, resp.StatusMessage)
+}
+
+// RefundPayment sends the refund to midtrans. Midtrans refunds a refund key
+// once, so sending a refund again is safe.
+func (p *MidtransProvider) RefundPayment(ctx context.Context, refund entity.Refund) error {
+	resp, err := p.core.RefundTransaction(refund.TransactionId, &coreapi.RefundReq{
+		RefundKey: refund.RefundKey,
+		Amount:    int64(refund.Amount),
+		Reason:    refund.Reason,
+	})
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != "200" {
+		return fmt.Errorf("midtrans refund %s: %s %s", refund.RefundKey, resp.StatusCode, resp.StatusMessage)
+	}
+
+	return nil
+}
 
-	snapResp, _ := snap.CreateTransaction(req)
-	return snapResp
+// ParseNotification decodes a midtrans notification and checks its signature
+// so a forged notification is never stored nor applied
+func (p *MidtransProvider) ParseNotification(body []byte) (*entity.PaymentNotification, error) {
+	return parseMidtransNotification(body, p.serverKey)
 }
 
 func generateSnapReq(t *entity.Transaction) *snap.Request {
@@ -48,14 +141,22 @@ func generateSnapReq(t *entity.Transaction) *snap.Request {
 		orderItems = append(orderItems, itemDetail)
 	}
 
-	// add service fee to item details because midtrans cannot put it automatically
-	serviceFee := midtrans.ItemDetails{
-		ID:    "FEE-" + t.Id,
-		Name:  "Service Fee",
-		Qty:   1,
-		Price: int64(t.ServiceFee),
+	// add fees to item details because midtrans cannot put them automatically
+	if t.Breakdown != nil {
+		for _, fee := range t.Breakdown.Fees {
+			// a waived fee such as a free delivery is not an item
+			if fee.Amount == 0 {
+				continue
+			}
+
+			orderItems = append(orderItems, midtrans.ItemDetails{
+				ID:    strings.ToUpper(fee.Code) + "-" + t.Id,
+				Name:  fee.Name,
+				Qty:   1,
+				Price: int64(fee.Amount),
+			})
+		}
 	}
-	orderItems = append(orderItems, serviceFee)
 
 	req := &snap.Request{
 		TransactionDetails: midtrans.TransactionDetails{
@@ -78,12 +179,84 @@ func generateSnapReq(t *entity.Transaction) *snap.Request {
 	return req
 }
 
-func ParseTransactionResponse(reqBody io.ReadCloser) (*coreapi.TransactionStatusResponse, error) {
-	transaction := new(coreapi.TransactionStatusResponse)
-
-	if err := json.NewDecoder(reqBody).Decode(transaction); err != nil {
+func parseMidtransNotification(body []byte, serverKey string) (*entity.PaymentNotification, error) {
+	t := new(coreapi.TransactionStatusResponse)
+	if err := json.Unmarshal(body, t); err != nil {
 		return nil, err
 	}
 
-	return transaction, nil
+	if !VerifySignature(t, serverKey) {
+		return nil, ErrInvalidSignature
+	}
+
+	refunds := make([]string, 0, len(t.Refunds))
+	for _, refund := range t.Refunds {
+		refunds = append(refunds, refund.RefundKey)
+	}
+
+	return &entity.PaymentNotification{
+		PaymentUpdate: NewPaymentUpdate(t),
+		// midtrans repeats a notification with the same status and refunds
+		DedupeKey: strings.Join([]string{t.OrderID, t.TransactionID, t.TransactionStatus, t.FraudStatus, t.StatusCode, strings.Join(refunds, ",")}, "|"),
+		Payload:   body,
+	}, nil
+}
+
+// VerifySignature checks the signature_key of a notification, the SHA512 of
+// order_id, status_code, gross_amount and the server key
+func VerifySignature(t *coreapi.TransactionStatusResponse, serverKey string) bool {
+	if serverKey == "" || t.SignatureKey == "" {
+		return false
+	}
+
+	expected := signature(t.OrderID, t.StatusCode, t.GrossAmount, serverKey)
+
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(strings.ToLower(t.SignatureKey))) == 1
+}
+
+func signature(orderID string, statusCode string, grossAmount string, serverKey string) string {
+	sum := sha512.Sum512([]byte(orderID + statusCode + grossAmount + serverKey))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewPaymentUpdate maps a midtrans notification to the status of the
+// transaction. The status is empty while the payment is still pending or
+// challenged and for partial refunds, which keep the status.
+func NewPaymentUpdate(t *coreapi.TransactionStatusResponse) entity.PaymentUpdate {
+	update := entity.PaymentUpdate{
+		TransactionId: t.OrderID,
+		GrossAmount:   parseAmount(t.GrossAmount),
+		Note:          "midtrans " + t.TransactionStatus,
+	}
+
+	switch t.TransactionStatus {
+	case "capture":
+		if t.FraudStatus == "accept" {
+			update.Status = entity.StatusPaid
+		}
+	case "settlement":
+		update.Status = entity.StatusPaid
+	case "deny", "expire":
+		update.Status = entity.StatusFailed
+	case "cancel":
+		update.Status = entity.StatusCancelled
+	case "refund":
+		update.Status = entity.StatusRefunded
+	}
+
+	for _, refund := range t.Refunds {
+		if refund.RefundKey != "" {
+			update.RefundKeys = append(update.RefundKeys, refund.RefundKey)
+		}
+	}
+
+	return update
+}
+
+// parseAmount reads a midtrans amount such as "71000.00". Rupiah amounts
+// have no fraction so it is dropped.
+func parseAmount(amount string) int {
+	whole, _, _ := strings.Cut(amount, ".")
+	value, _ := strconv.Atoi(whole)
+	return value
 }
@@ -0,0 +1,42 @@
+package thirdparty
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/yosepalexsander/waysbucks-api/entity"
+)
+
+func signedNotification(serverKey string, status string, amount string) []byte {
+	sum := sha512.Sum512([]byte("ORDER-1" + "200" + amount + serverKey))
+	return []byte(fmt.Sprintf(`{"order_id": "ORDER-1", "transaction_id": "tx-1", "status_code": "200", "gross_amount": "%s",
+		"transaction_status": "%s", "signature_key": "%s"}`, amount, status, hex.EncodeToString(sum[:])))
+}
+
+func TestParseNotification(t *testing.T) {
+	provider := NewMidtransProvider("server-key", false)
+
+	n, err := provider.ParseNotification(signedNotification("server-key", "settlement", "71000.00"))
+	if err != nil {
+		t.Fatalf("signed notification: error = %v", err)
+	}
+	if n.TransactionId != "ORDER-1" || n.Status != entity.StatusPaid || n.GrossAmount != 71000 || n.DedupeKey == "" {
+		t.Errorf("signed notification = %+v", n)
+	}
+
+	expired, _ := provider.ParseNotification(signedNotification("server-key", "expire", "71000.00"))
+	if expired.DedupeKey == n.DedupeKey {
+		t.Errorf("dedupe key %q is shared by different statuses", n.DedupeKey)
+	}
+
+	if _, err := provider.ParseNotification(signedNotification("guessed-key", "settlement", "71000.00")); err != ErrInvalidSignature {
+		t.Errorf("forged notification: error = %v, want %v", err, ErrInvalidSignature)
+	}
+
+	unsigned := []byte(`{"order_id": "ORDER-1", "status_code": "200", "gross_amount": "71000.00", "transaction_status": "settlement"}`)
+	if _, err := provider.ParseNotification(unsigned); err != ErrInvalidSignature {
+		t.Errorf("unsigned notification: error = %v, want %v", err, ErrInvalidSignature)
+	}
+}
@@ -0,0 +1,111 @@
+package usecase
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/yosepalexsander/waysbucks-api/entity"
+	"github.com/yosepalexsander/waysbucks-api/repository"
+)
+
+const (
+	// AbandonedCartCheckInterval is how often reminders and purges run
+	AbandonedCartCheckInterval = 15 * time.Minute
+	DefaultCartReminderDelay   = 24 * time.Hour
+	DefaultCartRetention       = 30 * 24 * time.Hour
+)
+
+// CartNotifier sends the reminder of an abandoned cart to its owner
+type CartNotifier interface {
+	NotifyAbandonedCart(ctx context.Context, cart entity.AbandonedCart) error
+}
+
+// AbandonedCartUseCase reminds users of carts left untouched for delay and
+// purges carts left untouched for retention
+type AbandonedCartUseCase struct {
+	repo      repository.CartRepository
+	notifier  CartNotifier
+	delay     time.Duration
+	retention time.Duration
+}
+
+func NewAbandonedCartUseCase(repo repository.CartRepository, notifier CartNotifier, delay time.Duration, retention time.Duration) AbandonedCartUseCase {
+	return AbandonedCartUseCase{repo, notifier, delay, retention}
+}
+
+// FindAbandonedCarts reports the carts left untouched for olderThan, or for
+// the reminder delay when olderThan is zero
+func (u *AbandonedCartUseCase) FindAbandonedCarts(ctx context.Context, now time.Time, olderThan time.Duration) (*entity.AbandonedCartReport, error) {
+	if olderThan <= 0 {
+		olderThan = u.delay
+	}
+
+	since := now.Add(-olderThan)
+	carts, err := u.repo.FindAbandonedCarts(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+
+	report := entity.NewAbandonedCartReport(since, carts)
+	return &report, nil
+}
+
+// SendReminders notifies the owners of abandoned carts once per period of
+// inactivity and returns the number of reminders sent. A failed notification
+// is retried on the next run.
+func (u *AbandonedCartUseCase) SendReminders(ctx context.Context, now time.Time) (int, error) {
+	carts, err := u.repo.FindAbandonedCarts(ctx, now.Add(-u.delay))
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, cart := range carts {
+		if !cart.NeedsReminder() {
+			continue
+		}
+
+		if err := u.notifier.NotifyAbandonedCart(ctx, cart); err != nil {
+			log.Printf("abandoned cart reminder for %s: %v", cart.UserId, err)
+			continue
+		}
+
+		if err := u.repo.MarkCartReminded(ctx, cart.UserId, now); err != nil {
+			return sent, err
+		}
+		sent++
+	}
+
+	return sent, nil
+}
+
+// PurgeCarts deletes the carts left untouched for the retention period
+func (u *AbandonedCartUseCase) PurgeCarts(ctx context.Context, now time.Time) (int64, error) {
+	return u.repo.PurgeCarts(ctx, now.Add(-u.retention))
+}
+
+// RunReminder sends reminders and purges old carts right away and then on
+// every interval until ctx is done.
+func (u *AbandonedCartUseCase) RunReminder(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		now := time.Now()
+
+		if _, err := u.SendReminders(ctx, now); err != nil {
+			log.Printf("send abandoned cart reminders: %v", err)
+		}
+
+		if _, err := u.PurgeCarts(ctx, now); err != nil {
+			log.Printf("purge abandoned carts: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
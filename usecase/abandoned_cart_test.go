@@ -0,0 +1,57 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/yosepalexsander/waysbucks-api/entity"
+)
+
+type stubCartNotifier struct {
+	sent []string
+	fail string
+}
+
+func (n *stubCartNotifier) NotifyAbandonedCart(ctx context.Context, cart entity.AbandonedCart) error {
+	if cart.UserId == n.fail {
+		return errors.New("provider down")
+	}
+	n.sent = append(n.sent, cart.UserId)
+	return nil
+}
+
+func TestSendReminders(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2023, 5, 10, 12, 0, 0, 0, time.UTC)
+	remindedAt := now.Add(-40 * time.Hour)
+
+	repo := &stubCartRepository{abandoned: []entity.AbandonedCart{
+		{UserId: "new", Email: "new@mail.com", LastActivity: now.Add(-30 * time.Hour)},
+		{UserId: "recent", Email: "recent@mail.com", LastActivity: now.Add(-time.Hour)},
+		{UserId: "reminded", Email: "reminded@mail.com", LastActivity: now.Add(-48 * time.Hour), RemindedAt: &remindedAt},
+		{UserId: "active-again", Email: "again@mail.com", LastActivity: now.Add(-30 * time.Hour), RemindedAt: &remindedAt},
+		{GuestId: "guest", LastActivity: now.Add(-30 * time.Hour)},
+		{UserId: "failing", Email: "failing@mail.com", LastActivity: now.Add(-30 * time.Hour)},
+	}}
+	notifier := &stubCartNotifier{fail: "failing"}
+	u := NewAbandonedCartUseCase(repo, notifier, 24*time.Hour, 30*24*time.Hour)
+
+	sent, err := u.SendReminders(ctx, now)
+	if err != nil {
+		t.Fatalf("SendReminders() error = %v", err)
+	}
+
+	if sent != 2 || len(notifier.sent) != 2 || notifier.sent[0] != "new" || notifier.sent[1] != "active-again" {
+		t.Errorf("SendReminders() sent %d to %v, want new and active-again", sent, notifier.sent)
+	}
+
+	if _, ok := repo.reminded["failing"]; ok {
+		t.Error("failed reminder was marked as sent")
+	}
+
+	if _, err := u.PurgeCarts(ctx, now); err != nil || !repo.purged.Equal(now.Add(-30*24*time.Hour)) {
+		t.Errorf("PurgeCarts() purged before %v, %v", repo.purged, err)
+	}
+}
@@ -50,13 +50,15 @@ func (u *AddressUseCase) DeleteAddress(ctx context.Context, id string, userID st
 }
 
 func addressFromRequest(req entity.AddressRequest) entity.Address {
+	longitude, latitude := req.Longitude, req.Latitude
+
 	return entity.Address{
 		Name:       req.Name,
 		Phone:      req.Phone,
 		Address:    req.Address,
 		City:       req.City,
 		PostalCode: req.PostalCode,
-		Longitude:  req.Longitude,
-		Latitude:   req.Latitude,
+		Longitude:  &longitude,
+		Latitude:   &latitude,
 	}
 }
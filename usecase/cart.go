@@ -2,43 +2,288 @@ package usecase
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
 
 	"github.com/yosepalexsander/waysbucks-api/entity"
 	"github.com/yosepalexsander/waysbucks-api/repository"
 )
 
+var ErrCartQtyExceeded = fmt.Errorf("quantity of a cart line cannot exceed %d", entity.MaxCartQty)
+
+// CartChangedError is returned by checkout when ordered lines drifted from
+// the catalog. It carries the same report as ValidateCart.
+type CartChangedError struct {
+	Validation *entity.CartValidation
+}
+
+func (e *CartChangedError) Error() string {
+	return "cart has changed since it was priced"
+}
+
 type CartUseCase struct {
-	repo repository.CartRepository
+	repo       repository.CartRepository
+	pricer     pricer
+	translator translator
 }
 
-func NewCartUseCase(r repository.CartRepository) CartUseCase {
-	return CartUseCase{r}
+func NewCartUseCase(r repository.CartRepository, productRepo repository.ProductFinder, storeRepo repository.StoreFinder, translations repository.TranslationFinder) CartUseCase {
+	return CartUseCase{r, pricer{productRepo, storeRepo}, translator{translations}}
 }
 
-func (u *CartUseCase) FindCarts(ctx context.Context, userID string) ([]entity.Cart, error) {
-	carts, err := u.repo.FindCarts(ctx, userID)
+func (u *CartUseCase) FindCarts(ctx context.Context, owner entity.CartOwner, locale string) ([]entity.Cart, error) {
+	carts, err := u.repo.FindCarts(ctx, owner)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := u.translator.translateCarts(ctx, carts, locale); err != nil {
+		return nil, err
+	}
+
 	return carts, nil
 }
 
-func (u *CartUseCase) SaveCart(ctx context.Context, req entity.CartRequest, userId string) error {
-	cart := entity.NewCart(req.ProductId, req.Price, req.Qty, req.ToppingIds, userId)
+// SaveCart adds a line priced from the current catalog. A price sent by the
+// client must match the server price. The line is merged into an identical
+// line already in the cart.
+func (u *CartUseCase) SaveCart(ctx context.Context, req entity.CartRequest, owner entity.CartOwner) error {
+	toppingIDs := entity.NormalizeToppingIds(req.ToppingIds)
 
-	err := u.repo.SaveCart(ctx, cart)
+	breakdown, err := u.pricer.priceLine(ctx, req.StoreId, req.ProductId, req.VariantId, toppingIDs, req.Qty)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	if err := checkPrice(req.Price, breakdown); err != nil {
+		return err
+	}
+
+	return u.addLine(ctx, entity.NewCart(req.StoreId, req.ProductId, req.VariantId, breakdown, req.Qty, toppingIDs, owner))
+}
+
+// SaveCustomization adds a saved customization to the user cart. Availability
+// and prices are checked against the current catalog instead of the values
+// known when the customization was saved.
+func (u *CartUseCase) SaveCustomization(ctx context.Context, c entity.Customization, userID string) error {
+	toppingIDs := entity.NormalizeToppingIds(c.ToppingIds)
+
+	breakdown, err := u.pricer.priceLine(ctx, nil, c.ProductId, c.VariantId, toppingIDs, c.Qty)
+	if err != nil {
+		return err
+	}
+
+	return u.addLine(ctx, entity.NewCart(nil, c.ProductId, c.VariantId, breakdown, c.Qty, toppingIDs, entity.CartOwner{UserId: userID}))
+}
+
+func (u *CartUseCase) UpdateCartQty(ctx context.Context, id int, owner entity.CartOwner, qty int) error {
+	cart, err := u.repo.FindCart(ctx, id, owner)
+	if err != nil {
+		return err
+	}
+
+	if qty > entity.MaxCartQty {
+		return ErrCartQtyExceeded
+	}
+
+	cart.Qty = qty
+	if err := u.reprice(ctx, cart); err != nil {
+		return err
+	}
+
+	return u.repo.UpdateCart(ctx, *cart)
+}
+
+// UpdateCartToppings replaces the toppings of the line. When the line becomes
+// identical to another line of the cart both lines are merged.
+func (u *CartUseCase) UpdateCartToppings(ctx context.Context, id int, owner entity.CartOwner, toppingIDs []int64) error {
+	cart, err := u.repo.FindCart(ctx, id, owner)
+	if err != nil {
+		return err
+	}
+
+	cart.ToppingIds = entity.NormalizeToppingIds(toppingIDs)
+
+	match, err := u.repo.FindMatchingCart(ctx, *cart)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	if match == nil {
+		if err := u.reprice(ctx, cart); err != nil {
+			return err
+		}
+
+		return u.repo.UpdateCart(ctx, *cart)
+	}
+
+	if err := u.mergeInto(ctx, match, cart.Qty); err != nil {
+		return err
+	}
+
+	return u.repo.DeleteCart(ctx, cart.Id, owner)
+}
+
+func (u *CartUseCase) DeleteCart(ctx context.Context, id int, owner entity.CartOwner) error {
+	return u.repo.DeleteCart(ctx, id, owner)
+}
+
+// ValidateCart reports the lines whose price or availability drifted from the
+// catalog since they were added. With apply the lines are fixed: unavailable
+// lines are deleted, removed toppings are dropped and prices are refreshed.
+func (u *CartUseCase) ValidateCart(ctx context.Context, owner entity.CartOwner, apply bool) (*entity.CartValidation, error) {
+	lines, err := u.repo.FindCarts(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	validation := &entity.CartValidation{Issues: []entity.CartIssue{}}
+	for _, line := range lines {
+		issue, breakdown, err := u.pricer.checkLine(ctx, line.StoreId, line.ProductId, line.VariantId, line.ToppingIds, line.Qty, line.Price)
+		if err != nil {
+			return nil, err
+		}
+
+		if issue == nil {
+			continue
+		}
+
+		issue.CartId = line.Id
+		validation.Issues = append(validation.Issues, *issue)
+
+		if !apply {
+			continue
+		}
+
+		if breakdown == nil {
+			err = u.repo.DeleteCart(ctx, line.Id, owner)
+		} else {
+			line.ToppingIds = breakdown.ToppingIds()
+			line.Price = breakdown.Total
+			line.Breakdown = breakdown
+			err = u.repo.UpdateCart(ctx, line)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	validation.Valid = len(validation.Issues) == 0
+	validation.Applied = apply && !validation.Valid
+
+	return validation, nil
 }
 
-func (u *CartUseCase) UpdateCart(ctx context.Context, id int, userID string, data map[string]interface{}) error {
-	return u.repo.UpdateCart(ctx, id, userID, data)
+// MergeGuestCart moves the lines of a guest cart into the user cart. A line
+// identical to a user line is added to it up to MaxCartQty, and a line that
+// is no longer available is dropped instead of failing the whole merge. The
+// merge runs in a database transaction so a failure leaves both carts as
+// they were.
+func (u *CartUseCase) MergeGuestCart(ctx context.Context, guestID string, userID string) (*entity.CartMerge, error) {
+	guest := entity.CartOwner{GuestId: guestID}
+	user := entity.CartOwner{UserId: userID}
+
+	merge := &entity.CartMerge{}
+	err := u.repo.ExecTx(ctx, func(tx repository.CartTransactioner) error {
+		lines, err := tx.FindCarts(ctx, guest)
+		if err != nil {
+			return err
+		}
+
+		for _, line := range lines {
+			if err := u.moveLine(ctx, tx, line, user, merge); err != nil {
+				return err
+			}
+
+			if err := tx.DeleteCart(ctx, line.Id, guest); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return merge, nil
 }
 
-func (u *CartUseCase) DeleteCart(ctx context.Context, id int, userID string) error {
-	return u.repo.DeleteCart(ctx, id, userID)
+// addLine saves a new line or adds its quantity to an identical line
+func (u *CartUseCase) addLine(ctx context.Context, cart entity.Cart) error {
+	if cart.Qty > entity.MaxCartQty {
+		return ErrCartQtyExceeded
+	}
+
+	match, err := u.repo.FindMatchingCart(ctx, cart)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return u.repo.SaveCart(ctx, cart)
+		}
+		return err
+	}
+
+	return u.mergeInto(ctx, match, cart.Qty)
+}
+
+func (u *CartUseCase) mergeInto(ctx context.Context, cart *entity.Cart, qty int) error {
+	cart.Qty += qty
+	if cart.Qty > entity.MaxCartQty {
+		return ErrCartQtyExceeded
+	}
+
+	if err := u.reprice(ctx, cart); err != nil {
+		return err
+	}
+
+	return u.repo.UpdateCart(ctx, *cart)
+}
+
+func (u *CartUseCase) moveLine(ctx context.Context, tx repository.CartTransactioner, line entity.Cart, owner entity.CartOwner, merge *entity.CartMerge) error {
+	breakdown, err := u.pricer.priceLine(ctx, line.StoreId, line.ProductId, line.VariantId, line.ToppingIds, line.Qty)
+	if err != nil {
+		if isUnavailable(err) {
+			merge.Dropped++
+			return nil
+		}
+		return err
+	}
+
+	cart := entity.NewCart(line.StoreId, line.ProductId, line.VariantId, breakdown, line.Qty, line.ToppingIds, owner)
+
+	match, err := tx.FindMatchingCart(ctx, cart)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			merge.Merged++
+			return tx.SaveCart(ctx, cart)
+		}
+		return err
+	}
+
+	match.Qty += line.Qty
+	if match.Qty > entity.MaxCartQty {
+		match.Qty = entity.MaxCartQty
+		merge.Capped++
+	}
+
+	if err := u.reprice(ctx, match); err != nil {
+		return err
+	}
+
+	merge.Merged++
+	return tx.UpdateCart(ctx, *match)
+}
+
+// reprice sets the price of the line from the current catalog
+func (u *CartUseCase) reprice(ctx context.Context, cart *entity.Cart) error {
+	breakdown, err := u.pricer.priceLine(ctx, cart.StoreId, cart.ProductId, cart.VariantId, cart.ToppingIds, cart.Qty)
+	if err != nil {
+		return err
+	}
+
+	cart.Price = breakdown.Total
+	cart.Breakdown = breakdown
+
+	return nil
 }
@@ -0,0 +1,611 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/yosepalexsander/waysbucks-api/entity"
+	"github.com/yosepalexsander/waysbucks-api/repository"
+)
+
+type stubProductFinder struct {
+	products map[int]entity.Product
+	variants map[int]entity.ProductVariant
+	toppings map[int]entity.ProductTopping
+}
+
+func (s *stubProductFinder) FindProducts(ctx context.Context, whereClauses []string, orderClause string) ([]entity.Product, error) {
+	products := []entity.Product{}
+	for _, p := range s.products {
+		products = append(products, p)
+	}
+	return products, nil
+}
+
+func (s *stubProductFinder) FindProduct(ctx context.Context, id int) (*entity.Product, error) {
+	p, ok := s.products[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &p, nil
+}
+
+func (s *stubProductFinder) FindToppings(ctx context.Context) ([]entity.ProductTopping, error) {
+	toppings := []entity.ProductTopping{}
+	for _, t := range s.toppings {
+		toppings = append(toppings, t)
+	}
+	return toppings, nil
+}
+
+func (s *stubProductFinder) FindTopping(ctx context.Context, id int) (*entity.ProductTopping, error) {
+	t, ok := s.toppings[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &t, nil
+}
+
+func (s *stubProductFinder) FindToppingsByIds(ctx context.Context, ids []int64) ([]entity.ProductTopping, error) {
+	toppings := []entity.ProductTopping{}
+	for _, id := range ids {
+		if t, ok := s.toppings[int(id)]; ok {
+			toppings = append(toppings, t)
+		}
+	}
+	return toppings, nil
+}
+
+func (s *stubProductFinder) FindProductVariants(ctx context.Context, productID int) ([]entity.ProductVariant, error) {
+	variants := []entity.ProductVariant{}
+	for _, v := range s.variants {
+		if v.ProductId == productID {
+			variants = append(variants, v)
+		}
+	}
+	return variants, nil
+}
+
+func (s *stubProductFinder) FindVariant(ctx context.Context, id int) (*entity.ProductVariant, error) {
+	v, ok := s.variants[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &v, nil
+}
+
+func (s *stubProductFinder) FindProductImages(ctx context.Context, productID int) ([]entity.ProductImage, error) {
+	return []entity.ProductImage{}, nil
+}
+
+type stubStoreFinder struct {
+	staff    map[string][]int64
+	stores   map[int]entity.Store
+	products []entity.StoreItem
+	toppings []entity.StoreItem
+}
+
+func (s *stubStoreFinder) FindStores(ctx context.Context) ([]entity.Store, error) {
+	stores := []entity.Store{}
+	for _, store := range s.stores {
+		stores = append(stores, store)
+	}
+	return stores, nil
+}
+
+func (s *stubStoreFinder) FindStore(ctx context.Context, id int) (*entity.Store, error) {
+	store, ok := s.stores[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &store, nil
+}
+
+func (s *stubStoreFinder) FindStaffStoreIDs(ctx context.Context, userID string) ([]int64, error) {
+	if ids, ok := s.staff[userID]; ok {
+		return ids, nil
+	}
+	return []int64{}, nil
+}
+
+func (s *stubStoreFinder) FindStoreProducts(ctx context.Context, storeID int) ([]entity.StoreItem, error) {
+	return s.products, nil
+}
+
+func (s *stubStoreFinder) FindStoreToppings(ctx context.Context, storeID int) ([]entity.StoreItem, error) {
+	return s.toppings, nil
+}
+
+func newStubStoreFinder() *stubStoreFinder {
+	price := 25000
+
+	return &stubStoreFinder{
+		stores: map[int]entity.Store{
+			1: {Id: 1, Name: "Kemang", IsActive: true},
+			2: {Id: 2, Name: "Closed for renovation", IsActive: false},
+		},
+		products: []entity.StoreItem{{StoreId: 1, ItemId: 1, Price: &price, IsAvailable: true}},
+		toppings: []entity.StoreItem{{StoreId: 1, ItemId: 1, IsAvailable: false}},
+	}
+}
+
+func newStubProductFinder() *stubProductFinder {
+	return &stubProductFinder{
+		products: map[int]entity.Product{
+			1: {Id: 1, Name: "Latte", Price: 30000, IsAvailable: true},
+			2: {Id: 2, Name: "Mocha", Price: 32000, IsAvailable: false},
+		},
+		variants: map[int]entity.ProductVariant{
+			1: {Id: 1, ProductId: 1, Name: "Large", Price: 5000, IsAvailable: true},
+			2: {Id: 2, ProductId: 2, Name: "Large", Price: 5000, IsAvailable: true},
+		},
+		toppings: map[int]entity.ProductTopping{
+			1: {Id: 1, Name: "Boba", Price: 4000, IsAvailable: true},
+			2: {Id: 2, Name: "Jelly", Price: 3000, IsAvailable: false},
+		},
+	}
+}
+
+// stubCartRepository keeps cart lines in memory. ExecTx restores the lines
+// when fn fails, and UpdateCart fails with failUpdate when it is set.
+type stubCartRepository struct {
+	lines      []entity.Cart
+	nextID     int
+	abandoned  []entity.AbandonedCart
+	reminded   map[string]time.Time
+	purged     time.Time
+	failUpdate error
+}
+
+func (s *stubCartRepository) ExecTx(ctx context.Context, fn func(repository.CartTransactioner) error) error {
+	lines, nextID := append([]entity.Cart{}, s.lines...), s.nextID
+	if err := fn(s); err != nil {
+		s.lines, s.nextID = lines, nextID
+		return err
+	}
+	return nil
+}
+
+func (s *stubCartRepository) Rollback() error {
+	return nil
+}
+
+func (s *stubCartRepository) Commit() error {
+	return nil
+}
+
+func (s *stubCartRepository) FindCarts(ctx context.Context, owner entity.CartOwner) ([]entity.Cart, error) {
+	carts := []entity.Cart{}
+	for _, c := range s.lines {
+		if c.Owner() == owner {
+			carts = append(carts, c)
+		}
+	}
+	return carts, nil
+}
+
+func (s *stubCartRepository) FindCart(ctx context.Context, id int, owner entity.CartOwner) (*entity.Cart, error) {
+	for _, c := range s.lines {
+		if c.Id == id && c.Owner() == owner {
+			return &c, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (s *stubCartRepository) FindMatchingCart(ctx context.Context, cart entity.Cart) (*entity.Cart, error) {
+	for _, c := range s.lines {
+		if c.Id != cart.Id && c.Owner() == cart.Owner() && c.ProductId == cart.ProductId &&
+			reflect.DeepEqual(c.VariantId, cart.VariantId) && reflect.DeepEqual(c.StoreId, cart.StoreId) &&
+			reflect.DeepEqual(entity.NormalizeToppingIds(c.ToppingIds), entity.NormalizeToppingIds(cart.ToppingIds)) {
+			return &c, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (s *stubCartRepository) SaveCart(ctx context.Context, cart entity.Cart) error {
+	s.nextID++
+	cart.Id = s.nextID
+	s.lines = append(s.lines, cart)
+	return nil
+}
+
+func (s *stubCartRepository) UpdateCart(ctx context.Context, cart entity.Cart) error {
+	if s.failUpdate != nil {
+		return s.failUpdate
+	}
+	for i, c := range s.lines {
+		if c.Id == cart.Id && c.Owner() == cart.Owner() {
+			s.lines[i] = cart
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (s *stubCartRepository) DeleteCart(ctx context.Context, id int, owner entity.CartOwner) error {
+	for i, c := range s.lines {
+		if c.Id == id && c.Owner() == owner {
+			s.lines = append(s.lines[:i], s.lines[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (s *stubCartRepository) FindAbandonedCarts(ctx context.Context, before time.Time) ([]entity.AbandonedCart, error) {
+	carts := []entity.AbandonedCart{}
+	for _, c := range s.abandoned {
+		if c.LastActivity.Before(before) {
+			carts = append(carts, c)
+		}
+	}
+	return carts, nil
+}
+
+func (s *stubCartRepository) MarkCartReminded(ctx context.Context, userID string, at time.Time) error {
+	if s.reminded == nil {
+		s.reminded = map[string]time.Time{}
+	}
+	s.reminded[userID] = at
+	return nil
+}
+
+func (s *stubCartRepository) PurgeCarts(ctx context.Context, before time.Time) (int64, error) {
+	s.purged = before
+	return 0, nil
+}
+
+func TestMergeGuestCart(t *testing.T) {
+	ctx := context.Background()
+	guest := entity.CartOwner{GuestId: "guest"}
+	user := entity.CartOwner{UserId: "user"}
+	repo := &stubCartRepository{}
+	u := NewCartUseCase(repo, newStubProductFinder(), newStubStoreFinder(), newStubTranslationFinder())
+
+	mustSave := func(req entity.CartRequest, owner entity.CartOwner) {
+		t.Helper()
+		if err := u.SaveCart(ctx, req, owner); err != nil {
+			t.Fatalf("SaveCart() error = %v", err)
+		}
+	}
+
+	mustSave(entity.CartRequest{ProductId: 1, Qty: 15, ToppingIds: []int64{1}}, user)
+	mustSave(entity.CartRequest{ProductId: 1, Qty: 10, ToppingIds: []int64{1, 1}}, guest)
+	mustSave(entity.CartRequest{ProductId: 1, Qty: 2}, guest)
+	repo.lines = append(repo.lines, entity.Cart{Id: 99, ProductId: 2, Qty: 1, GuestId: guest.GuestId})
+
+	merge, err := u.MergeGuestCart(ctx, guest.GuestId, user.UserId)
+	if err != nil {
+		t.Fatalf("MergeGuestCart() error = %v", err)
+	}
+
+	if want := (entity.CartMerge{Merged: 2, Capped: 1, Dropped: 1}); *merge != want {
+		t.Errorf("MergeGuestCart() = %+v, want %+v", *merge, want)
+	}
+
+	if lines, _ := repo.FindCarts(ctx, guest); len(lines) != 0 {
+		t.Errorf("guest cart still has %d lines", len(lines))
+	}
+
+	lines, _ := repo.FindCarts(ctx, user)
+	if len(lines) != 2 {
+		t.Fatalf("user cart has %d lines, want 2", len(lines))
+	}
+
+	if lines[0].Qty != entity.MaxCartQty || lines[0].Price != entity.MaxCartQty*34000 {
+		t.Errorf("merged line qty = %d price = %d, want qty %d priced from the catalog", lines[0].Qty, lines[0].Price, entity.MaxCartQty)
+	}
+
+	if lines[1].Qty != 2 || lines[1].UserId != user.UserId {
+		t.Errorf("moved line = %+v, want qty 2 owned by the user", lines[1])
+	}
+}
+
+func TestMergeGuestCartRollsBack(t *testing.T) {
+	ctx := context.Background()
+	guest := entity.CartOwner{GuestId: "guest"}
+	user := entity.CartOwner{UserId: "user"}
+	repo := &stubCartRepository{}
+	u := NewCartUseCase(repo, newStubProductFinder(), newStubStoreFinder(), newStubTranslationFinder())
+
+	for _, line := range []struct {
+		req   entity.CartRequest
+		owner entity.CartOwner
+	}{
+		{entity.CartRequest{ProductId: 1, Qty: 1, ToppingIds: []int64{1}}, user},
+		{entity.CartRequest{ProductId: 1, Qty: 2}, guest},
+		{entity.CartRequest{ProductId: 1, Qty: 3, ToppingIds: []int64{1}}, guest},
+	} {
+		if err := u.SaveCart(ctx, line.req, line.owner); err != nil {
+			t.Fatalf("SaveCart() error = %v", err)
+		}
+	}
+
+	// the first guest line is moved before the second fails
+	repo.failUpdate = errors.New("connection lost")
+	if _, err := u.MergeGuestCart(ctx, guest.GuestId, user.UserId); err != repo.failUpdate {
+		t.Fatalf("MergeGuestCart() error = %v, want %v", err, repo.failUpdate)
+	}
+
+	if lines, _ := repo.FindCarts(ctx, guest); len(lines) != 2 {
+		t.Errorf("guest cart has %d lines, want 2", len(lines))
+	}
+	if lines, _ := repo.FindCarts(ctx, user); len(lines) != 1 || lines[0].Qty != 1 {
+		t.Errorf("user cart = %+v, want the line it had", lines)
+	}
+}
+
+func TestPriceLine(t *testing.T) {
+	p := pricer{newStubProductFinder(), newStubStoreFinder()}
+	large, otherLarge := 1, 2
+	store, inactiveStore, missingStore := 1, 2, 9
+
+	tests := []struct {
+		name       string
+		storeID    *int
+		productID  int
+		variantID  *int
+		toppingIDs []int64
+		qty        int
+		want       int
+		wantErr    error
+	}{
+		{name: "product only", productID: 1, qty: 2, want: 60000},
+		{name: "variant and topping", productID: 1, variantID: &large, toppingIDs: []int64{1}, qty: 2, want: 78000},
+		{name: "unavailable product", productID: 2, qty: 1, wantErr: ErrProductUnavailable},
+		{name: "missing product", productID: 9, qty: 1, wantErr: ErrProductUnavailable},
+		{name: "variant of other product", productID: 1, variantID: &otherLarge, qty: 1, wantErr: ErrVariantUnavailable},
+		{name: "unavailable topping", productID: 1, toppingIDs: []int64{2}, qty: 1, wantErr: ErrToppingUnavailable},
+		{name: "missing topping", productID: 1, toppingIDs: []int64{9}, qty: 1, wantErr: ErrToppingUnavailable},
+		{name: "store price", storeID: &store, productID: 1, qty: 2, want: 50000},
+		{name: "topping unavailable in store", storeID: &store, productID: 1, toppingIDs: []int64{1}, qty: 1, wantErr: ErrToppingUnavailable},
+		{name: "inactive store", storeID: &inactiveStore, productID: 1, qty: 1, wantErr: ErrStoreUnavailable},
+		{name: "missing store", storeID: &missingStore, productID: 1, qty: 1, wantErr: ErrStoreUnavailable},
+		{name: "zero qty", productID: 1, qty: 0, wantErr: ErrInvalidQty},
+		{name: "negative qty", productID: 1, qty: -2, wantErr: ErrInvalidQty},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := p.priceLine(context.Background(), tt.storeID, tt.productID, tt.variantID, tt.toppingIDs, tt.qty)
+			if err != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+
+			if err == nil && got.Total != tt.want {
+				t.Errorf("expected price %d, got %d", tt.want, got.Total)
+			}
+		})
+	}
+}
+
+func TestCheckPrice(t *testing.T) {
+	breakdown := entity.NewPriceBreakdown(30000, 5000, []entity.ToppingPrice{{Id: 1, Price: 4000}}, 2)
+
+	if breakdown.Unit != 39000 || breakdown.Total != 78000 {
+		t.Fatalf("expected unit 39000 and total 78000, got %d and %d", breakdown.Unit, breakdown.Total)
+	}
+
+	if err := checkPrice(0, &breakdown); err != nil {
+		t.Errorf("expected missing client price to be accepted, got %v", err)
+	}
+
+	if err := checkPrice(78000, &breakdown); err != nil {
+		t.Errorf("expected matching client price to be accepted, got %v", err)
+	}
+
+	if err := checkPrice(1, &breakdown); err != ErrPriceMismatch {
+		t.Errorf("expected %v, got %v", ErrPriceMismatch, err)
+	}
+}
+
+func TestCheckTotals(t *testing.T) {
+	breakdown := entity.NewOrderBreakdown([]entity.PriceBreakdown{entity.NewPriceBreakdown(30000, 0, nil, 2)}, transactionFees(9000))
+
+	tests := []struct {
+		name    string
+		request entity.TransactionRequest
+		wantErr error
+	}{
+		{name: "left to the server", request: entity.TransactionRequest{}},
+		{name: "matching", request: entity.TransactionRequest{ServiceFee: serviceFee, DeliveryFee: 9000, Total: 60000 + serviceFee + 9000}},
+		{name: "total without fee", request: entity.TransactionRequest{Total: 60000}, wantErr: ErrTotalMismatch},
+		{name: "total without delivery", request: entity.TransactionRequest{Total: 60000 + serviceFee}, wantErr: ErrTotalMismatch},
+		{name: "lower fee", request: entity.TransactionRequest{ServiceFee: 1000}, wantErr: ErrTotalMismatch},
+		{name: "lower delivery fee", request: entity.TransactionRequest{DeliveryFee: 1000}, wantErr: ErrTotalMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkTotals(tt.request, breakdown); err != tt.wantErr {
+				t.Errorf("checkTotals() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateCart(t *testing.T) {
+	ctx := context.Background()
+	owner := entity.CartOwner{UserId: "user"}
+	repo := &stubCartRepository{lines: []entity.Cart{
+		{Id: 1, UserId: "user", ProductId: 1, Qty: 1, Price: 30000, ToppingIds: []int64{}},
+		{Id: 2, UserId: "user", ProductId: 1, Qty: 2, Price: 50000, ToppingIds: []int64{}},
+		{Id: 3, UserId: "user", ProductId: 1, Qty: 1, Price: 37000, ToppingIds: []int64{1, 2}},
+		{Id: 4, UserId: "user", ProductId: 2, Qty: 1, Price: 32000, ToppingIds: []int64{}},
+	}}
+	u := NewCartUseCase(repo, newStubProductFinder(), newStubStoreFinder(), newStubTranslationFinder())
+
+	validation, err := u.ValidateCart(ctx, owner, false)
+	if err != nil {
+		t.Fatalf("ValidateCart() error = %v", err)
+	}
+
+	want := []entity.CartIssue{
+		{CartId: 2, ProductId: 1, Kind: entity.CartIssuePriceChanged, Message: ErrPriceMismatch.Error(), OldPrice: 50000, NewPrice: 60000},
+		{CartId: 3, ProductId: 1, Kind: entity.CartIssueToppingRemoved, Message: ErrToppingUnavailable.Error(), OldPrice: 37000, NewPrice: 34000, ToppingIds: []int64{2}},
+		{CartId: 4, ProductId: 2, Kind: entity.CartIssueUnavailable, Message: ErrProductUnavailable.Error(), OldPrice: 32000},
+	}
+	if validation.Valid || validation.Applied || !reflect.DeepEqual(validation.Issues, want) {
+		t.Fatalf("ValidateCart() = %+v, want issues %+v", validation, want)
+	}
+
+	if len(repo.lines) != 4 || repo.lines[1].Price != 50000 {
+		t.Fatal("ValidateCart() without apply changed the cart")
+	}
+
+	if validation, err = u.ValidateCart(ctx, owner, true); err != nil || !validation.Applied {
+		t.Fatalf("ValidateCart(apply) = %+v, %v", validation, err)
+	}
+
+	if validation, _ = u.ValidateCart(ctx, owner, false); !validation.Valid {
+		t.Errorf("cart still has issues after apply: %+v", validation.Issues)
+	}
+
+	if len(repo.lines) != 3 || !reflect.DeepEqual(repo.lines[2].ToppingIds, []int64{1}) {
+		t.Errorf("cart after apply = %+v", repo.lines)
+	}
+}
+
+func TestSaveCart(t *testing.T) {
+	owner := entity.CartOwner{UserId: "user"}
+	large := 1
+
+	type line struct {
+		productID  int
+		variantID  *int
+		toppingIDs []int64
+		qty        int
+	}
+
+	tests := []struct {
+		name      string
+		saved     []line
+		save      line
+		wantErr   error
+		wantQty   []int
+		wantPrice []int
+	}{
+		{
+			name:      "same product and topping set merges",
+			saved:     []line{{productID: 1, toppingIDs: []int64{3, 1}, qty: 2}},
+			save:      line{productID: 1, toppingIDs: []int64{1, 3, 1}, qty: 3},
+			wantQty:   []int{5},
+			wantPrice: []int{5 * 37500},
+		},
+		{
+			name:      "same product without toppings merges",
+			saved:     []line{{productID: 1, qty: 1}},
+			save:      line{productID: 1, toppingIDs: []int64{}, qty: 1},
+			wantQty:   []int{2},
+			wantPrice: []int{60000},
+		},
+		{
+			name:      "different topping set adds a line",
+			saved:     []line{{productID: 1, toppingIDs: []int64{1}, qty: 1}},
+			save:      line{productID: 1, toppingIDs: []int64{1, 3}, qty: 1},
+			wantQty:   []int{1, 1},
+			wantPrice: []int{34000, 37500},
+		},
+		{
+			name:      "different variant adds a line",
+			saved:     []line{{productID: 1, qty: 1}},
+			save:      line{productID: 1, variantID: &large, qty: 1},
+			wantQty:   []int{1, 1},
+			wantPrice: []int{30000, 35000},
+		},
+		{
+			name:      "merge up to the limit",
+			saved:     []line{{productID: 1, qty: 15}},
+			save:      line{productID: 1, qty: entity.MaxCartQty - 15},
+			wantQty:   []int{entity.MaxCartQty},
+			wantPrice: []int{entity.MaxCartQty * 30000},
+		},
+		{
+			name:      "new line over the limit is rejected",
+			save:      line{productID: 1, qty: entity.MaxCartQty + 1},
+			wantErr:   ErrCartQtyExceeded,
+			wantQty:   []int{},
+			wantPrice: []int{},
+		},
+		{
+			name:      "merge over the limit is rejected",
+			saved:     []line{{productID: 1, toppingIDs: []int64{1}, qty: 15}},
+			save:      line{productID: 1, toppingIDs: []int64{1}, qty: 6},
+			wantErr:   ErrCartQtyExceeded,
+			wantQty:   []int{15},
+			wantPrice: []int{15 * 34000},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			products := newStubProductFinder()
+			products.toppings[3] = entity.ProductTopping{Id: 3, Name: "Grass Jelly", Price: 3500, IsAvailable: true}
+			repo := &stubCartRepository{}
+			u := NewCartUseCase(repo, products, newStubStoreFinder(), newStubTranslationFinder())
+
+			for _, l := range tt.saved {
+				if err := u.SaveCart(ctx, entity.CartRequest{ProductId: l.productID, VariantId: l.variantID, ToppingIds: l.toppingIDs, Qty: l.qty}, owner); err != nil {
+					t.Fatalf("SaveCart() error = %v", err)
+				}
+			}
+
+			req := entity.CartRequest{ProductId: tt.save.productID, VariantId: tt.save.variantID, ToppingIds: tt.save.toppingIDs, Qty: tt.save.qty}
+			if err := u.SaveCart(ctx, req, owner); err != tt.wantErr {
+				t.Fatalf("SaveCart() error = %v, want %v", err, tt.wantErr)
+			}
+
+			qty, price := []int{}, []int{}
+			for _, c := range repo.lines {
+				qty = append(qty, c.Qty)
+				price = append(price, c.Price)
+			}
+
+			if !reflect.DeepEqual(qty, tt.wantQty) || !reflect.DeepEqual(price, tt.wantPrice) {
+				t.Errorf("lines qty = %v prices = %v, want %v %v", qty, price, tt.wantQty, tt.wantPrice)
+			}
+		})
+	}
+}
+
+func TestUpdateCartToppingsMergesIdenticalLine(t *testing.T) {
+	ctx := context.Background()
+	owner := entity.CartOwner{UserId: "user"}
+	repo := &stubCartRepository{}
+	u := NewCartUseCase(repo, newStubProductFinder(), newStubStoreFinder(), newStubTranslationFinder())
+
+	for _, req := range []entity.CartRequest{{ProductId: 1, ToppingIds: []int64{1}, Qty: 2}, {ProductId: 1, Qty: 3}} {
+		if err := u.SaveCart(ctx, req, owner); err != nil {
+			t.Fatalf("SaveCart() error = %v", err)
+		}
+	}
+
+	if err := u.UpdateCartToppings(ctx, 2, owner, []int64{1, 1}); err != nil {
+		t.Fatalf("UpdateCartToppings() error = %v", err)
+	}
+
+	if len(repo.lines) != 1 || repo.lines[0].Id != 1 || repo.lines[0].Qty != 5 || repo.lines[0].Price != 5*34000 {
+		t.Errorf("cart = %+v, want one line of 5 with the topping", repo.lines)
+	}
+}
+
+func TestSaveCustomizationQtyLimit(t *testing.T) {
+	repo := &stubCartRepository{}
+	u := NewCartUseCase(repo, newStubProductFinder(), newStubStoreFinder(), newStubTranslationFinder())
+
+	customization := entity.Customization{ProductId: 1, Qty: entity.MaxCartQty + 1}
+	if err := u.SaveCustomization(context.Background(), customization, "user"); err != ErrCartQtyExceeded {
+		t.Fatalf("SaveCustomization() error = %v, want %v", err, ErrCartQtyExceeded)
+	}
+
+	if len(repo.lines) != 0 {
+		t.Errorf("SaveCustomization() saved %+v", repo.lines)
+	}
+}
@@ -0,0 +1,363 @@
+package usecase
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/yosepalexsander/waysbucks-api/entity"
+	"github.com/yosepalexsander/waysbucks-api/helper"
+	"github.com/yosepalexsander/waysbucks-api/repository"
+	"github.com/yosepalexsander/waysbucks-api/thirdparty"
+)
+
+var ErrInvalidCSV = errors.New("csv file is invalid")
+
+var (
+	productCSVHeader = []string{"id", "name", "description", "image", "price", "is_available"}
+	toppingCSVHeader = []string{"id", "name", "image", "price", "is_available"}
+)
+
+func (u *ProductUseCase) ExportProducts(ctx context.Context, w io.Writer) error {
+	products, err := u.repo.FindProducts(ctx, nil, "id ASC")
+	if err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(productCSVHeader); err != nil {
+		return err
+	}
+
+	for _, p := range products {
+		record := []string{strconv.Itoa(p.Id), p.Name, p.Description, p.Image, strconv.Itoa(p.Price), strconv.FormatBool(p.IsAvailable)}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func (u *ProductUseCase) ExportToppings(ctx context.Context, w io.Writer) error {
+	toppings, err := u.repo.FindToppings(ctx)
+	if err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(toppingCSVHeader); err != nil {
+		return err
+	}
+
+	for _, t := range toppings {
+		record := []string{strconv.Itoa(t.Id), t.Name, t.Image, strconv.Itoa(t.Price), strconv.FormatBool(t.IsAvailable)}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// ImportProducts diffs the csv rows against the current products. Rows without
+// an id are created and rows with an id update the matching product. Nothing
+// is written when dryRun is true or when any row is invalid.
+func (u *ProductUseCase) ImportProducts(ctx context.Context, r io.Reader, dryRun bool) (*entity.ImportReport, error) {
+	rows, err := readCSV(r, productCSVHeader)
+	if err != nil {
+		return nil, err
+	}
+
+	products, err := u.repo.FindProducts(ctx, nil, "id ASC")
+	if err != nil {
+		return nil, err
+	}
+
+	existing := make(map[int]entity.Product, len(products))
+	for _, p := range products {
+		existing[p.Id] = p
+	}
+
+	report := &entity.ImportReport{DryRun: dryRun}
+	creates := []entity.Product{}
+	updates := make(map[int]map[string]interface{})
+	replacedImages := make(map[int]string)
+
+	for i, row := range rows {
+		line := i + 2
+		req := entity.ProductRequest{
+			Name:        row["name"],
+			Description: row["description"],
+			Image:       row["image"],
+		}
+
+		id, err := parseCSVRow(row, &req.Price, &req.IsAvailable)
+		if err != nil {
+			report.Add(entity.ImportRow{Line: line, Name: req.Name, Action: entity.ImportActionError, Error: err.Error()})
+			continue
+		}
+
+		if valid, msg := helper.Validate(req); !valid {
+			report.Add(entity.ImportRow{Line: line, Id: id, Name: req.Name, Action: entity.ImportActionError, Error: msg})
+			continue
+		}
+
+		if id == 0 {
+			creates = append(creates, entity.NewProduct(req))
+			report.Add(entity.ImportRow{Line: line, Name: req.Name, Action: entity.ImportActionCreate})
+			continue
+		}
+
+		old, ok := existing[id]
+		if !ok {
+			report.Add(entity.ImportRow{Line: line, Id: id, Name: req.Name, Action: entity.ImportActionError, Error: fmt.Sprintf("product %d not found", id)})
+			continue
+		}
+
+		changes := make(map[string]entity.Change)
+		diffField(changes, "name", old.Name, req.Name)
+		diffField(changes, "description", old.Description, req.Description)
+		diffField(changes, "image", old.Image, req.Image)
+		diffField(changes, "price", old.Price, req.Price)
+		diffField(changes, "is_available", old.IsAvailable, req.IsAvailable)
+
+		if len(changes) == 0 {
+			report.Add(entity.ImportRow{Line: line, Id: id, Name: req.Name, Action: entity.ImportActionUnchanged})
+			continue
+		}
+
+		updates[id] = changesToData(changes)
+		if _, ok := changes["image"]; ok {
+			replacedImages[id] = old.Image
+		}
+		report.Add(entity.ImportRow{Line: line, Id: id, Name: req.Name, Action: entity.ImportActionUpdate, Changes: changes})
+	}
+
+	if dryRun || report.Failed > 0 {
+		return report, nil
+	}
+
+	defer u.cache.invalidate()
+
+	err = u.repo.ExecTx(ctx, func(tx repository.ProductTransactioner) error {
+		for _, p := range creates {
+			if err := tx.SaveProduct(ctx, p); err != nil {
+				return err
+			}
+		}
+
+		for id, data := range updates {
+			if err := tx.UpdateProduct(ctx, id, data); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for id, image := range replacedImages {
+		// the old image stays when it is still part of the product gallery
+		images, err := u.repo.FindProductImages(ctx, id)
+		if err != nil || inGallery(images, image) {
+			continue
+		}
+		_ = thirdparty.RemoveFile(ctx, image)
+	}
+
+	report.Applied = true
+	return report, nil
+}
+
+// ImportToppings works like ImportProducts for toppings.
+func (u *ProductUseCase) ImportToppings(ctx context.Context, r io.Reader, dryRun bool) (*entity.ImportReport, error) {
+	rows, err := readCSV(r, toppingCSVHeader)
+	if err != nil {
+		return nil, err
+	}
+
+	toppings, err := u.repo.FindToppings(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	existing := make(map[int]entity.ProductTopping, len(toppings))
+	for _, t := range toppings {
+		existing[t.Id] = t
+	}
+
+	report := &entity.ImportReport{DryRun: dryRun}
+	creates := []entity.ProductTopping{}
+	updates := make(map[int]map[string]interface{})
+	replacedImages := []string{}
+
+	for i, row := range rows {
+		line := i + 2
+		req := entity.ProductToppingRequest{
+			Name:  row["name"],
+			Image: row["image"],
+		}
+
+		id, err := parseCSVRow(row, &req.Price, &req.IsAvailable)
+		if err != nil {
+			report.Add(entity.ImportRow{Line: line, Name: req.Name, Action: entity.ImportActionError, Error: err.Error()})
+			continue
+		}
+
+		if valid, msg := helper.Validate(req); !valid {
+			report.Add(entity.ImportRow{Line: line, Id: id, Name: req.Name, Action: entity.ImportActionError, Error: msg})
+			continue
+		}
+
+		if id == 0 {
+			creates = append(creates, entity.NewProductTopping(req))
+			report.Add(entity.ImportRow{Line: line, Name: req.Name, Action: entity.ImportActionCreate})
+			continue
+		}
+
+		old, ok := existing[id]
+		if !ok {
+			report.Add(entity.ImportRow{Line: line, Id: id, Name: req.Name, Action: entity.ImportActionError, Error: fmt.Sprintf("topping %d not found", id)})
+			continue
+		}
+
+		changes := make(map[string]entity.Change)
+		diffField(changes, "name", old.Name, req.Name)
+		diffField(changes, "image", old.Image, req.Image)
+		diffField(changes, "price", old.Price, req.Price)
+		diffField(changes, "is_available", old.IsAvailable, req.IsAvailable)
+
+		if len(changes) == 0 {
+			report.Add(entity.ImportRow{Line: line, Id: id, Name: req.Name, Action: entity.ImportActionUnchanged})
+			continue
+		}
+
+		updates[id] = changesToData(changes)
+		if _, ok := changes["image"]; ok {
+			replacedImages = append(replacedImages, old.Image)
+		}
+		report.Add(entity.ImportRow{Line: line, Id: id, Name: req.Name, Action: entity.ImportActionUpdate, Changes: changes})
+	}
+
+	if dryRun || report.Failed > 0 {
+		return report, nil
+	}
+
+	defer u.cache.invalidate()
+
+	err = u.repo.ExecTx(ctx, func(tx repository.ProductTransactioner) error {
+		for _, t := range creates {
+			if err := tx.SaveTopping(ctx, t); err != nil {
+				return err
+			}
+		}
+
+		for id, data := range updates {
+			if err := tx.UpdateTopping(ctx, id, data); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, image := range replacedImages {
+		_ = thirdparty.RemoveFile(ctx, image)
+	}
+
+	report.Applied = true
+	return report, nil
+}
+
+// readCSV reads every record of r keyed by the column names of its header
+// row. The header must contain all the expected columns in any order.
+func readCSV(r io.Reader, expected []string) ([]map[string]string, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, ErrInvalidCSV
+	}
+
+	index := make(map[string]int, len(header))
+	for i, column := range header {
+		index[strings.ToLower(strings.TrimSpace(column))] = i
+	}
+
+	for _, column := range expected {
+		if _, ok := index[column]; !ok {
+			return nil, ErrInvalidCSV
+		}
+	}
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, ErrInvalidCSV
+	}
+
+	rows := make([]map[string]string, 0, len(records))
+	for _, record := range records {
+		row := make(map[string]string, len(expected))
+		for _, column := range expected {
+			row[column] = strings.TrimSpace(record[index[column]])
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// parseCSVRow parses the columns shared by products and toppings and returns
+// the row id, which is zero for new records.
+func parseCSVRow(row map[string]string, price *int, isAvailable *bool) (int, error) {
+	var id int
+	var err error
+
+	if row["id"] != "" {
+		if id, err = strconv.Atoi(row["id"]); err != nil || id < 1 {
+			return 0, errors.New("id must be a positive number")
+		}
+	}
+
+	if row["price"] != "" {
+		if *price, err = strconv.Atoi(row["price"]); err != nil {
+			return id, errors.New("price must be a number")
+		}
+	}
+
+	if row["is_available"] != "" {
+		if *isAvailable, err = strconv.ParseBool(row["is_available"]); err != nil {
+			return id, errors.New("is_available must be true or false")
+		}
+	}
+
+	return id, nil
+}
+
+func diffField(changes map[string]entity.Change, field string, old interface{}, new interface{}) {
+	if old != new {
+		changes[field] = entity.Change{Old: old, New: new}
+	}
+}
+
+func changesToData(changes map[string]entity.Change) map[string]interface{} {
+	data := make(map[string]interface{}, len(changes))
+	for field, change := range changes {
+		data[field] = change.New
+	}
+
+	return data
+}
@@ -0,0 +1,92 @@
+package usecase
+
+import (
+	"expvar"
+	"sync"
+	"time"
+)
+
+const (
+	// catalogCacheTTL bounds how stale data changed outside ProductUseCase,
+	// like review ratings, can get
+	catalogCacheTTL  = time.Minute
+	catalogCacheSize = 256
+)
+
+// catalogCacheStats publishes "<kind>_hits" and "<kind>_misses" counters on
+// the admin only /api/v1/metrics endpoint
+var catalogCacheStats = expvar.NewMap("catalog_cache")
+
+type catalogCacheEntry struct {
+	value        interface{}
+	lastModified time.Time
+	expiresAt    time.Time
+}
+
+// catalogCache keeps the public catalog listings in process. It is shared by
+// every copy of ProductUseCase and emptied by each catalog mutation.
+type catalogCache struct {
+	mu            sync.RWMutex
+	entries       map[string]catalogCacheEntry
+	generation    uint64
+	invalidatedAt time.Time
+}
+
+func newCatalogCache() *catalogCache {
+	return &catalogCache{entries: map[string]catalogCacheEntry{}}
+}
+
+// get returns the cached entry and the generation a loaded value must be
+// stored with on a miss
+func (c *catalogCache) get(kind string, key string) (catalogCacheEntry, uint64, bool) {
+	c.mu.RLock()
+	entry, ok := c.entries[kind+":"+key]
+	generation := c.generation
+	c.mu.RUnlock()
+
+	if ok && time.Now().Before(entry.expiresAt) {
+		catalogCacheStats.Add(kind+"_hits", 1)
+		return entry, generation, true
+	}
+
+	catalogCacheStats.Add(kind+"_misses", 1)
+	return catalogCacheEntry{}, generation, false
+}
+
+// set stores a loaded value unless the catalog changed while it was loading.
+// The last modified time never goes before the last invalidation so deletes
+// are reflected too.
+func (c *catalogCache) set(kind string, key string, generation uint64, value interface{}, lastModified time.Time) catalogCacheEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.invalidatedAt.After(lastModified) {
+		lastModified = c.invalidatedAt
+	}
+
+	entry := catalogCacheEntry{
+		value:        value,
+		lastModified: lastModified.UTC().Truncate(time.Second),
+		expiresAt:    time.Now().Add(catalogCacheTTL),
+	}
+
+	if generation != c.generation {
+		return entry
+	}
+
+	if len(c.entries) >= catalogCacheSize {
+		c.entries = map[string]catalogCacheEntry{}
+	}
+	c.entries[kind+":"+key] = entry
+
+	return entry
+}
+
+func (c *catalogCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = map[string]catalogCacheEntry{}
+	c.generation++
+	c.invalidatedAt = time.Now()
+}
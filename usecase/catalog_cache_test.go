@@ -0,0 +1,48 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCatalogCache(t *testing.T) {
+	c := newCatalogCache()
+	updatedAt := time.Date(2023, 5, 1, 10, 0, 0, 500, time.UTC)
+
+	_, generation, ok := c.get("products", "en")
+	if ok {
+		t.Fatal("expected a miss on an empty cache")
+	}
+
+	entry := c.set("products", "en", generation, []int{1}, updatedAt)
+	if !entry.lastModified.Equal(updatedAt.Truncate(time.Second)) {
+		t.Errorf("expected last modified %v, got %v", updatedAt.Truncate(time.Second), entry.lastModified)
+	}
+
+	if _, _, ok := c.get("products", "en"); !ok {
+		t.Fatal("expected a hit after set")
+	}
+
+	if _, _, ok := c.get("toppings", "en"); ok {
+		t.Fatal("expected kinds to be cached separately")
+	}
+
+	_, generation, _ = c.get("products", "id")
+	c.invalidate()
+
+	if _, _, ok := c.get("products", "en"); ok {
+		t.Fatal("expected a miss after invalidate")
+	}
+
+	// a value loaded before the invalidation must not be cached
+	c.set("products", "id", generation, []int{1}, updatedAt)
+	if _, _, ok := c.get("products", "id"); ok {
+		t.Fatal("expected stale value to be dropped")
+	}
+
+	_, generation, _ = c.get("products", "id")
+	entry = c.set("products", "id", generation, []int{1}, updatedAt)
+	if !entry.lastModified.After(updatedAt) {
+		t.Errorf("expected last modified to include the invalidation, got %v", entry.lastModified)
+	}
+}
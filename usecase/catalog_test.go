@@ -0,0 +1,45 @@
+package usecase
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadCSV(t *testing.T) {
+	input := "Price,name,id,image,is_available\n15000, Boba ,,boba.png,true\n"
+
+	rows, err := readCSV(strings.NewReader(input), toppingCSVHeader)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(rows) != 1 {
+		t.Fatalf("expected 1 row, got %d", len(rows))
+	}
+
+	if rows[0]["name"] != "Boba" || rows[0]["price"] != "15000" || rows[0]["id"] != "" {
+		t.Errorf("unexpected row: %v", rows[0])
+	}
+
+	if _, err := readCSV(strings.NewReader("name,price\nBoba,1000\n"), toppingCSVHeader); err != ErrInvalidCSV {
+		t.Errorf("expected ErrInvalidCSV for missing columns, got %v", err)
+	}
+}
+
+func TestParseCSVRow(t *testing.T) {
+	var price int
+	var isAvailable bool
+
+	id, err := parseCSVRow(map[string]string{"id": "3", "price": "12000", "is_available": "true"}, &price, &isAvailable)
+	if err != nil || id != 3 || price != 12000 || !isAvailable {
+		t.Errorf("unexpected result id=%d price=%d available=%v err=%v", id, price, isAvailable, err)
+	}
+
+	if _, err := parseCSVRow(map[string]string{"price": "abc"}, &price, &isAvailable); err == nil {
+		t.Error("expected error for invalid price")
+	}
+
+	if _, err := parseCSVRow(map[string]string{"id": "-1"}, &price, &isAvailable); err == nil {
+		t.Error("expected error for invalid id")
+	}
+}
@@ -0,0 +1,82 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/yosepalexsander/waysbucks-api/entity"
+)
+
+const (
+	DefaultDeliveryBaseFee       = 5000
+	DefaultDeliveryPerKmFee      = 2000
+	DefaultDeliveryFreeAbove     = 150000
+	DefaultDeliveryMaxDistanceKm = 15
+)
+
+var (
+	ErrAddressNotFound    = errors.New("address not found")
+	ErrAddressNotLocated  = errors.New("latitude and longitude of the address are required")
+	ErrOutOfDeliveryRange = errors.New("address is out of the delivery range of the store")
+)
+
+// QuoteDelivery prices the delivery of an order of the given subtotal from
+// the store to a saved address or to coordinates, the way checkout does
+func (u *TransactionUseCase) QuoteDelivery(ctx context.Context, req entity.DeliveryQuoteRequest) (*entity.DeliveryQuote, error) {
+	store, err := u.stores.FindStore(ctx, req.StoreId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrStoreUnavailable
+		}
+		return nil, err
+	}
+
+	if !store.IsActive {
+		return nil, ErrStoreUnavailable
+	}
+
+	latitude, longitude := req.Latitude, req.Longitude
+	if req.AddressId != "" {
+		address, err := u.savedAddress(ctx, req.AddressId, req.UserId)
+		if err != nil {
+			return nil, err
+		}
+		latitude, longitude = address.Latitude, address.Longitude
+	}
+
+	return u.quoteDelivery(*store, latitude, longitude, req.Subtotal)
+}
+
+// quoteDelivery fails with ErrAddressNotLocated when the address has no
+// coordinates, such as a typed address without them or an old saved address
+func (u *TransactionUseCase) quoteDelivery(store entity.Store, latitude *float64, longitude *float64, subtotal int) (*entity.DeliveryQuote, error) {
+	if latitude == nil || longitude == nil {
+		return nil, ErrAddressNotLocated
+	}
+
+	quote, ok := u.delivery.Quote(store, *latitude, *longitude, subtotal)
+	if !ok {
+		return nil, ErrOutOfDeliveryRange
+	}
+
+	return &quote, nil
+}
+
+// savedAddress returns the saved address of the user. Addresses of other
+// users are reported as not found.
+func (u *TransactionUseCase) savedAddress(ctx context.Context, id string, userID string) (*entity.Address, error) {
+	address, err := u.addresses.FindAddress(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrAddressNotFound
+		}
+		return nil, err
+	}
+
+	if address.UserId != userID {
+		return nil, ErrAddressNotFound
+	}
+
+	return address, nil
+}
@@ -0,0 +1,90 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/yosepalexsander/waysbucks-api/entity"
+	"github.com/yosepalexsander/waysbucks-api/repository"
+)
+
+type FavoriteUseCase struct {
+	repo repository.FavoriteRepository
+	cart CartUseCase
+}
+
+func NewFavoriteUseCase(repo repository.FavoriteRepository, cart CartUseCase) FavoriteUseCase {
+	return FavoriteUseCase{repo, cart}
+}
+
+func (u *FavoriteUseCase) FindFavoriteProducts(ctx context.Context, userID string, locale string) ([]entity.Product, error) {
+	products, err := u.repo.FindFavoriteProducts(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.cart.translator.translateProducts(ctx, products, locale); err != nil {
+		return nil, err
+	}
+
+	return products, nil
+}
+
+func (u *FavoriteUseCase) AddFavorite(ctx context.Context, userID string, productID int) error {
+	if _, err := u.cart.pricer.products.FindProduct(ctx, productID); err != nil {
+		return err
+	}
+
+	return u.repo.SaveFavorite(ctx, userID, productID)
+}
+
+func (u *FavoriteUseCase) RemoveFavorite(ctx context.Context, userID string, productID int) error {
+	return u.repo.DeleteFavorite(ctx, userID, productID)
+}
+
+func (u *FavoriteUseCase) FindCustomizations(ctx context.Context, userID string, locale string) ([]entity.Customization, error) {
+	customizations, err := u.repo.FindCustomizations(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.cart.translator.translateCustomizations(ctx, customizations, locale); err != nil {
+		return nil, err
+	}
+
+	return customizations, nil
+}
+
+// CreateCustomization saves the customization of an existing product. The
+// variant, when set, must belong to the product.
+func (u *FavoriteUseCase) CreateCustomization(ctx context.Context, userID string, req entity.CustomizationRequest) error {
+	if _, err := u.cart.pricer.products.FindProduct(ctx, req.ProductId); err != nil {
+		return err
+	}
+
+	if req.VariantId != nil {
+		variant, err := u.cart.pricer.products.FindVariant(ctx, *req.VariantId)
+		if err != nil {
+			return err
+		}
+
+		if variant.ProductId != req.ProductId {
+			return sql.ErrNoRows
+		}
+	}
+
+	return u.repo.SaveCustomization(ctx, entity.NewCustomization(userID, req))
+}
+
+func (u *FavoriteUseCase) DeleteCustomization(ctx context.Context, id int, userID string) error {
+	return u.repo.DeleteCustomization(ctx, id, userID)
+}
+
+func (u *FavoriteUseCase) AddCustomizationToCart(ctx context.Context, id int, userID string) error {
+	customization, err := u.repo.FindCustomization(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+
+	return u.cart.SaveCustomization(ctx, *customization, userID)
+}
@@ -0,0 +1,143 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/yosepalexsander/waysbucks-api/entity"
+)
+
+type stubFavoriteRepository struct {
+	favorites      []int
+	customizations []entity.Customization
+}
+
+func (s *stubFavoriteRepository) FindFavoriteProducts(ctx context.Context, userID string) ([]entity.Product, error) {
+	return []entity.Product{}, nil
+}
+
+func (s *stubFavoriteRepository) FindCustomizations(ctx context.Context, userID string) ([]entity.Customization, error) {
+	return s.customizations, nil
+}
+
+func (s *stubFavoriteRepository) FindCustomization(ctx context.Context, id int, userID string) (*entity.Customization, error) {
+	for _, c := range s.customizations {
+		if c.Id == id && c.UserId == userID {
+			return &c, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (s *stubFavoriteRepository) SaveFavorite(ctx context.Context, userID string, productID int) error {
+	s.favorites = append(s.favorites, productID)
+	return nil
+}
+
+func (s *stubFavoriteRepository) DeleteFavorite(ctx context.Context, userID string, productID int) error {
+	return nil
+}
+
+func (s *stubFavoriteRepository) SaveCustomization(ctx context.Context, customization entity.Customization) error {
+	customization.Id = len(s.customizations) + 1
+	s.customizations = append(s.customizations, customization)
+	return nil
+}
+
+func (s *stubFavoriteRepository) DeleteCustomization(ctx context.Context, id int, userID string) error {
+	return nil
+}
+
+func newFavoriteUseCase(repo *stubFavoriteRepository) FavoriteUseCase {
+	cart := NewCartUseCase(&stubCartRepository{}, newStubProductFinder(), newStubStoreFinder(), newStubTranslationFinder())
+	return NewFavoriteUseCase(repo, cart)
+}
+
+func TestAddFavorite(t *testing.T) {
+	tests := []struct {
+		name      string
+		productID int
+		wantErr   error
+	}{
+		{name: "existing product", productID: 1},
+		{name: "unavailable product", productID: 2},
+		{name: "unknown product", productID: 99, wantErr: sql.ErrNoRows},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &stubFavoriteRepository{}
+			u := newFavoriteUseCase(repo)
+
+			if err := u.AddFavorite(context.Background(), "user", tt.productID); err != tt.wantErr {
+				t.Fatalf("AddFavorite() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if saved := len(repo.favorites) == 1; saved != (tt.wantErr == nil) {
+				t.Errorf("AddFavorite() saved %v", repo.favorites)
+			}
+		})
+	}
+}
+
+func TestCreateCustomization(t *testing.T) {
+	variant := func(id int) *int { return &id }
+
+	tests := []struct {
+		name    string
+		req     entity.CustomizationRequest
+		wantErr error
+	}{
+		{name: "product only", req: entity.CustomizationRequest{Name: "Usual", ProductId: 1, Qty: 1}},
+		{name: "variant of the product", req: entity.CustomizationRequest{Name: "Large", ProductId: 1, VariantId: variant(1), Qty: 1}},
+		{name: "unknown product", req: entity.CustomizationRequest{Name: "Gone", ProductId: 99, Qty: 1}, wantErr: sql.ErrNoRows},
+		{name: "unknown variant", req: entity.CustomizationRequest{Name: "Gone", ProductId: 1, VariantId: variant(99), Qty: 1}, wantErr: sql.ErrNoRows},
+		{name: "variant of another product", req: entity.CustomizationRequest{Name: "Mixed", ProductId: 1, VariantId: variant(2), Qty: 1}, wantErr: sql.ErrNoRows},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &stubFavoriteRepository{}
+			u := newFavoriteUseCase(repo)
+
+			if err := u.CreateCustomization(context.Background(), "user", tt.req); err != tt.wantErr {
+				t.Fatalf("CreateCustomization() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if saved := len(repo.customizations) == 1; saved != (tt.wantErr == nil) {
+				t.Errorf("CreateCustomization() saved %+v", repo.customizations)
+			}
+		})
+	}
+}
+
+func TestAddCustomizationToCart(t *testing.T) {
+	large := 1
+	repo := &stubFavoriteRepository{customizations: []entity.Customization{
+		{Id: 1, UserId: "user", ProductId: 2, Qty: 1},
+		{Id: 2, UserId: "user", ProductId: 1, VariantId: &large, ToppingIds: []int64{2}, Qty: 1},
+		{Id: 3, UserId: "other", ProductId: 1, Qty: 1},
+	}}
+
+	tests := []struct {
+		name    string
+		id      int
+		wantErr error
+	}{
+		{name: "product no longer available", id: 1, wantErr: ErrProductUnavailable},
+		{name: "topping no longer available", id: 2, wantErr: ErrToppingUnavailable},
+		{name: "customization of another user", id: 3, wantErr: sql.ErrNoRows},
+		{name: "unknown customization", id: 9, wantErr: sql.ErrNoRows},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := newFavoriteUseCase(repo)
+
+			if err := u.AddCustomizationToCart(context.Background(), tt.id, "user"); err != tt.wantErr {
+				t.Errorf("AddCustomizationToCart() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/yosepalexsander/waysbucks-api/entity"
+	"github.com/yosepalexsander/waysbucks-api/repository"
+)
+
+const (
+	// IdempotencyPurgeInterval is how often expired idempotency keys are purged
+	IdempotencyPurgeInterval       = time.Hour
+	DefaultIdempotencyKeyRetention = 24 * time.Hour
+)
+
+var (
+	ErrIdempotencyKeyReused  = errors.New("idempotency key was already used for a different request")
+	ErrIdempotencyInProgress = errors.New("a request with this idempotency key is still in progress")
+)
+
+// IdempotencyUseCase remembers the first response to each idempotency key of
+// an owner for the retention period
+type IdempotencyUseCase struct {
+	repo      repository.IdempotencyRepository
+	retention time.Duration
+}
+
+func NewIdempotencyUseCase(repo repository.IdempotencyRepository, retention time.Duration) IdempotencyUseCase {
+	return IdempotencyUseCase{repo, retention}
+}
+
+// BeginRequest claims the key for the request. It returns the stored key when
+// the request is a retry of a completed one, whose response must be replayed
+// instead of handling the request again.
+func (u *IdempotencyUseCase) BeginRequest(ctx context.Context, key entity.IdempotencyKey) (*entity.IdempotencyKey, error) {
+	stored, created, err := u.repo.CreateIdempotencyKey(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if created {
+		return nil, nil
+	}
+
+	if stored.RequestHash != key.RequestHash {
+		return nil, ErrIdempotencyKeyReused
+	}
+
+	if !stored.Completed() {
+		return nil, ErrIdempotencyInProgress
+	}
+
+	return stored, nil
+}
+
+// CompleteRequest stores the response of the request that claimed the key
+func (u *IdempotencyUseCase) CompleteRequest(ctx context.Context, key entity.IdempotencyKey, now time.Time) error {
+	key.CompletedAt = &now
+	return u.repo.CompleteIdempotencyKey(ctx, key)
+}
+
+// AbortRequest frees the key of a request that ended without a response so
+// it can be retried
+func (u *IdempotencyUseCase) AbortRequest(ctx context.Context, key entity.IdempotencyKey) error {
+	return u.repo.DeleteIdempotencyKey(ctx, key.Owner, key.Key)
+}
+
+// PurgeKeys deletes the keys older than the retention period
+func (u *IdempotencyUseCase) PurgeKeys(ctx context.Context, now time.Time) (int64, error) {
+	return u.repo.PurgeIdempotencyKeys(ctx, now.Add(-u.retention))
+}
+
+// RunPurger purges expired keys right away and then on every interval until
+// ctx is done.
+func (u *IdempotencyUseCase) RunPurger(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := u.PurgeKeys(ctx, time.Now()); err != nil {
+			log.Printf("purge idempotency keys: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/yosepalexsander/waysbucks-api/entity"
+)
+
+type stubIdempotencyRepository struct {
+	keys   map[string]entity.IdempotencyKey
+	purged time.Time
+}
+
+func (s *stubIdempotencyRepository) CreateIdempotencyKey(ctx context.Context, key entity.IdempotencyKey) (*entity.IdempotencyKey, bool, error) {
+	if stored, ok := s.keys[key.Owner+key.Key]; ok {
+		return &stored, false, nil
+	}
+	s.keys[key.Owner+key.Key] = key
+	return &key, true, nil
+}
+
+func (s *stubIdempotencyRepository) CompleteIdempotencyKey(ctx context.Context, key entity.IdempotencyKey) error {
+	s.keys[key.Owner+key.Key] = key
+	return nil
+}
+
+func (s *stubIdempotencyRepository) DeleteIdempotencyKey(ctx context.Context, owner string, key string) error {
+	if !s.keys[owner+key].Completed() {
+		delete(s.keys, owner+key)
+	}
+	return nil
+}
+
+func (s *stubIdempotencyRepository) PurgeIdempotencyKeys(ctx context.Context, before time.Time) (int64, error) {
+	s.purged = before
+	return 0, nil
+}
+
+func TestIdempotencyBeginRequest(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2023, 5, 10, 12, 0, 0, 0, time.UTC)
+	repo := &stubIdempotencyRepository{keys: map[string]entity.IdempotencyKey{}}
+	u := NewIdempotencyUseCase(repo, 24*time.Hour)
+
+	key := entity.NewIdempotencyKey("user-1", "key-1", "POST", "/api/v1/transactions/", []byte(`{"name":"a"}`))
+	if stored, err := u.BeginRequest(ctx, key); stored != nil || err != nil {
+		t.Fatalf("BeginRequest() first = %v, %v, want nil, nil", stored, err)
+	}
+
+	if _, err := u.BeginRequest(ctx, key); err != ErrIdempotencyInProgress {
+		t.Errorf("BeginRequest() while in progress error = %v, want %v", err, ErrIdempotencyInProgress)
+	}
+
+	key.StatusCode = 201
+	key.ResponseBody = []byte(`{"id":"TX-1"}`)
+	if err := u.CompleteRequest(ctx, key, now); err != nil {
+		t.Fatalf("CompleteRequest() error = %v", err)
+	}
+
+	stored, err := u.BeginRequest(ctx, key)
+	if err != nil || stored == nil || stored.StatusCode != 201 || string(stored.ResponseBody) != `{"id":"TX-1"}` {
+		t.Errorf("BeginRequest() retry = %+v, %v, want the stored response", stored, err)
+	}
+
+	other := entity.NewIdempotencyKey("user-1", "key-1", "POST", "/api/v1/transactions/", []byte(`{"name":"b"}`))
+	if _, err := u.BeginRequest(ctx, other); err != ErrIdempotencyKeyReused {
+		t.Errorf("BeginRequest() other body error = %v, want %v", err, ErrIdempotencyKeyReused)
+	}
+
+	otherUser := entity.NewIdempotencyKey("user-2", "key-1", "POST", "/api/v1/transactions/", []byte(`{"name":"a"}`))
+	if stored, err := u.BeginRequest(ctx, otherUser); stored != nil || err != nil {
+		t.Errorf("BeginRequest() other user = %v, %v, want nil, nil", stored, err)
+	}
+
+	if err := u.AbortRequest(ctx, otherUser); err != nil {
+		t.Fatalf("AbortRequest() error = %v", err)
+	}
+	if stored, err := u.BeginRequest(ctx, otherUser); stored != nil || err != nil {
+		t.Errorf("BeginRequest() after abort = %v, %v, want nil, nil", stored, err)
+	}
+
+	if _, err := u.PurgeKeys(ctx, now); err != nil || !repo.purged.Equal(now.Add(-24*time.Hour)) {
+		t.Errorf("PurgeKeys() purged before %v, %v", repo.purged, err)
+	}
+}
@@ -0,0 +1,89 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/yosepalexsander/waysbucks-api/entity"
+	"github.com/yosepalexsander/waysbucks-api/repository"
+)
+
+var ErrAmountMismatch = errors.New("paid amount does not match the transaction total")
+
+// ParsePaymentNotification authenticates a notification of the payment
+// provider and reads it
+func (u *TransactionUseCase) ParsePaymentNotification(body []byte) (*entity.PaymentNotification, error) {
+	return u.provider.ParseNotification(body)
+}
+
+// HandlePaymentNotification stores an authenticated notification and applies
+// it. A repeated delivery of a notification already applied is ignored, and
+// one that failed before is applied again.
+func (u *TransactionUseCase) HandlePaymentNotification(ctx context.Context, n entity.PaymentNotification) error {
+	err := u.repo.ExecTx(ctx, func(tx repository.Transactioner) error {
+		return tx.SavePaymentNotification(ctx, &n)
+	})
+	if err != nil {
+		return err
+	}
+
+	if n.ProcessedAt != nil {
+		return nil
+	}
+
+	return u.processNotification(ctx, &n)
+}
+
+// ReplayPaymentNotification applies a stored notification of the transaction
+// again, whether it was processed or not
+func (u *TransactionUseCase) ReplayPaymentNotification(ctx context.Context, transactionID string, id int) (*entity.PaymentNotification, error) {
+	n, err := u.repo.FindPaymentNotification(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if n.TransactionId != transactionID {
+		return nil, sql.ErrNoRows
+	}
+
+	// a failure to apply it is recorded in the notification
+	if err := u.processNotification(ctx, n); err != nil && err.Error() != n.Error {
+		return nil, err
+	}
+
+	return n, nil
+}
+
+func (u *TransactionUseCase) FindPaymentNotifications(ctx context.Context, transactionID string) ([]entity.PaymentNotification, error) {
+	return u.repo.FindPaymentNotifications(ctx, transactionID)
+}
+
+// processNotification reconciles the notification and records the outcome.
+// A notification delivered out of order is processed without changing the
+// status, and a refund the gateway rejected stays failed for a retry, so
+// neither is applied again. Any other failure is kept for the next delivery.
+func (u *TransactionUseCase) processNotification(ctx context.Context, n *entity.PaymentNotification) error {
+	err := u.ReconcilePayment(ctx, n.PaymentUpdate)
+
+	n.Error = ""
+	if err != nil {
+		n.Error = err.Error()
+	}
+
+	n.ProcessedAt = nil
+	if err == nil || err == ErrInvalidTransition || errors.Is(err, ErrPaymentGateway) {
+		now := time.Now()
+		n.ProcessedAt = &now
+	}
+
+	markErr := u.repo.ExecTx(ctx, func(tx repository.Transactioner) error {
+		return tx.MarkPaymentNotification(ctx, n.Id, n.ProcessedAt, n.Error)
+	})
+	if markErr != nil {
+		return markErr
+	}
+
+	return err
+}
@@ -0,0 +1,149 @@
+package usecase
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/yosepalexsander/waysbucks-api/entity"
+	"github.com/yosepalexsander/waysbucks-api/thirdparty"
+)
+
+const (
+	// PaymentReconcileInterval is how often stale pending payments are checked
+	PaymentReconcileInterval = 5 * time.Minute
+	DefaultPaymentStaleAfter = 15 * time.Minute
+	DefaultPaymentDeadline   = 24 * time.Hour
+)
+
+// PaymentReconcileUseCase catches up on lost notifications. Transactions
+// pending for staleAfter are checked at the payment provider, and the ones
+// still unpaid after deadline are expired.
+type PaymentReconcileUseCase struct {
+	transactions TransactionUseCase
+	staleAfter   time.Duration
+	deadline     time.Duration
+}
+
+func NewPaymentReconcileUseCase(transactions TransactionUseCase, staleAfter time.Duration, deadline time.Duration) PaymentReconcileUseCase {
+	return PaymentReconcileUseCase{transactions, staleAfter, deadline}
+}
+
+// ReconcilePayments checks the transactions pending for olderThan, or for
+// the stale delay when olderThan is zero. Without apply it only reports what
+// would change.
+func (u *PaymentReconcileUseCase) ReconcilePayments(ctx context.Context, now time.Time, olderThan time.Duration, apply bool) (*entity.PaymentReconciliation, error) {
+	if olderThan <= 0 {
+		olderThan = u.staleAfter
+	}
+
+	since := now.Add(-olderThan)
+	pending, err := u.transactions.repo.FindPendingPayments(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+
+	checks := make([]entity.PaymentCheck, 0, len(pending))
+	for _, p := range pending {
+		checks = append(checks, u.check(ctx, p, now, apply))
+	}
+
+	report := entity.NewPaymentReconciliation(since, apply, checks)
+	return &report, nil
+}
+
+// check applies the provider status to the transaction the way a
+// notification would, and expires it when no payment came before the
+// deadline
+func (u *PaymentReconcileUseCase) check(ctx context.Context, p entity.PendingPayment, now time.Time, apply bool) entity.PaymentCheck {
+	check := entity.PaymentCheck{PendingPayment: p, ProviderStatus: entity.ProviderStatusPending}
+
+	update, err := u.transactions.provider.GetStatus(ctx, p.TransactionId)
+	switch {
+	case err == thirdparty.ErrPaymentNotFound:
+		check.ProviderStatus = entity.ProviderStatusNotFound
+	case err != nil:
+		check.Mismatch = true
+		check.Error = err.Error()
+		return check
+	case update.Status != "":
+		check.ProviderStatus = update.Status
+		check.Action = update.Status
+
+		if update.GrossAmount != 0 && update.GrossAmount != p.Total {
+			return mismatch(check, ErrAmountMismatch)
+		}
+		if !entity.CanTransition(entity.StatusPendingPayment, update.Status) {
+			return mismatch(check, ErrInvalidTransition)
+		}
+
+		if apply {
+			update.Note = "reconciled: " + update.Note
+			if err := u.transactions.ReconcilePayment(ctx, *update); err != nil {
+				return mismatch(check, err)
+			}
+		}
+		return check
+	}
+
+	if now.Sub(p.CreatedAt) < u.deadline {
+		return check
+	}
+
+	check.Action = entity.PaymentActionExpire
+	if apply {
+		if err := u.expire(ctx, p.TransactionId); err != nil {
+			return mismatch(check, err)
+		}
+	}
+
+	return check
+}
+
+// expire fails the transaction and cancels its payment so it cannot be paid
+// anymore. A payment that settles anyway is refunded by ReconcilePayment.
+func (u *PaymentReconcileUseCase) expire(ctx context.Context, id string) error {
+	transaction, err := u.transactions.repo.FindTransactionByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	actor := entity.TransactionActor{Kind: entity.ActorSystem}
+	if err := u.transactions.transition(ctx, transaction, entity.StatusFailed, actor, "payment deadline passed"); err != nil {
+		return err
+	}
+
+	if err := u.transactions.provider.CancelPayment(ctx, id); err != nil {
+		log.Printf("cancel expired payment of %s: %v", id, err)
+	}
+
+	return nil
+}
+
+// RunReconciler reconciles stale pending payments right away and then on
+// every interval until ctx is done.
+func (u *PaymentReconcileUseCase) RunReconciler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		report, err := u.ReconcilePayments(ctx, time.Now(), 0, true)
+		if err != nil {
+			log.Printf("reconcile payments: %v", err)
+		} else if report.Mismatches > 0 {
+			log.Printf("reconcile payments: %d of %d pending payments do not match the provider", report.Mismatches, report.Checked)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func mismatch(check entity.PaymentCheck, err error) entity.PaymentCheck {
+	check.Mismatch = true
+	check.Error = err.Error()
+	return check
+}
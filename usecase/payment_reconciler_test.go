@@ -0,0 +1,80 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/yosepalexsander/waysbucks-api/entity"
+	"github.com/yosepalexsander/waysbucks-api/thirdparty"
+)
+
+func TestReconcilePayments(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC)
+
+	repo := &stubTransactionRepository{
+		transactions: map[string]*entity.Transaction{
+			"ORDER-1": newPaidTransaction("ORDER-1", entity.StatusPendingPayment), // settled, webhook lost
+			"ORDER-2": newPaidTransaction("ORDER-2", entity.StatusPendingPayment), // still pending
+			"ORDER-3": newPaidTransaction("ORDER-3", entity.StatusPendingPayment), // never charged, past deadline
+			"ORDER-4": newPaidTransaction("ORDER-4", entity.StatusPendingPayment), // refunded at the provider
+			"ORDER-5": newPaidTransaction("ORDER-5", entity.StatusPendingPayment), // too recent
+		},
+		createdAt: map[string]time.Time{
+			"ORDER-1": now.Add(-time.Hour),
+			"ORDER-2": now.Add(-time.Hour),
+			"ORDER-3": now.Add(-48 * time.Hour),
+			"ORDER-4": now.Add(-time.Hour),
+			"ORDER-5": now.Add(-time.Minute),
+		},
+	}
+	provider := thirdparty.NewFakePaymentProvider()
+	for _, id := range []string{"ORDER-1", "ORDER-2", "ORDER-4"} {
+		provider.CreateCharge(ctx, repo.transactions[id])
+	}
+	provider.SetStatus("ORDER-1", "settlement")
+	provider.SetStatus("ORDER-4", "refund")
+
+	u := NewPaymentReconcileUseCase(NewTransactionUseCase(repo, newStubProductFinder(), newStubStoreFinder(), newStubAddressFinder(), newStubTranslationFinder(), provider, testDeliveryRules), 15*time.Minute, 24*time.Hour)
+
+	dryRun, err := u.ReconcilePayments(ctx, now, 0, false)
+	if err != nil {
+		t.Fatalf("dry run: error = %v", err)
+	}
+	if dryRun.Checked != 4 || dryRun.Updated != 1 || dryRun.Expired != 1 || dryRun.Mismatches != 1 {
+		t.Errorf("dry run = %+v", dryRun)
+	}
+	if repo.transactions["ORDER-1"].Status != entity.StatusPendingPayment {
+		t.Errorf("dry run changed ORDER-1 to %s", repo.transactions["ORDER-1"].Status)
+	}
+
+	report, err := u.ReconcilePayments(ctx, now, 0, true)
+	if err != nil {
+		t.Fatalf("reconcile: error = %v", err)
+	}
+
+	want := map[string]string{
+		"ORDER-1": entity.StatusPaid,
+		"ORDER-2": entity.StatusPendingPayment,
+		"ORDER-3": entity.StatusFailed,
+		"ORDER-4": entity.StatusPendingPayment,
+		"ORDER-5": entity.StatusPendingPayment,
+	}
+	for id, status := range want {
+		if got := repo.transactions[id].Status; got != status {
+			t.Errorf("%s status = %s, want %s", id, got, status)
+		}
+	}
+
+	mismatch := report.Checks[3]
+	if mismatch.TransactionId != "ORDER-4" || !mismatch.Mismatch || mismatch.ProviderStatus != entity.StatusRefunded {
+		t.Errorf("mismatch = %+v", mismatch)
+	}
+
+	// only ORDER-2 and ORDER-4 are left pending
+	report, _ = u.ReconcilePayments(ctx, now, 0, true)
+	if report.Checked != 2 || report.Updated != 0 || report.Mismatches != 1 {
+		t.Errorf("second run = %+v", report)
+	}
+}
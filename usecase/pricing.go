@@ -0,0 +1,241 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/yosepalexsander/waysbucks-api/entity"
+	"github.com/yosepalexsander/waysbucks-api/repository"
+)
+
+var (
+	ErrProductUnavailable = errors.New("product is not available")
+	ErrVariantUnavailable = errors.New("product variant is not available")
+	ErrToppingUnavailable = errors.New("topping is not available")
+	ErrPriceMismatch      = errors.New("price does not match the current price")
+	ErrTotalMismatch      = errors.New("total or fees do not match the server total")
+	ErrInvalidQty         = errors.New("quantity must be at least 1")
+)
+
+// serviceFee is charged on every transaction
+const serviceFee = 5000
+
+// isUnavailable reports whether err means an item of the line can no longer
+// be ordered
+func isUnavailable(err error) bool {
+	switch err {
+	case ErrProductUnavailable, ErrVariantUnavailable, ErrToppingUnavailable, ErrStoreUnavailable:
+		return true
+	}
+
+	return false
+}
+
+// pricer computes line prices from the current catalog so the price sent by a
+// client is never trusted
+type pricer struct {
+	products repository.ProductFinder
+	stores   repository.StoreFinder
+}
+
+// priceLine returns the price breakdown of a line from the current product,
+// variant and topping prices of the store, or of the catalog when storeID is
+// nil. It fails when any of them is unavailable.
+func (p pricer) priceLine(ctx context.Context, storeID *int, productID int, variantID *int, toppingIDs []int64, qty int) (*entity.PriceBreakdown, error) {
+	if qty < 1 {
+		return nil, ErrInvalidQty
+	}
+
+	product, err := p.products.FindProduct(ctx, productID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrProductUnavailable
+		}
+		return nil, err
+	}
+
+	toppings, err := p.products.FindToppingsByIds(ctx, toppingIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	if storeID != nil {
+		if err := p.applyStore(ctx, *storeID, product, toppings); err != nil {
+			return nil, err
+		}
+	}
+
+	if !product.IsAvailable {
+		return nil, ErrProductUnavailable
+	}
+
+	variantPrice := 0
+	if variantID != nil {
+		variant, err := p.products.FindVariant(ctx, *variantID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return nil, ErrVariantUnavailable
+			}
+			return nil, err
+		}
+
+		if variant.ProductId != productID || !variant.IsAvailable {
+			return nil, ErrVariantUnavailable
+		}
+
+		variantPrice = variant.Price
+	}
+
+	if len(toppings) != len(toppingIDs) {
+		return nil, ErrToppingUnavailable
+	}
+
+	toppingPrices := make([]entity.ToppingPrice, 0, len(toppings))
+	for _, topping := range toppings {
+		if !topping.IsAvailable {
+			return nil, ErrToppingUnavailable
+		}
+
+		toppingPrices = append(toppingPrices, entity.ToppingPrice{Id: topping.Id, Name: topping.Name, Price: topping.Price})
+	}
+
+	breakdown := entity.NewPriceBreakdown(product.Price, variantPrice, toppingPrices, qty)
+	return &breakdown, nil
+}
+
+// checkPrice compares the price sent by a client with the server price. A
+// zero client price means the client left the price to the server.
+func checkPrice(clientPrice int, breakdown *entity.PriceBreakdown) error {
+	if clientPrice != 0 && clientPrice != breakdown.Total {
+		return ErrPriceMismatch
+	}
+
+	return nil
+}
+
+// checkLine compares a line with the current catalog. It returns the issue
+// found, if any, and the breakdown of the line once the issue is fixed, which
+// is nil when the line cannot be ordered anymore.
+func (p pricer) checkLine(ctx context.Context, storeID *int, productID int, variantID *int, toppingIDs []int64, qty int, price int) (*entity.CartIssue, *entity.PriceBreakdown, error) {
+	breakdown, err := p.priceLine(ctx, storeID, productID, variantID, toppingIDs, qty)
+	if err == nil {
+		if checkPrice(price, breakdown) != nil {
+			return &entity.CartIssue{
+				ProductId: productID,
+				Kind:      entity.CartIssuePriceChanged,
+				Message:   ErrPriceMismatch.Error(),
+				OldPrice:  price,
+				NewPrice:  breakdown.Total,
+			}, breakdown, nil
+		}
+		return nil, breakdown, nil
+	}
+
+	if err == ErrToppingUnavailable {
+		kept, removed, err := p.splitToppings(ctx, storeID, productID, variantID, toppingIDs, qty)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		breakdown, err := p.priceLine(ctx, storeID, productID, variantID, kept, qty)
+		if err == nil {
+			return &entity.CartIssue{
+				ProductId:  productID,
+				Kind:       entity.CartIssueToppingRemoved,
+				Message:    ErrToppingUnavailable.Error(),
+				OldPrice:   price,
+				NewPrice:   breakdown.Total,
+				ToppingIds: removed,
+			}, breakdown, nil
+		}
+	}
+
+	if isUnavailable(err) {
+		return &entity.CartIssue{
+			ProductId: productID,
+			Kind:      entity.CartIssueUnavailable,
+			Message:   err.Error(),
+			OldPrice:  price,
+		}, nil, nil
+	}
+
+	return nil, nil, err
+}
+
+// splitToppings separates the toppings that can still be ordered with the
+// product from the ones that cannot
+func (p pricer) splitToppings(ctx context.Context, storeID *int, productID int, variantID *int, toppingIDs []int64, qty int) (kept []int64, removed []int64, err error) {
+	kept = []int64{}
+	for _, id := range toppingIDs {
+		_, err := p.priceLine(ctx, storeID, productID, variantID, []int64{id}, qty)
+		switch err {
+		case nil:
+			kept = append(kept, id)
+		case ErrToppingUnavailable:
+			removed = append(removed, id)
+		default:
+			return nil, nil, err
+		}
+	}
+
+	return kept, removed, nil
+}
+
+// transactionFees returns the fees charged on top of the ordered lines
+func transactionFees(deliveryFee int) []entity.Fee {
+	return []entity.Fee{
+		{Code: entity.FeeService, Name: "Service Fee", Amount: serviceFee},
+		{Code: entity.FeeDelivery, Name: "Delivery Fee", Amount: deliveryFee},
+	}
+}
+
+// checkTotals compares the totals sent by a client with the server breakdown.
+// Zero values mean the client left them to the server.
+func checkTotals(request entity.TransactionRequest, breakdown entity.OrderBreakdown) error {
+	if request.ServiceFee != 0 && request.ServiceFee != breakdown.Fee(entity.FeeService) {
+		return ErrTotalMismatch
+	}
+
+	if request.DeliveryFee != 0 && request.DeliveryFee != breakdown.Fee(entity.FeeDelivery) {
+		return ErrTotalMismatch
+	}
+
+	if request.Total != 0 && request.Total != breakdown.Total {
+		return ErrTotalMismatch
+	}
+
+	return nil
+}
+
+// applyStore replaces the catalog prices and availability with the ones of the store
+func (p pricer) applyStore(ctx context.Context, storeID int, product *entity.Product, toppings []entity.ProductTopping) error {
+	store, err := p.stores.FindStore(ctx, storeID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrStoreUnavailable
+		}
+		return err
+	}
+
+	if !store.IsActive {
+		return ErrStoreUnavailable
+	}
+
+	productItems, err := p.stores.FindStoreProducts(ctx, storeID)
+	if err != nil {
+		return err
+	}
+
+	toppingItems, err := p.stores.FindStoreToppings(ctx, storeID)
+	if err != nil {
+		return err
+	}
+
+	products := []entity.Product{*product}
+	entity.ApplyStoreProducts(products, productItems)
+	*product = products[0]
+	entity.ApplyStoreToppings(toppings, toppingItems)
+
+	return nil
+}
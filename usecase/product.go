@@ -2,6 +2,9 @@ package usecase
 
 import (
 	"context"
+	"errors"
+	"net/url"
+	"time"
 
 	"github.com/yosepalexsander/waysbucks-api/entity"
 	"github.com/yosepalexsander/waysbucks-api/helper"
@@ -10,46 +13,91 @@ import (
 	"golang.org/x/sync/errgroup"
 )
 
+var ErrUnsupportedLocale = errors.New("locale is not supported")
+
 type ProductUseCase struct {
-	repo repository.ProductRepository
+	repo         repository.ProductRepository
+	translations repository.TranslationRepository
+	cache        *catalogCache
 }
 
-func NewProductUseCase(repo repository.ProductRepository) ProductUseCase {
-	return ProductUseCase{repo}
+func NewProductUseCase(repo repository.ProductRepository, translations repository.TranslationRepository) ProductUseCase {
+	return ProductUseCase{repo, translations, newCatalogCache()}
 }
 
-func (u *ProductUseCase) FindProducts(ctx context.Context, params map[string][]string) ([]entity.Product, error) {
+// FindProducts returns the products matching params and when any of them was
+// last modified. The returned slice is shared with the cache and must not be
+// modified.
+func (u *ProductUseCase) FindProducts(ctx context.Context, params map[string][]string, locale string) ([]entity.Product, time.Time, error) {
+	delete(params, "lang")
+	key := locale + "?" + url.Values(params).Encode()
+
+	entry, generation, ok := u.cache.get("products", key)
+	if ok {
+		return entry.value.([]entity.Product), entry.lastModified, nil
+	}
+
 	whereClauses, orderClauses := helper.QueryParamsToSqlClauses(params)
 	products, err := u.repo.FindProducts(ctx, whereClauses, orderClauses)
 	if err != nil {
-		return nil, err
+		return nil, time.Time{}, err
+	}
+
+	if err := u.translator().translateProducts(ctx, products, locale); err != nil {
+		return nil, time.Time{}, err
 	}
 
-	return products, nil
+	var lastModified time.Time
+	for _, p := range products {
+		if p.Updated_At.After(lastModified) {
+			lastModified = p.Updated_At
+		}
+	}
+
+	entry = u.cache.set("products", key, generation, products, lastModified)
+	return products, entry.lastModified, nil
 }
 
-func (u *ProductUseCase) GetProduct(ctx context.Context, productID int) (*entity.Product, error) {
+func (u *ProductUseCase) GetProduct(ctx context.Context, productID int, locale string) (*entity.Product, error) {
 	product, err := u.repo.FindProduct(ctx, productID)
 
 	if err != nil {
 		return nil, err
 	}
 
-	return product, nil
+	if product.Images, err = u.repo.FindProductImages(ctx, productID); err != nil {
+		return nil, err
+	}
+
+	products := []entity.Product{*product}
+	if err := u.translator().translateProducts(ctx, products, locale); err != nil {
+		return nil, err
+	}
+
+	return &products[0], nil
 }
 
 func (u *ProductUseCase) CreateProduct(ctx context.Context, productReq entity.ProductRequest) error {
+	defer u.cache.invalidate()
+
 	product := entity.NewProduct(productReq)
 
 	return u.repo.SaveProduct(ctx, product)
 }
 
 func (u *ProductUseCase) UpdateProduct(ctx context.Context, id int, newData map[string]interface{}) error {
+	defer u.cache.invalidate()
+
 	product, err := u.repo.FindProduct(ctx, id)
 	if err != nil {
 		return err
 	}
 
+	images, err := u.repo.FindProductImages(ctx, id)
+	if err != nil {
+		return err
+	}
+
 	g, ctx := errgroup.WithContext(ctx)
 
 	g.Go(func() error {
@@ -57,7 +105,8 @@ func (u *ProductUseCase) UpdateProduct(ctx context.Context, id int, newData map[
 	})
 
 	g.Go(func() error {
-		if newImage, ok := newData["image"]; ok && newImage != product.Image {
+		// the old image stays when it is still part of the product gallery
+		if newImage, ok := newData["image"]; ok && newImage != product.Image && !inGallery(images, product.Image) {
 			return thirdparty.RemoveFile(ctx, product.Image)
 		}
 		return nil
@@ -71,11 +120,18 @@ func (u *ProductUseCase) UpdateProduct(ctx context.Context, id int, newData map[
 }
 
 func (u *ProductUseCase) DeleteProduct(ctx context.Context, id int) error {
+	defer u.cache.invalidate()
+
 	product, err := u.repo.FindProduct(ctx, id)
 	if err != nil {
 		return err
 	}
 
+	images, err := u.repo.FindProductImages(ctx, id)
+	if err != nil {
+		return err
+	}
+
 	g, ctx := errgroup.WithContext(ctx)
 
 	g.Go(func() error {
@@ -86,6 +142,17 @@ func (u *ProductUseCase) DeleteProduct(ctx context.Context, id int) error {
 		return thirdparty.RemoveFile(ctx, product.Image)
 	})
 
+	for _, image := range images {
+		if image.Image == product.Image {
+			continue
+		}
+
+		publicID := image.Image
+		g.Go(func() error {
+			return thirdparty.RemoveFile(ctx, publicID)
+		})
+	}
+
 	if err := g.Wait(); err != nil {
 		return err
 	}
@@ -93,13 +160,32 @@ func (u *ProductUseCase) DeleteProduct(ctx context.Context, id int) error {
 	return nil
 }
 
-func (u *ProductUseCase) FindToppings(ctx context.Context) ([]entity.ProductTopping, error) {
+// FindToppings returns every topping and when any of them was last modified.
+// The returned slice is shared with the cache and must not be modified.
+func (u *ProductUseCase) FindToppings(ctx context.Context, locale string) ([]entity.ProductTopping, time.Time, error) {
+	entry, generation, ok := u.cache.get("toppings", locale)
+	if ok {
+		return entry.value.([]entity.ProductTopping), entry.lastModified, nil
+	}
+
 	toppings, err := u.repo.FindToppings(ctx)
 	if err != nil {
-		return nil, err
+		return nil, time.Time{}, err
+	}
+
+	if err := u.translator().translateToppings(ctx, toppings, locale); err != nil {
+		return nil, time.Time{}, err
+	}
+
+	var lastModified time.Time
+	for _, t := range toppings {
+		if t.Updated_At.After(lastModified) {
+			lastModified = t.Updated_At
+		}
 	}
 
-	return toppings, nil
+	entry = u.cache.set("toppings", locale, generation, toppings, lastModified)
+	return toppings, entry.lastModified, nil
 }
 
 func (u *ProductUseCase) GetTopping(ctx context.Context, id int) (*entity.ProductTopping, error) {
@@ -107,6 +193,8 @@ func (u *ProductUseCase) GetTopping(ctx context.Context, id int) (*entity.Produc
 }
 
 func (u *ProductUseCase) CreateTopping(ctx context.Context, toppingReq entity.ProductToppingRequest) error {
+	defer u.cache.invalidate()
+
 	topping := entity.NewProductTopping(toppingReq)
 
 	if err := u.repo.SaveTopping(ctx, topping); err != nil {
@@ -118,6 +206,8 @@ func (u *ProductUseCase) CreateTopping(ctx context.Context, toppingReq entity.Pr
 }
 
 func (u *ProductUseCase) UpdateTopping(ctx context.Context, id int, newData map[string]interface{}) error {
+	defer u.cache.invalidate()
+
 	topping, err := u.repo.FindTopping(ctx, id)
 	if err != nil {
 		return err
@@ -144,6 +234,8 @@ func (u *ProductUseCase) UpdateTopping(ctx context.Context, id int, newData map[
 }
 
 func (u *ProductUseCase) DeleteTopping(ctx context.Context, id int) error {
+	defer u.cache.invalidate()
+
 	topping, err := u.repo.FindTopping(ctx, id)
 	if err != nil {
 		return err
@@ -165,3 +257,19 @@ func (u *ProductUseCase) DeleteTopping(ctx context.Context, id int) error {
 
 	return nil
 }
+
+func (u *ProductUseCase) FindProductVariants(ctx context.Context, productID int) ([]entity.ProductVariant, error) {
+	return u.repo.FindProductVariants(ctx, productID)
+}
+
+func (u *ProductUseCase) CreateProductVariant(ctx context.Context, productID int, req entity.ProductVariantRequest) error {
+	if _, err := u.repo.FindProduct(ctx, productID); err != nil {
+		return err
+	}
+
+	return u.repo.SaveVariant(ctx, entity.NewProductVariant(productID, req))
+}
+
+func (u *ProductUseCase) DeleteProductVariant(ctx context.Context, id int, productID int) error {
+	return u.repo.DeleteVariant(ctx, id, productID)
+}
@@ -0,0 +1,142 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/yosepalexsander/waysbucks-api/entity"
+	"github.com/yosepalexsander/waysbucks-api/thirdparty"
+)
+
+var (
+	ErrInvalidImageOrder = errors.New("image order must contain every image of the product once")
+	ErrLastProductImage  = errors.New("the only image of a product cannot be removed")
+)
+
+func (u *ProductUseCase) FindProductImages(ctx context.Context, productID int) ([]entity.ProductImage, error) {
+	return u.repo.FindProductImages(ctx, productID)
+}
+
+// AddProductImage attaches an uploaded image to the product gallery. A product
+// without a gallery keeps its current image as the primary one.
+func (u *ProductUseCase) AddProductImage(ctx context.Context, productID int, req entity.ProductImageRequest) error {
+	defer u.cache.invalidate()
+
+	product, err := u.repo.FindProduct(ctx, productID)
+	if err != nil {
+		return err
+	}
+
+	images, err := u.repo.FindProductImages(ctx, productID)
+	if err != nil {
+		return err
+	}
+
+	if len(images) == 0 && product.Image != "" && product.Image != req.Image {
+		current := entity.NewProductImage(productID, entity.ProductImageRequest{Image: product.Image})
+		id, err := u.repo.SaveProductImage(ctx, current)
+		if err != nil {
+			return err
+		}
+
+		if err := u.repo.SetPrimaryProductImage(ctx, productID, id); err != nil {
+			return err
+		}
+
+		images = append(images, current)
+	}
+
+	id, err := u.repo.SaveProductImage(ctx, entity.NewProductImage(productID, req))
+	if err != nil {
+		return err
+	}
+
+	if req.IsPrimary || len(images) == 0 {
+		return u.repo.SetPrimaryProductImage(ctx, productID, id)
+	}
+
+	return nil
+}
+
+func (u *ProductUseCase) SetPrimaryProductImage(ctx context.Context, productID int, imageID int) error {
+	defer u.cache.invalidate()
+
+	return u.repo.SetPrimaryProductImage(ctx, productID, imageID)
+}
+
+func (u *ProductUseCase) ReorderProductImages(ctx context.Context, productID int, imageIDs []int) error {
+	images, err := u.repo.FindProductImages(ctx, productID)
+	if err != nil {
+		return err
+	}
+
+	if len(images) != len(imageIDs) {
+		return ErrInvalidImageOrder
+	}
+
+	known := make(map[int]bool, len(images))
+	for _, image := range images {
+		known[image.Id] = true
+	}
+
+	for _, id := range imageIDs {
+		if !known[id] {
+			return ErrInvalidImageOrder
+		}
+		delete(known, id)
+	}
+
+	return u.repo.ReorderProductImages(ctx, productID, imageIDs)
+}
+
+// RemoveProductImage removes the image from the gallery and from object
+// storage. When the primary image is removed the next image is promoted.
+func (u *ProductUseCase) RemoveProductImage(ctx context.Context, productID int, imageID int) error {
+	defer u.cache.invalidate()
+
+	images, err := u.repo.FindProductImages(ctx, productID)
+	if err != nil {
+		return err
+	}
+
+	var removed *entity.ProductImage
+	var next *entity.ProductImage
+	for i := range images {
+		if images[i].Id == imageID {
+			removed = &images[i]
+		} else if next == nil {
+			next = &images[i]
+		}
+	}
+
+	if removed == nil {
+		return sql.ErrNoRows
+	}
+
+	if removed.IsPrimary && next == nil {
+		return ErrLastProductImage
+	}
+
+	if removed.IsPrimary {
+		if err := u.repo.SetPrimaryProductImage(ctx, productID, next.Id); err != nil {
+			return err
+		}
+	}
+
+	if err := u.repo.DeleteProductImage(ctx, imageID, productID); err != nil {
+		return err
+	}
+
+	return thirdparty.RemoveFile(ctx, removed.Image)
+}
+
+func inGallery(images []entity.ProductImage, publicID string) bool {
+	for _, image := range images {
+		if image.Image == publicID {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,236 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"testing"
+
+	"github.com/yosepalexsander/waysbucks-api/config"
+	"github.com/yosepalexsander/waysbucks-api/entity"
+	"github.com/yosepalexsander/waysbucks-api/repository"
+	"github.com/yosepalexsander/waysbucks-api/thirdparty"
+)
+
+// stubProductRepository keeps the gallery of product 1 in memory
+type stubProductRepository struct {
+	*stubProductFinder
+	images  []entity.ProductImage
+	order   []int
+	deleted []int
+}
+
+func (s *stubProductRepository) FindProductImages(ctx context.Context, productID int) ([]entity.ProductImage, error) {
+	images := []entity.ProductImage{}
+	for _, image := range s.images {
+		if image.ProductId == productID {
+			images = append(images, image)
+		}
+	}
+	return images, nil
+}
+
+func (s *stubProductRepository) SaveProduct(ctx context.Context, product entity.Product) error {
+	return nil
+}
+
+func (s *stubProductRepository) UpdateProduct(ctx context.Context, id int, newProduct map[string]interface{}) error {
+	return nil
+}
+
+func (s *stubProductRepository) SaveTopping(ctx context.Context, topping entity.ProductTopping) error {
+	return nil
+}
+
+func (s *stubProductRepository) UpdateTopping(ctx context.Context, id int, newData map[string]interface{}) error {
+	return nil
+}
+
+func (s *stubProductRepository) SaveVariant(ctx context.Context, variant entity.ProductVariant) error {
+	return nil
+}
+
+func (s *stubProductRepository) SaveProductImage(ctx context.Context, image entity.ProductImage) (int, error) {
+	image.Id = len(s.images) + 1
+	s.images = append(s.images, image)
+	return image.Id, nil
+}
+
+func (s *stubProductRepository) SetPrimaryProductImage(ctx context.Context, productID int, imageID int) error {
+	found := false
+	for _, image := range s.images {
+		if image.Id == imageID && image.ProductId == productID {
+			found = true
+		}
+	}
+	if !found {
+		return sql.ErrNoRows
+	}
+
+	for i := range s.images {
+		if s.images[i].ProductId == productID {
+			s.images[i].IsPrimary = s.images[i].Id == imageID
+		}
+	}
+	return nil
+}
+
+func (s *stubProductRepository) ReorderProductImages(ctx context.Context, productID int, imageIDs []int) error {
+	s.order = imageIDs
+	return nil
+}
+
+func (s *stubProductRepository) DeleteProduct(ctx context.Context, id int) error {
+	return nil
+}
+
+func (s *stubProductRepository) DeleteTopping(ctx context.Context, id int) error {
+	return nil
+}
+
+func (s *stubProductRepository) DeleteVariant(ctx context.Context, id int, productID int) error {
+	return nil
+}
+
+func (s *stubProductRepository) DeleteProductImage(ctx context.Context, id int, productID int) error {
+	for i, image := range s.images {
+		if image.Id == id && image.ProductId == productID {
+			s.images = append(s.images[:i], s.images[i+1:]...)
+			s.deleted = append(s.deleted, id)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (s *stubProductRepository) ExecTx(ctx context.Context, fn func(repository.ProductTransactioner) error) error {
+	return nil
+}
+
+func newStubProductRepository() *stubProductRepository {
+	return &stubProductRepository{
+		stubProductFinder: newStubProductFinder(),
+		images: []entity.ProductImage{
+			{Id: 1, ProductId: 1, Image: "latte-front", SortOrder: 0, IsPrimary: true},
+			{Id: 2, ProductId: 1, Image: "latte-side", SortOrder: 1},
+			{Id: 3, ProductId: 1, Image: "latte-top", SortOrder: 2},
+			{Id: 4, ProductId: 2, Image: "mocha", SortOrder: 0, IsPrimary: true},
+		},
+	}
+}
+
+func primaryImages(images []entity.ProductImage, productID int) []int {
+	ids := []int{}
+	for _, image := range images {
+		if image.ProductId == productID && image.IsPrimary {
+			ids = append(ids, image.Id)
+		}
+	}
+	return ids
+}
+
+func TestSetPrimaryProductImage(t *testing.T) {
+	tests := []struct {
+		name    string
+		imageID int
+		want    []int
+		wantErr error
+	}{
+		{name: "another image of the product", imageID: 3, want: []int{3}},
+		{name: "current primary image", imageID: 1, want: []int{1}},
+		{name: "image of another product", imageID: 4, want: []int{1}, wantErr: sql.ErrNoRows},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newStubProductRepository()
+			u := NewProductUseCase(repo, nil)
+			generation := u.cache.generation
+
+			if err := u.SetPrimaryProductImage(context.Background(), 1, tt.imageID); err != tt.wantErr {
+				t.Fatalf("SetPrimaryProductImage() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if u.cache.generation == generation {
+				t.Error("SetPrimaryProductImage() did not invalidate the catalog cache")
+			}
+
+			if got := primaryImages(repo.images, 1); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("primary images = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReorderProductImages(t *testing.T) {
+	tests := []struct {
+		name     string
+		imageIDs []int
+		wantErr  error
+	}{
+		{name: "every image once", imageIDs: []int{3, 1, 2}},
+		{name: "missing image", imageIDs: []int{3, 1}, wantErr: ErrInvalidImageOrder},
+		{name: "duplicate image", imageIDs: []int{3, 3, 1}, wantErr: ErrInvalidImageOrder},
+		{name: "image of another product", imageIDs: []int{3, 1, 4}, wantErr: ErrInvalidImageOrder},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newStubProductRepository()
+			u := NewProductUseCase(repo, nil)
+
+			if err := u.ReorderProductImages(context.Background(), 1, tt.imageIDs); err != tt.wantErr {
+				t.Fatalf("ReorderProductImages() error = %v, want %v", err, tt.wantErr)
+			}
+
+			want := tt.imageIDs
+			if tt.wantErr != nil {
+				want = nil
+			}
+
+			if !reflect.DeepEqual(repo.order, want) {
+				t.Errorf("saved order = %v, want %v", repo.order, want)
+			}
+		})
+	}
+}
+
+func TestRemoveProductImage(t *testing.T) {
+	// without a cloudinary url the file removal fails after the gallery changes
+	cloudinaryURL := config.CLOUDINARY_URL
+	config.CLOUDINARY_URL = ""
+	t.Cleanup(func() { config.CLOUDINARY_URL = cloudinaryURL })
+
+	tests := []struct {
+		name        string
+		productID   int
+		imageID     int
+		wantErr     error
+		wantDeleted []int
+		wantPrimary []int
+	}{
+		{name: "primary image promotes the next image", productID: 1, imageID: 1, wantErr: thirdparty.ErrServiceUnavailable, wantDeleted: []int{1}, wantPrimary: []int{2}},
+		{name: "secondary image keeps the primary", productID: 1, imageID: 3, wantErr: thirdparty.ErrServiceUnavailable, wantDeleted: []int{3}, wantPrimary: []int{1}},
+		{name: "last primary image", productID: 2, imageID: 4, wantErr: ErrLastProductImage, wantPrimary: []int{4}},
+		{name: "image of another product", productID: 1, imageID: 4, wantErr: sql.ErrNoRows, wantPrimary: []int{1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newStubProductRepository()
+			u := NewProductUseCase(repo, nil)
+
+			if err := u.RemoveProductImage(context.Background(), tt.productID, tt.imageID); err != tt.wantErr {
+				t.Fatalf("RemoveProductImage() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(repo.deleted, tt.wantDeleted) {
+				t.Errorf("deleted images = %v, want %v", repo.deleted, tt.wantDeleted)
+			}
+
+			if got := primaryImages(repo.images, tt.productID); !reflect.DeepEqual(got, tt.wantPrimary) {
+				t.Errorf("primary images = %v, want %v", got, tt.wantPrimary)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package usecase
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/yosepalexsander/waysbucks-api/entity"
+	"github.com/yosepalexsander/waysbucks-api/repository"
+)
+
+const (
+	// RecommendationRefreshInterval is how often affinities are recomputed
+	RecommendationRefreshInterval = time.Hour
+	// minAffinitySupport drops pairs bought together fewer times than this
+	minAffinitySupport  = 2
+	recommendationLimit = 5
+)
+
+type RecommendationUseCase struct {
+	repo  repository.RecommendationRepository
+	carts repository.CartRepository
+}
+
+func NewRecommendationUseCase(repo repository.RecommendationRepository, carts repository.CartRepository) RecommendationUseCase {
+	return RecommendationUseCase{repo, carts}
+}
+
+func (u *RecommendationUseCase) FindProductRecommendations(ctx context.Context, productID int) (*entity.Recommendations, error) {
+	return u.recommend(ctx, []int64{int64(productID)})
+}
+
+// FindCartRecommendations suggests products and toppings bought together with
+// the products in the cart. Products already in the cart are left out.
+func (u *RecommendationUseCase) FindCartRecommendations(ctx context.Context, owner entity.CartOwner) (*entity.Recommendations, error) {
+	carts, err := u.carts.FindCarts(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.recommend(ctx, cartProductIDs(carts))
+}
+
+func (u *RecommendationUseCase) RefreshAffinities(ctx context.Context) error {
+	return u.repo.RefreshAffinities(ctx, minAffinitySupport)
+}
+
+// RunRefresher recomputes affinities right away and then on every interval
+// until ctx is done.
+func (u *RecommendationUseCase) RunRefresher(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := u.RefreshAffinities(ctx); err != nil {
+			log.Printf("refresh recommendations: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (u *RecommendationUseCase) recommend(ctx context.Context, productIDs []int64) (*entity.Recommendations, error) {
+	products, err := u.repo.FindRelatedProducts(ctx, productIDs, recommendationLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	toppings, err := u.repo.FindRelatedToppings(ctx, productIDs, recommendationLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.Recommendations{Products: products, Toppings: toppings}, nil
+}
+
+func cartProductIDs(carts []entity.Cart) []int64 {
+	seen := make(map[int]bool, len(carts))
+	ids := []int64{}
+
+	for _, cart := range carts {
+		if seen[cart.ProductId] {
+			continue
+		}
+
+		seen[cart.ProductId] = true
+		ids = append(ids, int64(cart.ProductId))
+	}
+
+	return ids
+}
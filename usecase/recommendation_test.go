@@ -0,0 +1,23 @@
+package usecase
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/yosepalexsander/waysbucks-api/entity"
+)
+
+func TestCartProductIDs(t *testing.T) {
+	carts := []entity.Cart{{ProductId: 3}, {ProductId: 1}, {ProductId: 3}, {ProductId: 2}}
+
+	got := cartProductIDs(carts)
+	want := []int64{3, 1, 2}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	if got := cartProductIDs(nil); len(got) != 0 {
+		t.Errorf("expected no product ids, got %v", got)
+	}
+}
@@ -0,0 +1,305 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/yosepalexsander/waysbucks-api/entity"
+	"github.com/yosepalexsander/waysbucks-api/repository"
+)
+
+var (
+	ErrNotCancellable     = errors.New("transaction cannot be cancelled once it is being prepared")
+	ErrNotRefundable      = errors.New("transaction has no payment to refund")
+	ErrOrderRefunded      = errors.New("order line is unknown or already refunded")
+	ErrRefundNotRetryable = errors.New("only a failed refund can be retried")
+	ErrPaymentGateway     = errors.New("payment gateway rejected the request")
+)
+
+// CancelTransaction lets the customer cancel an order that is not being
+// prepared yet. A paid order is refunded in full. The status changes before
+// the gateway is called so a concurrent change by the store wins.
+func (u *TransactionUseCase) CancelTransaction(ctx context.Context, id string, userID string, reason string) error {
+	transaction, err := u.repo.FindTransactionByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if transaction.UserId != userID {
+		return sql.ErrNoRows
+	}
+
+	actor := entity.TransactionActor{Kind: entity.ActorCustomer, UserId: userID}
+
+	switch transaction.Status {
+	case entity.StatusPendingPayment:
+		if err := u.transition(ctx, transaction, entity.StatusCancelled, actor, reason); err != nil {
+			return cancelError(err)
+		}
+
+		// a payment settled later is refunded by ReconcilePayment
+		if err := u.provider.CancelPayment(ctx, id); err != nil {
+			log.Printf("cancel payment of %s: %v", id, err)
+		}
+		return nil
+	case entity.StatusPaid:
+		refunds, err := u.repo.FindRefunds(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		refund, _, ok := entity.NewRefund(*transaction, nil, refunds, reason, actor)
+		if !ok {
+			return ErrNotRefundable
+		}
+
+		if err := u.refundTx(ctx, transaction, &refund, entity.StatusCancelled, actor); err != nil {
+			return cancelError(err)
+		}
+
+		return u.sendRefund(ctx, &refund)
+	}
+
+	return ErrNotCancellable
+}
+
+// RefundTransaction refunds the given order lines of a paid transaction, or
+// everything not refunded yet when orderIDs is empty. The transaction moves
+// to refunded once nothing is left to refund.
+func (u *TransactionUseCase) RefundTransaction(ctx context.Context, id string, req entity.RefundRequest, actor entity.TransactionActor) (*entity.Refund, error) {
+	transaction, err := u.repo.FindTransactionByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !entity.IsPaidStatus(transaction.Status) {
+		return nil, ErrNotRefundable
+	}
+
+	refunds, err := u.repo.FindRefunds(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	refund, full, ok := entity.NewRefund(*transaction, req.OrderIds, refunds, req.Reason, actor)
+	if !ok {
+		return nil, ErrOrderRefunded
+	}
+
+	to := ""
+	if full {
+		to = entity.StatusRefunded
+	}
+
+	if err := u.refundTx(ctx, transaction, &refund, to, actor); err != nil {
+		if err == ErrInvalidTransition {
+			return nil, ErrNotRefundable
+		}
+		return nil, err
+	}
+
+	return &refund, u.sendRefund(ctx, &refund)
+}
+
+// RetryRefund sends a failed refund to the gateway again with the same
+// refund key. Its lines must not have been refunded since. The refund is
+// checked and set back to pending with the transaction row locked, so only
+// one of concurrent retries reaches the gateway.
+func (u *TransactionUseCase) RetryRefund(ctx context.Context, id string, refundID int) (*entity.Refund, error) {
+	transaction, err := u.repo.FindTransactionByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var retried *entity.Refund
+	err = u.repo.ExecTx(ctx, func(tx repository.Transactioner) error {
+		if _, err := tx.LockTransaction(ctx, id); err != nil {
+			return err
+		}
+
+		refunds, err := tx.FindRefunds(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		for _, refund := range refunds {
+			if refund.Id != refundID {
+				continue
+			}
+
+			// a pending refund is already with the gateway
+			if refund.Status != entity.RefundFailed {
+				return ErrRefundNotRetryable
+			}
+
+			if _, _, ok := entity.NewRefund(*transaction, refund.OrderIds, refunds, refund.Reason, entity.TransactionActor{}); !ok {
+				return ErrOrderRefunded
+			}
+
+			if err := tx.UpdateRefundStatus(ctx, id, refund.RefundKey, entity.RefundPending); err != nil {
+				return err
+			}
+
+			refund.Status = entity.RefundPending
+			retried = &refund
+			return nil
+		}
+
+		return sql.ErrNoRows
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return retried, u.sendRefund(ctx, retried)
+}
+
+// ReconcilePayment applies a status reported by the payment gateway. Refunds
+// the gateway completed are marked succeeded. A payment that settles after
+// the customer cancelled or the order expired is refunded in full. Repeated
+// reports of the current status are ignored and reports of an earlier status
+// fail with ErrInvalidTransition, so the order of delivery does not matter.
+func (u *TransactionUseCase) ReconcilePayment(ctx context.Context, update entity.PaymentUpdate) error {
+	transaction, err := u.repo.FindTransactionByID(ctx, update.TransactionId)
+	if err != nil {
+		return err
+	}
+
+	if update.GrossAmount != 0 && update.GrossAmount != transaction.Total {
+		return ErrAmountMismatch
+	}
+
+	for _, key := range update.RefundKeys {
+		refund := entity.Refund{TransactionId: transaction.Id, RefundKey: key}
+		if err := u.setRefundStatus(ctx, refund, entity.RefundSucceeded); err != nil {
+			return err
+		}
+	}
+
+	if update.Status == "" || update.Status == transaction.Status {
+		return nil
+	}
+
+	if update.Status == entity.StatusPaid && (transaction.Status == entity.StatusCancelled || transaction.Status == entity.StatusFailed) {
+		return u.refundLatePayment(ctx, transaction)
+	}
+
+	actor := entity.TransactionActor{Kind: entity.ActorPayment}
+	return u.transition(ctx, transaction, update.Status, actor, update.Note)
+}
+
+// refundLatePayment refunds a payment received for a cancelled or failed
+// transaction unless it was refunded already
+func (u *TransactionUseCase) refundLatePayment(ctx context.Context, transaction *entity.Transaction) error {
+	refunds, err := u.repo.FindRefunds(ctx, transaction.Id)
+	if err != nil {
+		return err
+	}
+
+	actor := entity.TransactionActor{Kind: entity.ActorSystem}
+	refund, _, ok := entity.NewRefund(*transaction, nil, refunds, "paid after "+transaction.Status, actor)
+	if !ok {
+		return nil
+	}
+
+	if err := u.refundTx(ctx, transaction, &refund, "", actor); err != nil {
+		// a concurrent delivery of the payment refunded it first
+		if err == ErrOrderRefunded {
+			return nil
+		}
+		return err
+	}
+
+	return u.sendRefund(ctx, &refund)
+}
+
+// refundTx records the pending refund and, unless to is empty, moves the
+// transaction to the status in the same database transaction. The
+// transaction row is locked first so concurrent refunds are planned one
+// after the other, and the refund fails with ErrOrderRefunded when a line
+// was refunded since it was planned.
+func (u *TransactionUseCase) refundTx(ctx context.Context, transaction *entity.Transaction, refund *entity.Refund, to string, actor entity.TransactionActor) error {
+	if to != "" && !entity.CanTransition(transaction.Status, to) {
+		return ErrInvalidTransition
+	}
+
+	err := u.repo.ExecTx(ctx, func(tx repository.Transactioner) error {
+		if _, err := tx.LockTransaction(ctx, transaction.Id); err != nil {
+			return err
+		}
+
+		refunds, err := tx.FindRefunds(ctx, transaction.Id)
+		if err != nil {
+			return err
+		}
+
+		planned, _, ok := entity.NewRefund(*transaction, refund.OrderIds, refunds, refund.Reason, entity.TransactionActor{})
+		if !ok {
+			return ErrOrderRefunded
+		}
+		refund.Amount = planned.Amount
+
+		if to != "" {
+			if err := tx.UpdateTransactionStatus(ctx, transaction.Id, transaction.Status, to); err != nil {
+				return err
+			}
+
+			event := entity.NewTransactionEvent(transaction.Id, transaction.Status, to, actor, refund.Reason)
+			if err := tx.CreateTransactionEvent(ctx, event); err != nil {
+				return err
+			}
+		}
+
+		id, err := tx.CreateRefund(ctx, *refund)
+		if err != nil {
+			return err
+		}
+
+		refund.Id = id
+		return nil
+	})
+
+	// the status changed since it was read
+	if err == sql.ErrNoRows {
+		return ErrInvalidTransition
+	}
+
+	return err
+}
+
+// sendRefund sends a pending refund to the gateway and records the outcome
+func (u *TransactionUseCase) sendRefund(ctx context.Context, refund *entity.Refund) error {
+	gatewayErr := u.provider.RefundPayment(ctx, *refund)
+
+	refund.Status = entity.RefundSucceeded
+	if gatewayErr != nil {
+		refund.Status = entity.RefundFailed
+	}
+
+	if err := u.setRefundStatus(ctx, *refund, refund.Status); err != nil {
+		return err
+	}
+
+	if gatewayErr != nil {
+		return fmt.Errorf("%w: %v", ErrPaymentGateway, gatewayErr)
+	}
+
+	return nil
+}
+
+func (u *TransactionUseCase) setRefundStatus(ctx context.Context, refund entity.Refund, status string) error {
+	return u.repo.ExecTx(ctx, func(tx repository.Transactioner) error {
+		return tx.UpdateRefundStatus(ctx, refund.TransactionId, refund.RefundKey, status)
+	})
+}
+
+func cancelError(err error) error {
+	if err == ErrInvalidTransition {
+		return ErrNotCancellable
+	}
+
+	return err
+}
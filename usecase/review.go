@@ -0,0 +1,65 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+
+	"github.com/yosepalexsander/waysbucks-api/entity"
+	"github.com/yosepalexsander/waysbucks-api/repository"
+)
+
+var ErrNotVerifiedBuyer = errors.New("only customers who bought this product can review it")
+
+type ReviewUseCase struct {
+	repo repository.ReviewRepository
+}
+
+func NewReviewUseCase(repo repository.ReviewRepository) ReviewUseCase {
+	return ReviewUseCase{repo}
+}
+
+func (u *ReviewUseCase) FindProductReviews(ctx context.Context, productID int) ([]entity.Review, error) {
+	return u.repo.FindProductReviews(ctx, productID, false)
+}
+
+func (u *ReviewUseCase) FindFlaggedReviews(ctx context.Context) ([]entity.Review, error) {
+	return u.repo.FindFlaggedReviews(ctx)
+}
+
+// SubmitReview creates the user's review for a product or replaces the
+// previous one. Only users with a paid transaction containing the
+// product are allowed to review it.
+func (u *ReviewUseCase) SubmitReview(ctx context.Context, productID int, userID string, req entity.ReviewRequest) error {
+	purchased, err := u.repo.HasPurchasedProduct(ctx, userID, productID)
+	if err != nil {
+		return err
+	}
+
+	if !purchased {
+		return ErrNotVerifiedBuyer
+	}
+
+	return u.repo.SaveReview(ctx, entity.NewReview(productID, userID, req))
+}
+
+func (u *ReviewUseCase) ModerateReview(ctx context.Context, id int, req entity.ReviewModerationRequest) error {
+	data := make(map[string]interface{}, 2)
+
+	if req.IsHidden != nil {
+		data["is_hidden"] = *req.IsHidden
+	}
+
+	if req.IsFlagged != nil {
+		data["is_flagged"] = *req.IsFlagged
+	}
+
+	if len(data) == 0 {
+		return nil
+	}
+
+	return u.repo.UpdateReview(ctx, id, data)
+}
+
+func (u *ReviewUseCase) DeleteReview(ctx context.Context, id int, userID string) error {
+	return u.repo.DeleteReview(ctx, id, userID)
+}
@@ -0,0 +1,147 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"testing"
+
+	"github.com/yosepalexsander/waysbucks-api/entity"
+)
+
+type stubReviewRepository struct {
+	purchases map[string][]int
+	reviews   []entity.Review
+	updates   map[int]map[string]interface{}
+}
+
+func (s *stubReviewRepository) FindProductReviews(ctx context.Context, productID int, includeHidden bool) ([]entity.Review, error) {
+	reviews := []entity.Review{}
+	for _, r := range s.reviews {
+		if r.ProductId == productID {
+			reviews = append(reviews, r)
+		}
+	}
+	return reviews, nil
+}
+
+func (s *stubReviewRepository) FindFlaggedReviews(ctx context.Context) ([]entity.Review, error) {
+	return nil, nil
+}
+
+func (s *stubReviewRepository) FindReview(ctx context.Context, id int) (*entity.Review, error) {
+	for _, r := range s.reviews {
+		if r.Id == id {
+			return &r, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (s *stubReviewRepository) HasPurchasedProduct(ctx context.Context, userID string, productID int) (bool, error) {
+	for _, id := range s.purchases[userID] {
+		if id == productID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *stubReviewRepository) SaveReview(ctx context.Context, review entity.Review) error {
+	for i, r := range s.reviews {
+		if r.ProductId == review.ProductId && r.UserId == review.UserId {
+			s.reviews[i] = review
+			return nil
+		}
+	}
+	s.reviews = append(s.reviews, review)
+	return nil
+}
+
+func (s *stubReviewRepository) UpdateReview(ctx context.Context, id int, data map[string]interface{}) error {
+	if s.updates == nil {
+		s.updates = map[int]map[string]interface{}{}
+	}
+	s.updates[id] = data
+	return nil
+}
+
+func (s *stubReviewRepository) DeleteReview(ctx context.Context, id int, userID string) error {
+	return nil
+}
+
+func TestSubmitReview(t *testing.T) {
+	tests := []struct {
+		name      string
+		userID    string
+		productID int
+		wantErr   error
+	}{
+		{name: "verified buyer", userID: "buyer", productID: 1},
+		{name: "buyer of another product", userID: "buyer", productID: 2, wantErr: ErrNotVerifiedBuyer},
+		{name: "never bought anything", userID: "visitor", productID: 1, wantErr: ErrNotVerifiedBuyer},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &stubReviewRepository{purchases: map[string][]int{"buyer": {1}}}
+			u := NewReviewUseCase(repo)
+
+			err := u.SubmitReview(context.Background(), tt.productID, tt.userID, entity.ReviewRequest{Rating: 5, Comment: "great"})
+			if err != tt.wantErr {
+				t.Fatalf("SubmitReview() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if saved := len(repo.reviews) == 1; saved != (tt.wantErr == nil) {
+				t.Errorf("SubmitReview() saved %d reviews", len(repo.reviews))
+			}
+		})
+	}
+}
+
+func TestSubmitReviewReplacesPreviousReview(t *testing.T) {
+	ctx := context.Background()
+	repo := &stubReviewRepository{purchases: map[string][]int{"buyer": {1}}}
+	u := NewReviewUseCase(repo)
+
+	if err := u.SubmitReview(ctx, 1, "buyer", entity.ReviewRequest{Rating: 2}); err != nil {
+		t.Fatalf("SubmitReview() error = %v", err)
+	}
+	if err := u.SubmitReview(ctx, 1, "buyer", entity.ReviewRequest{Rating: 4, Comment: "better now"}); err != nil {
+		t.Fatalf("SubmitReview() error = %v", err)
+	}
+
+	if len(repo.reviews) != 1 || repo.reviews[0].Rating != 4 {
+		t.Errorf("reviews = %+v, want a single review rated 4", repo.reviews)
+	}
+}
+
+func TestModerateReview(t *testing.T) {
+	yes, no := true, false
+
+	tests := []struct {
+		name string
+		req  entity.ReviewModerationRequest
+		want map[string]interface{}
+	}{
+		{name: "flag", req: entity.ReviewModerationRequest{IsFlagged: &yes}, want: map[string]interface{}{"is_flagged": true}},
+		{name: "hide and clear the flag", req: entity.ReviewModerationRequest{IsHidden: &yes, IsFlagged: &no}, want: map[string]interface{}{"is_hidden": true, "is_flagged": false}},
+		{name: "unhide", req: entity.ReviewModerationRequest{IsHidden: &no}, want: map[string]interface{}{"is_hidden": false}},
+		{name: "nothing to change", req: entity.ReviewModerationRequest{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &stubReviewRepository{}
+			u := NewReviewUseCase(repo)
+
+			if err := u.ModerateReview(context.Background(), 7, tt.req); err != nil {
+				t.Fatalf("ModerateReview() error = %v", err)
+			}
+
+			if got := repo.updates[7]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ModerateReview() updated %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
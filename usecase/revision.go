@@ -0,0 +1,131 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/yosepalexsander/waysbucks-api/entity"
+	"github.com/yosepalexsander/waysbucks-api/repository"
+)
+
+type RevisionUseCase struct {
+	repo repository.RevisionRepository
+}
+
+func NewRevisionUseCase(repo repository.RevisionRepository) RevisionUseCase {
+	return RevisionUseCase{repo}
+}
+
+func (u *RevisionUseCase) FindProductHistory(ctx context.Context, productID int) ([]entity.ProductRevision, error) {
+	return u.repo.FindProductRevisions(ctx, productID)
+}
+
+func (u *RevisionUseCase) FindToppingHistory(ctx context.Context, toppingID int) ([]entity.ToppingRevision, error) {
+	return u.repo.FindToppingRevisions(ctx, toppingID)
+}
+
+// GetCatalogAt returns the menu as it was at the given time
+func (u *RevisionUseCase) GetCatalogAt(ctx context.Context, at time.Time) (*entity.CatalogSnapshot, error) {
+	products, err := u.repo.FindProductsAt(ctx, at)
+	if err != nil {
+		return nil, err
+	}
+
+	toppings, err := u.repo.FindToppingsAt(ctx, at)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.CatalogSnapshot{At: at, Products: products, Toppings: toppings}, nil
+}
+
+// DiffCatalog compares the menu between two points in time
+func (u *RevisionUseCase) DiffCatalog(ctx context.Context, from time.Time, to time.Time) (*entity.CatalogDiff, error) {
+	before, err := u.GetCatalogAt(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+
+	after, err := u.GetCatalogAt(ctx, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.CatalogDiff{
+		From:     from,
+		To:       to,
+		Products: diffProductRevisions(before.Products, after.Products),
+		Toppings: diffToppingRevisions(before.Toppings, after.Toppings),
+	}, nil
+}
+
+func diffProductRevisions(before []entity.ProductRevision, after []entity.ProductRevision) []entity.RevisionDiff {
+	diffs := []entity.RevisionDiff{}
+	old := make(map[int]entity.ProductRevision, len(before))
+	for _, p := range before {
+		old[p.ProductId] = p
+	}
+
+	for _, p := range after {
+		o, ok := old[p.ProductId]
+		if !ok {
+			diffs = append(diffs, entity.RevisionDiff{Id: p.ProductId, Name: p.Name, Action: entity.RevisionActionAdded})
+			continue
+		}
+		delete(old, p.ProductId)
+
+		changes := make(map[string]entity.Change)
+		diffField(changes, "name", o.Name, p.Name)
+		diffField(changes, "description", o.Description, p.Description)
+		diffField(changes, "image", o.Image, p.Image)
+		diffField(changes, "price", o.Price, p.Price)
+		diffField(changes, "is_available", o.IsAvailable, p.IsAvailable)
+
+		if len(changes) > 0 {
+			diffs = append(diffs, entity.RevisionDiff{Id: p.ProductId, Name: p.Name, Action: entity.RevisionActionChanged, Changes: changes})
+		}
+	}
+
+	for _, p := range before {
+		if _, ok := old[p.ProductId]; ok {
+			diffs = append(diffs, entity.RevisionDiff{Id: p.ProductId, Name: p.Name, Action: entity.RevisionActionRemoved})
+		}
+	}
+
+	return diffs
+}
+
+func diffToppingRevisions(before []entity.ToppingRevision, after []entity.ToppingRevision) []entity.RevisionDiff {
+	diffs := []entity.RevisionDiff{}
+	old := make(map[int]entity.ToppingRevision, len(before))
+	for _, t := range before {
+		old[t.ToppingId] = t
+	}
+
+	for _, t := range after {
+		o, ok := old[t.ToppingId]
+		if !ok {
+			diffs = append(diffs, entity.RevisionDiff{Id: t.ToppingId, Name: t.Name, Action: entity.RevisionActionAdded})
+			continue
+		}
+		delete(old, t.ToppingId)
+
+		changes := make(map[string]entity.Change)
+		diffField(changes, "name", o.Name, t.Name)
+		diffField(changes, "image", o.Image, t.Image)
+		diffField(changes, "price", o.Price, t.Price)
+		diffField(changes, "is_available", o.IsAvailable, t.IsAvailable)
+
+		if len(changes) > 0 {
+			diffs = append(diffs, entity.RevisionDiff{Id: t.ToppingId, Name: t.Name, Action: entity.RevisionActionChanged, Changes: changes})
+		}
+	}
+
+	for _, t := range before {
+		if _, ok := old[t.ToppingId]; ok {
+			diffs = append(diffs, entity.RevisionDiff{Id: t.ToppingId, Name: t.Name, Action: entity.RevisionActionRemoved})
+		}
+	}
+
+	return diffs
+}
@@ -0,0 +1,40 @@
+package usecase
+
+import (
+	"testing"
+
+	"github.com/yosepalexsander/waysbucks-api/entity"
+)
+
+func TestDiffProductRevisions(t *testing.T) {
+	before := []entity.ProductRevision{
+		{ProductId: 1, Name: "Latte", Price: 30000, IsAvailable: true},
+		{ProductId: 2, Name: "Mocha", Price: 32000, IsAvailable: true},
+	}
+	after := []entity.ProductRevision{
+		{ProductId: 1, Name: "Latte", Price: 33000, IsAvailable: true},
+		{ProductId: 3, Name: "Matcha", Price: 35000, IsAvailable: true},
+	}
+
+	diffs := diffProductRevisions(before, after)
+	if len(diffs) != 3 {
+		t.Fatalf("expected 3 diffs, got %d", len(diffs))
+	}
+
+	actions := make(map[int]entity.RevisionDiff)
+	for _, d := range diffs {
+		actions[d.Id] = d
+	}
+
+	if d := actions[1]; d.Action != entity.RevisionActionChanged || d.Changes["price"].New != 33000 {
+		t.Errorf("expected price change for product 1, got %+v", d)
+	}
+
+	if actions[2].Action != entity.RevisionActionRemoved {
+		t.Errorf("expected product 2 removed, got %s", actions[2].Action)
+	}
+
+	if actions[3].Action != entity.RevisionActionAdded {
+		t.Errorf("expected product 3 added, got %s", actions[3].Action)
+	}
+}
@@ -0,0 +1,172 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/yosepalexsander/waysbucks-api/entity"
+	"github.com/yosepalexsander/waysbucks-api/repository"
+)
+
+var (
+	ErrStoreUnavailable = errors.New("store is not available")
+	ErrStoreClosed      = errors.New("store is closed")
+)
+
+type StoreUseCase struct {
+	repo    repository.StoreRepository
+	product ProductUseCase
+}
+
+func NewStoreUseCase(repo repository.StoreRepository, product ProductUseCase) StoreUseCase {
+	return StoreUseCase{repo, product}
+}
+
+func (u *StoreUseCase) FindStores(ctx context.Context) ([]entity.Store, error) {
+	return u.repo.FindStores(ctx)
+}
+
+func (u *StoreUseCase) GetStore(ctx context.Context, id int) (*entity.Store, error) {
+	return u.repo.FindStore(ctx, id)
+}
+
+func (u *StoreUseCase) CreateStore(ctx context.Context, req entity.StoreRequest) error {
+	return u.repo.SaveStore(ctx, entity.NewStore(req))
+}
+
+func (u *StoreUseCase) UpdateStore(ctx context.Context, id int, req entity.StoreRequest) error {
+	return u.repo.UpdateStore(ctx, id, entity.NewStore(req))
+}
+
+func (u *StoreUseCase) DeleteStore(ctx context.Context, id int) error {
+	return u.repo.DeleteStore(ctx, id)
+}
+
+func (u *StoreUseCase) AssignStaff(ctx context.Context, storeID int, userID string) error {
+	if _, err := u.repo.FindStore(ctx, storeID); err != nil {
+		return err
+	}
+
+	return u.repo.SaveStaff(ctx, storeID, userID)
+}
+
+func (u *StoreUseCase) RemoveStaff(ctx context.Context, storeID int, userID string) error {
+	return u.repo.DeleteStaff(ctx, storeID, userID)
+}
+
+// CanManageStore reports whether the user is an admin of every store or is
+// assigned to the store
+func (u *StoreUseCase) CanManageStore(ctx context.Context, userID string, isAdmin bool, storeID int) (bool, error) {
+	storeIDs, all, err := staffScope(ctx, u.repo, userID, isAdmin)
+	if err != nil {
+		return false, err
+	}
+
+	if all {
+		return true, nil
+	}
+
+	for _, id := range storeIDs {
+		if int(id) == storeID {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// ManagesAllStores reports whether the user is an admin of every store, who
+// is not assigned to any store
+func (u *StoreUseCase) ManagesAllStores(ctx context.Context, userID string, isAdmin bool) (bool, error) {
+	_, all, err := staffScope(ctx, u.repo, userID, isAdmin)
+	return all, err
+}
+
+// GetMenu returns the catalog of the store with its prices and availability
+func (u *StoreUseCase) GetMenu(ctx context.Context, storeID int, params map[string][]string, locale string) (*entity.StoreMenu, error) {
+	store, err := u.repo.FindStore(ctx, storeID)
+	if err != nil {
+		return nil, err
+	}
+
+	products, _, err := u.product.FindProducts(ctx, params, locale)
+	if err != nil {
+		return nil, err
+	}
+
+	toppings, _, err := u.product.FindToppings(ctx, locale)
+	if err != nil {
+		return nil, err
+	}
+
+	productItems, err := u.repo.FindStoreProducts(ctx, storeID)
+	if err != nil {
+		return nil, err
+	}
+
+	toppingItems, err := u.repo.FindStoreToppings(ctx, storeID)
+	if err != nil {
+		return nil, err
+	}
+
+	// the catalog slices are shared with the catalog cache
+	menu := &entity.StoreMenu{
+		Store:    *store,
+		Products: append([]entity.Product{}, products...),
+		Toppings: append([]entity.ProductTopping{}, toppings...),
+	}
+	entity.ApplyStoreProducts(menu.Products, productItems)
+	entity.ApplyStoreToppings(menu.Toppings, toppingItems)
+
+	return menu, nil
+}
+
+func (u *StoreUseCase) SaveStoreProduct(ctx context.Context, storeID int, productID int, req entity.StoreItemRequest) error {
+	return u.repo.SaveStoreProduct(ctx, entity.NewStoreItem(storeID, productID, req))
+}
+
+func (u *StoreUseCase) DeleteStoreProduct(ctx context.Context, storeID int, productID int) error {
+	return u.repo.DeleteStoreProduct(ctx, storeID, productID)
+}
+
+func (u *StoreUseCase) SaveStoreTopping(ctx context.Context, storeID int, toppingID int, req entity.StoreItemRequest) error {
+	return u.repo.SaveStoreTopping(ctx, entity.NewStoreItem(storeID, toppingID, req))
+}
+
+func (u *StoreUseCase) DeleteStoreTopping(ctx context.Context, storeID int, toppingID int) error {
+	return u.repo.DeleteStoreTopping(ctx, storeID, toppingID)
+}
+
+// staffScope returns the stores the user manages. Admins not assigned to any
+// store manage all of them.
+func staffScope(ctx context.Context, stores repository.StoreFinder, userID string, isAdmin bool) ([]int64, bool, error) {
+	storeIDs, err := stores.FindStaffStoreIDs(ctx, userID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return storeIDs, isAdmin && len(storeIDs) == 0, nil
+}
+
+// openStore returns the store when it accepts orders at t
+func openStore(ctx context.Context, stores repository.StoreFinder, storeID int, t time.Time) (*entity.Store, error) {
+	store, err := stores.FindStore(ctx, storeID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrStoreUnavailable
+		}
+		return nil, err
+	}
+
+	if !store.IsActive {
+		return nil, ErrStoreUnavailable
+	}
+
+	if !store.IsOpenAt(t) {
+		return nil, ErrStoreClosed
+	}
+
+	return store, nil
+}
@@ -2,61 +2,227 @@ package usecase
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
 
 	"github.com/yosepalexsander/waysbucks-api/entity"
 	"github.com/yosepalexsander/waysbucks-api/repository"
 )
 
+var ErrInvalidTransition = errors.New("transaction cannot move to this status")
+
+// PaymentProvider charges, checks, cancels and refunds payments at a payment
+// gateway and authenticates its notifications
+type PaymentProvider interface {
+	CreateCharge(ctx context.Context, t *entity.Transaction) (*entity.PaymentCharge, error)
+	GetStatus(ctx context.Context, transactionID string) (*entity.PaymentUpdate, error)
+	CancelPayment(ctx context.Context, transactionID string) error
+	RefundPayment(ctx context.Context, refund entity.Refund) error
+	ParseNotification(body []byte) (*entity.PaymentNotification, error)
+}
+
 type TransactionUseCase struct {
-	repo repository.TransactionRepository
+	repo       repository.TransactionRepository
+	stores     repository.StoreFinder
+	addresses  repository.AddressFinder
+	pricer     pricer
+	provider   PaymentProvider
+	delivery   entity.DeliveryRules
+	translator translator
+}
+
+func NewTransactionUseCase(repo repository.TransactionRepository, products repository.ProductFinder, stores repository.StoreFinder, addresses repository.AddressFinder, translations repository.TranslationFinder, provider PaymentProvider, delivery entity.DeliveryRules) TransactionUseCase {
+	return TransactionUseCase{repo, stores, addresses, pricer{products, stores}, provider, delivery, translator{translations}}
 }
 
-func NewTransactionUseCase(repo repository.TransactionRepository) TransactionUseCase {
-	return TransactionUseCase{repo}
+// FindTransactions returns the transactions of the stores the user manages
+func (u *TransactionUseCase) FindTransactions(ctx context.Context, userID string, isAdmin bool, locale string) ([]entity.Transaction, error) {
+	storeIDs, all, err := staffScope(ctx, u.stores, userID, isAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	if all {
+		storeIDs = nil
+	} else if len(storeIDs) == 0 {
+		return []entity.Transaction{}, nil
+	}
+
+	transactions, err := u.repo.FindTransactions(ctx, storeIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.translator.translateOrders(ctx, transactions, locale); err != nil {
+		return nil, err
+	}
+
+	return transactions, nil
 }
 
-func (u *TransactionUseCase) FindTransactions(ctx context.Context) ([]entity.Transaction, error) {
-	transactions, err := u.repo.FindTransactions(ctx)
+func (u *TransactionUseCase) FindStoreTransactions(ctx context.Context, storeID int, locale string) ([]entity.Transaction, error) {
+	transactions, err := u.repo.FindTransactions(ctx, []int64{int64(storeID)})
 	if err != nil {
 		return nil, err
 	}
 
+	if err := u.translator.translateOrders(ctx, transactions, locale); err != nil {
+		return nil, err
+	}
+
 	return transactions, nil
 }
 
-func (u *TransactionUseCase) GetUserTransactions(ctx context.Context, userID string) ([]entity.Transaction, error) {
+func (u *TransactionUseCase) GetUserTransactions(ctx context.Context, userID string, locale string) ([]entity.Transaction, error) {
 	transactions, err := u.repo.FindUserTransactions(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := u.translator.translateOrders(ctx, transactions, locale); err != nil {
+		return nil, err
+	}
+
 	return transactions, nil
 }
 
+// GetDetailTransaction returns the transaction with its status timeline and
+// refunds
 func (u *TransactionUseCase) GetDetailTransaction(ctx context.Context, id string) (*entity.Transaction, error) {
 	transaction, err := u.repo.FindTransactionByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
-	return transaction, err
+	transaction.Timeline, err = u.repo.FindTransactionEvents(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	transaction.Refunds, err = u.repo.FindRefunds(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return transaction, nil
+}
+
+// GetTransaction returns the detail of a transaction to its customer or to
+// the admins and staff of its store. Anyone else gets sql.ErrNoRows, since the
+// detail holds the delivery address and the people who handled it.
+func (u *TransactionUseCase) GetTransaction(ctx context.Context, id string, userID string, isAdmin bool, locale string) (*entity.Transaction, error) {
+	transaction, err := u.GetDetailTransaction(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if transaction.UserId != userID {
+		allowed, err := u.canManage(ctx, transaction, userID, isAdmin)
+		if err != nil {
+			return nil, err
+		}
+
+		if !allowed {
+			return nil, sql.ErrNoRows
+		}
+	}
+
+	// the copy shares its orders with transaction
+	transactions := []entity.Transaction{*transaction}
+	if err := u.translator.translateOrders(ctx, transactions, locale); err != nil {
+		return nil, err
+	}
+
+	return transaction, nil
 }
 
+// MakeTransaction prices the orders and computes the fees, including the
+// delivery fee to the address, and total on the server. Totals sent by the
+// client must match them. The transaction is returned with the payment page
+// opened at the payment provider.
 func (u *TransactionUseCase) MakeTransaction(ctx context.Context, request entity.TransactionRequest) (*entity.Transaction, error) {
-	transaction := entity.NewTransaction(request)
+	store, err := openStore(ctx, u.stores, request.StoreId, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	if request.AddressId != "" {
+		address, err := u.savedAddress(ctx, request.AddressId, request.UserId)
+		if err != nil {
+			return nil, err
+		}
+		request.UseAddress(*address)
+	}
+
+	lines, err := u.checkOrders(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	subtotal := entity.NewOrderBreakdown(lines, nil).Subtotal
+	delivery, err := u.quoteDelivery(*store, request.Latitude, request.Longitude, subtotal)
+	if err != nil {
+		return nil, err
+	}
+
+	breakdown := entity.NewOrderBreakdown(lines, transactionFees(delivery.Fee))
+	if err := checkTotals(request, breakdown); err != nil {
+		return nil, err
+	}
+
+	transaction := entity.NewTransaction(request, breakdown)
 	if err := u.orderTx(ctx, transaction); err != nil {
 		return nil, err
 	}
+
 	newTransaction, err := u.GetDetailTransaction(ctx, transaction.Transaction.Id)
-	newTransaction.Email = transaction.Transaction.Email
-	newTransaction.ServiceFee = transaction.Transaction.ServiceFee
 	if err != nil {
 		return nil, err
 	}
+	newTransaction.Email = transaction.Transaction.Email
+
+	// without a charge the order stays pending payment until it expires. It is
+	// returned with the error since it is already stored.
+	newTransaction.Payment, err = u.provider.CreateCharge(ctx, newTransaction)
+	if err != nil {
+		return newTransaction, fmt.Errorf("%w: %v", ErrPaymentGateway, err)
+	}
 
 	return newTransaction, nil
 }
 
+// checkOrders runs the cart validation on the ordered lines and fails with a
+// CartChangedError listing every line that drifted from the catalog. It
+// returns the breakdown of every line otherwise.
+func (u *TransactionUseCase) checkOrders(ctx context.Context, request entity.TransactionRequest) ([]entity.PriceBreakdown, error) {
+	validation := &entity.CartValidation{Issues: []entity.CartIssue{}}
+	lines := make([]entity.PriceBreakdown, 0, len(request.Order))
+
+	for _, o := range request.Order {
+		toppingIDs := entity.NormalizeToppingIds(o.ToppingIds)
+
+		issue, breakdown, err := u.pricer.checkLine(ctx, &request.StoreId, o.ProductId, o.VariantId, toppingIDs, o.Qty, o.Price)
+		if err != nil {
+			return nil, err
+		}
+
+		if issue != nil {
+			validation.Issues = append(validation.Issues, *issue)
+			continue
+		}
+
+		lines = append(lines, *breakdown)
+	}
+
+	if len(validation.Issues) > 0 {
+		return nil, &CartChangedError{validation}
+	}
+
+	return lines, nil
+}
+
 func (u *TransactionUseCase) orderTx(ctx context.Context, arg entity.TransactionTxParams) error {
 	txErr := u.repo.ExecTx(ctx, func(tx repository.Transactioner) error {
 		var err error
@@ -65,6 +231,11 @@ func (u *TransactionUseCase) orderTx(ctx context.Context, arg entity.Transaction
 		if err != nil {
 			return err
 		}
+
+		actor := entity.TransactionActor{Kind: entity.ActorCustomer, UserId: arg.Transaction.UserId}
+		if err := tx.CreateTransactionEvent(ctx, entity.NewTransactionEvent(id, "", arg.Transaction.Status, actor, "")); err != nil {
+			return err
+		}
 		for i := range arg.Order {
 			arg.Order[i].TransactionId = id
 			err := tx.CreateOrder(ctx, arg.Order[i])
@@ -72,7 +243,7 @@ func (u *TransactionUseCase) orderTx(ctx context.Context, arg entity.Transaction
 				return err
 			}
 
-			err = tx.DeleteCart(ctx, arg.Order[i].ProductId, arg.Transaction.UserId)
+			err = tx.DeleteOrderedCart(ctx, arg.Order[i], arg.Transaction.UserId)
 			if err != nil {
 				return err
 			}
@@ -88,6 +259,78 @@ func (u *TransactionUseCase) orderTx(ctx context.Context, arg entity.Transaction
 	return nil
 }
 
-func (u *TransactionUseCase) UpdateTransaction(ctx context.Context, id string, data map[string]interface{}) error {
-	return u.repo.UpdateTransaction(ctx, id, data)
+// CanManageTransaction reports whether the user is an admin of every store or
+// is assigned to the store of the transaction
+func (u *TransactionUseCase) CanManageTransaction(ctx context.Context, id string, userID string, isAdmin bool) (bool, error) {
+	transaction, err := u.repo.FindTransactionByID(ctx, id)
+	if err != nil {
+		return false, err
+	}
+
+	return u.canManage(ctx, transaction, userID, isAdmin)
+}
+
+func (u *TransactionUseCase) canManage(ctx context.Context, transaction *entity.Transaction, userID string, isAdmin bool) (bool, error) {
+	storeIDs, all, err := staffScope(ctx, u.stores, userID, isAdmin)
+	if err != nil {
+		return false, err
+	}
+
+	if all {
+		return true, nil
+	}
+
+	if transaction.StoreId == nil {
+		return false, nil
+	}
+
+	for _, id := range storeIDs {
+		if int(id) == *transaction.StoreId {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// AdvanceStoreTransaction lets store staff move an order of their store
+// through preparing, ready and completed
+func (u *TransactionUseCase) AdvanceStoreTransaction(ctx context.Context, storeID int, id string, to string, actor entity.TransactionActor, note string) error {
+	transaction, err := u.repo.FindTransactionByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if transaction.StoreId == nil || *transaction.StoreId != storeID {
+		return sql.ErrNoRows
+	}
+
+	if !entity.IsStaffStatus(to) {
+		return ErrInvalidTransition
+	}
+
+	return u.transition(ctx, transaction, to, actor, note)
+}
+
+func (u *TransactionUseCase) transition(ctx context.Context, transaction *entity.Transaction, to string, actor entity.TransactionActor, note string) error {
+	if !entity.CanTransition(transaction.Status, to) {
+		return ErrInvalidTransition
+	}
+
+	event := entity.NewTransactionEvent(transaction.Id, transaction.Status, to, actor, note)
+
+	err := u.repo.ExecTx(ctx, func(tx repository.Transactioner) error {
+		if err := tx.UpdateTransactionStatus(ctx, transaction.Id, transaction.Status, to); err != nil {
+			return err
+		}
+
+		return tx.CreateTransactionEvent(ctx, event)
+	})
+
+	// the status changed since it was read
+	if err == sql.ErrNoRows {
+		return ErrInvalidTransition
+	}
+
+	return err
 }
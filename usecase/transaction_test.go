@@ -0,0 +1,710 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/yosepalexsander/waysbucks-api/entity"
+	"github.com/yosepalexsander/waysbucks-api/helper"
+	"github.com/yosepalexsander/waysbucks-api/repository"
+	"github.com/yosepalexsander/waysbucks-api/thirdparty"
+)
+
+// stubTransactionRepository keeps transactions in memory. Transactioner
+// methods apply directly since the stub never fails halfway.
+type stubTransactionRepository struct {
+	transactions  map[string]*entity.Transaction
+	events        []entity.TransactionEvent
+	refunds       []entity.Refund
+	notifications []entity.PaymentNotification
+	createdAt     map[string]time.Time
+	carts         []entity.Cart
+}
+
+func (s *stubTransactionRepository) FindTransactions(ctx context.Context, storeIDs []int64) ([]entity.Transaction, error) {
+	return []entity.Transaction{}, nil
+}
+
+func (s *stubTransactionRepository) FindUserTransactions(ctx context.Context, userID string) ([]entity.Transaction, error) {
+	return []entity.Transaction{}, nil
+}
+
+func (s *stubTransactionRepository) FindTransactionByID(ctx context.Context, id string) (*entity.Transaction, error) {
+	t, ok := s.transactions[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	copied := *t
+	return &copied, nil
+}
+
+func (s *stubTransactionRepository) FindTransactionEvents(ctx context.Context, transactionID string) ([]entity.TransactionEvent, error) {
+	events := []entity.TransactionEvent{}
+	for _, e := range s.events {
+		if e.TransactionId == transactionID {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+func (s *stubTransactionRepository) FindRefunds(ctx context.Context, transactionID string) ([]entity.Refund, error) {
+	refunds := []entity.Refund{}
+	for _, r := range s.refunds {
+		if r.TransactionId == transactionID {
+			refunds = append(refunds, r)
+		}
+	}
+	return refunds, nil
+}
+
+func (s *stubTransactionRepository) FindPaymentNotifications(ctx context.Context, transactionID string) ([]entity.PaymentNotification, error) {
+	notifications := []entity.PaymentNotification{}
+	for _, n := range s.notifications {
+		if n.TransactionId == transactionID {
+			notifications = append(notifications, n)
+		}
+	}
+	return notifications, nil
+}
+
+func (s *stubTransactionRepository) FindPaymentNotification(ctx context.Context, id int) (*entity.PaymentNotification, error) {
+	if id < 1 || id > len(s.notifications) {
+		return nil, sql.ErrNoRows
+	}
+	n := s.notifications[id-1]
+	return &n, nil
+}
+
+func (s *stubTransactionRepository) FindPendingPayments(ctx context.Context, before time.Time) ([]entity.PendingPayment, error) {
+	payments := []entity.PendingPayment{}
+	for id, t := range s.transactions {
+		if t.Status == entity.StatusPendingPayment && s.createdAt[id].Before(before) {
+			payments = append(payments, entity.PendingPayment{TransactionId: id, Total: t.Total, CreatedAt: s.createdAt[id]})
+		}
+	}
+	sort.Slice(payments, func(i, j int) bool { return payments[i].TransactionId < payments[j].TransactionId })
+	return payments, nil
+}
+
+func (s *stubTransactionRepository) ExecTx(ctx context.Context, fn func(repository.Transactioner) error) error {
+	return fn(s)
+}
+
+func (s *stubTransactionRepository) DeleteOrderedCart(ctx context.Context, order entity.Order, userID string) error {
+	carts := []entity.Cart{}
+	for _, c := range s.carts {
+		sameVariant := (c.VariantId == nil && order.VariantId == nil) ||
+			(c.VariantId != nil && order.VariantId != nil && *c.VariantId == *order.VariantId)
+		sameToppings := fmt.Sprint(entity.NormalizeToppingIds(c.ToppingIds)) == fmt.Sprint(entity.NormalizeToppingIds(order.ToppingIds))
+		if c.UserId == userID && c.ProductId == order.ProductId && sameVariant && sameToppings {
+			continue
+		}
+		carts = append(carts, c)
+	}
+	s.carts = carts
+	return nil
+}
+
+func (s *stubTransactionRepository) CreateOrder(ctx context.Context, order entity.Order) error {
+	return nil
+}
+
+func (s *stubTransactionRepository) CreateTransaction(ctx context.Context, tx entity.Transaction) (string, error) {
+	s.transactions[tx.Id] = &tx
+	return tx.Id, nil
+}
+
+func (s *stubTransactionRepository) UpdateTransactionStatus(ctx context.Context, id string, from string, to string) error {
+	t, ok := s.transactions[id]
+	if !ok || t.Status != from {
+		return sql.ErrNoRows
+	}
+	t.Status = to
+	return nil
+}
+
+func (s *stubTransactionRepository) CreateTransactionEvent(ctx context.Context, event entity.TransactionEvent) error {
+	s.events = append(s.events, event)
+	return nil
+}
+
+func (s *stubTransactionRepository) LockTransaction(ctx context.Context, id string) (string, error) {
+	t, ok := s.transactions[id]
+	if !ok {
+		return "", sql.ErrNoRows
+	}
+	return t.Status, nil
+}
+
+func (s *stubTransactionRepository) CreateRefund(ctx context.Context, refund entity.Refund) (int, error) {
+	refund.Id = len(s.refunds) + 1
+	s.refunds = append(s.refunds, refund)
+	return refund.Id, nil
+}
+
+func (s *stubTransactionRepository) UpdateRefundStatus(ctx context.Context, transactionID string, refundKey string, status string) error {
+	for i, r := range s.refunds {
+		if r.TransactionId == transactionID && r.RefundKey == refundKey && r.Status != entity.RefundSucceeded {
+			s.refunds[i].Status = status
+		}
+	}
+	return nil
+}
+
+func (s *stubTransactionRepository) SavePaymentNotification(ctx context.Context, n *entity.PaymentNotification) error {
+	for _, stored := range s.notifications {
+		if stored.DedupeKey == n.DedupeKey {
+			n.Id, n.ProcessedAt, n.Error = stored.Id, stored.ProcessedAt, stored.Error
+			return nil
+		}
+	}
+	n.Id = len(s.notifications) + 1
+	s.notifications = append(s.notifications, *n)
+	return nil
+}
+
+func (s *stubTransactionRepository) MarkPaymentNotification(ctx context.Context, id int, processedAt *time.Time, errMsg string) error {
+	s.notifications[id-1].ProcessedAt = processedAt
+	s.notifications[id-1].Error = errMsg
+	return nil
+}
+
+func (s *stubTransactionRepository) Rollback() error {
+	return nil
+}
+
+func (s *stubTransactionRepository) Commit() error {
+	return nil
+}
+
+func newPaidTransaction(id string, status string) *entity.Transaction {
+	storeID := 1
+	return &entity.Transaction{
+		Id:      id,
+		UserId:  "user",
+		Status:  status,
+		StoreId: &storeID,
+		Total:   71000,
+		Orders:  []entity.Order{{Id: 1, Price: 34000}, {Id: 2, Price: 32000}},
+	}
+}
+
+type stubAddressFinder struct {
+	addresses map[string]entity.Address
+}
+
+func (s *stubAddressFinder) FindAllUserAddresses(ctx context.Context, userID string) ([]entity.Address, error) {
+	return []entity.Address{}, nil
+}
+
+func (s *stubAddressFinder) FindAddress(ctx context.Context, id string) (*entity.Address, error) {
+	address, ok := s.addresses[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &address, nil
+}
+
+var testDeliveryRules = entity.DeliveryRules{BaseFee: 5000, PerKmFee: 2000, FreeAbove: 150000, MaxDistanceKm: 10}
+
+func newStubAddressFinder() *stubAddressFinder {
+	return &stubAddressFinder{addresses: map[string]entity.Address{
+		"home":  {Id: "home", UserId: "user", Name: "Budi", Phone: "0812", Address: "Jl. Kemang Raya 8", City: "Jakarta", PostalCode: 12730, Longitude: coordinate(106.8135), Latitude: coordinate(-6.2607)},
+		"bogor": {Id: "bogor", UserId: "user", Name: "Budi", Phone: "0812", Address: "Jl. Pajajaran 1", City: "Bogor", PostalCode: 16143, Longitude: coordinate(106.8166), Latitude: coordinate(-6.5950)},
+		"old":   {Id: "old", UserId: "user", Name: "Budi", Phone: "0812", Address: "Jl. Senopati 2", City: "Jakarta", PostalCode: 12190},
+	}}
+}
+
+func coordinate(value float64) *float64 {
+	return &value
+}
+
+func TestMakeTransactionWithSavedAddress(t *testing.T) {
+	ctx := context.Background()
+	repo := &stubTransactionRepository{transactions: map[string]*entity.Transaction{}}
+	stores := newStubStoreFinder()
+	store := stores.stores[1]
+	store.Latitude, store.Longitude = -6.2615, 106.8106
+	for day := 0; day < 7; day++ {
+		store.OpeningHours = append(store.OpeningHours, entity.OpeningHour{Weekday: day, Opens: "00:00", Closes: "00:00"})
+	}
+	stores.stores[1] = store
+	u := NewTransactionUseCase(repo, newStubProductFinder(), stores, newStubAddressFinder(), newStubTranslationFinder(), thirdparty.NewFakePaymentProvider(), testDeliveryRules)
+	order := []entity.OrderRequest{{Qty: 1, Price: 25000, ProductId: 1}}
+
+	request := entity.TransactionRequest{Email: "budi@mail.com", AddressId: "home", StoreId: 1, Order: order, UserId: "other"}
+	if _, err := u.MakeTransaction(ctx, request); err != ErrAddressNotFound {
+		t.Errorf("address of another user: error = %v, want %v", err, ErrAddressNotFound)
+	}
+
+	request.AddressId = "unknown"
+	request.UserId = "user"
+	if _, err := u.MakeTransaction(ctx, request); err != ErrAddressNotFound {
+		t.Errorf("unknown address: error = %v, want %v", err, ErrAddressNotFound)
+	}
+
+	request.AddressId = "home"
+	transaction, err := u.MakeTransaction(ctx, request)
+	if err != nil {
+		t.Fatalf("MakeTransaction() error = %v", err)
+	}
+
+	stored := repo.transactions[transaction.Id]
+	if stored.Name != "Budi" || stored.Address != "Jl. Kemang Raya 8" || stored.PostalCode != 12730 || *stored.AddressId != "home" ||
+		*stored.Latitude != -6.2607 || *stored.Longitude != 106.8135 {
+		t.Errorf("stored transaction = %+v, want a snapshot of the saved address", stored)
+	}
+
+	// 0.33 km away
+	if stored.DeliveryFee != 7000 || stored.Total != 25000+serviceFee+7000 {
+		t.Errorf("delivery fee = %d and total = %d, want 7000 and %d", stored.DeliveryFee, stored.Total, 25000+serviceFee+7000)
+	}
+
+	negative := append(order, entity.OrderRequest{Qty: -2, Price: -50000, ProductId: 1})
+	if _, err := u.MakeTransaction(ctx, entity.TransactionRequest{Email: "budi@mail.com", AddressId: "home", StoreId: 1, Order: negative, UserId: "user"}); err != ErrInvalidQty {
+		t.Errorf("negative qty: error = %v, want %v", err, ErrInvalidQty)
+	}
+
+	request.AddressId = "bogor"
+	if _, err := u.MakeTransaction(ctx, request); err != ErrOutOfDeliveryRange {
+		t.Errorf("address out of range: error = %v, want %v", err, ErrOutOfDeliveryRange)
+	}
+
+	request.AddressId = "old"
+	if _, err := u.MakeTransaction(ctx, request); err != ErrAddressNotLocated {
+		t.Errorf("saved address without coordinates: error = %v, want %v", err, ErrAddressNotLocated)
+	}
+
+	oneOff := entity.TransactionRequest{Email: "budi@mail.com", Name: "Budi", Phone: "0812", Address: "Jl. Senopati 2", City: "Jakarta", PostalCode: 12190,
+		StoreId: 1, Order: order, UserId: "user"}
+	if _, err := u.MakeTransaction(ctx, oneOff); err != ErrAddressNotLocated {
+		t.Errorf("one-off without coordinates: error = %v, want %v", err, ErrAddressNotLocated)
+	}
+
+	latitude, longitude := -6.2297, 106.8087
+	oneOff.Latitude, oneOff.Longitude = &latitude, &longitude
+	transaction, err = u.MakeTransaction(ctx, oneOff)
+	if err != nil {
+		t.Fatalf("MakeTransaction() one-off error = %v", err)
+	}
+
+	// 3.54 km away
+	if stored := repo.transactions[transaction.Id]; stored.Address != "Jl. Senopati 2" || stored.AddressId != nil || stored.DeliveryFee != 13000 {
+		t.Errorf("one-off transaction = %+v", stored)
+	}
+}
+
+func TestMakeTransactionDeletesOrderedCartLines(t *testing.T) {
+	ctx := context.Background()
+	large := 1
+	repo := &stubTransactionRepository{transactions: map[string]*entity.Transaction{}, carts: []entity.Cart{
+		{Id: 1, UserId: "user", ProductId: 1, Qty: 1},
+		{Id: 2, UserId: "user", ProductId: 1, VariantId: &large, ToppingIds: []int64{1}, Qty: 1},
+		{Id: 3, UserId: "user", ProductId: 1, VariantId: &large, Qty: 1},
+		{Id: 4, UserId: "other", ProductId: 1, VariantId: &large, Qty: 1},
+	}}
+	stores := newStubStoreFinder()
+	store := stores.stores[1]
+	store.Latitude, store.Longitude = -6.2615, 106.8106
+	for day := 0; day < 7; day++ {
+		store.OpeningHours = append(store.OpeningHours, entity.OpeningHour{Weekday: day, Opens: "00:00", Closes: "00:00"})
+	}
+	stores.stores[1] = store
+	u := NewTransactionUseCase(repo, newStubProductFinder(), stores, newStubAddressFinder(), newStubTranslationFinder(), thirdparty.NewFakePaymentProvider(), testDeliveryRules)
+
+	// only the large latte without toppings is checked out
+	order := []entity.OrderRequest{{Qty: 1, Price: 30000, ProductId: 1, VariantId: &large}}
+	if _, err := u.MakeTransaction(ctx, entity.TransactionRequest{Email: "budi@mail.com", AddressId: "home", StoreId: 1, Order: order, UserId: "user"}); err != nil {
+		t.Fatalf("MakeTransaction() error = %v", err)
+	}
+
+	remaining := []int{}
+	for _, c := range repo.carts {
+		remaining = append(remaining, c.Id)
+	}
+	if fmt.Sprint(remaining) != "[1 2 4]" {
+		t.Errorf("cart lines left = %v, want [1 2 4]", remaining)
+	}
+}
+
+func TestQuoteDelivery(t *testing.T) {
+	ctx := context.Background()
+	stores := newStubStoreFinder()
+	store := stores.stores[1]
+	store.Latitude, store.Longitude = -6.2615, 106.8106
+	stores.stores[1] = store
+	u := NewTransactionUseCase(&stubTransactionRepository{}, newStubProductFinder(), stores, newStubAddressFinder(), newStubTranslationFinder(), thirdparty.NewFakePaymentProvider(), testDeliveryRules)
+
+	quote, err := u.QuoteDelivery(ctx, entity.DeliveryQuoteRequest{StoreId: 1, AddressId: "home", Subtotal: 50000, UserId: "user"})
+	if err != nil || quote.Fee != 7000 || quote.DistanceKm != 0.33 {
+		t.Errorf("QuoteDelivery() = %+v, %v, want 7000 for 0.33 km", quote, err)
+	}
+
+	quote, err = u.QuoteDelivery(ctx, entity.DeliveryQuoteRequest{StoreId: 1, AddressId: "home", Subtotal: 150000, UserId: "user"})
+	if err != nil || quote.Fee != 0 {
+		t.Errorf("QuoteDelivery() above the free threshold = %+v, %v", quote, err)
+	}
+
+	if _, err := u.QuoteDelivery(ctx, entity.DeliveryQuoteRequest{StoreId: 1, AddressId: "home", UserId: "other"}); err != ErrAddressNotFound {
+		t.Errorf("address of another user: error = %v, want %v", err, ErrAddressNotFound)
+	}
+
+	if _, err := u.QuoteDelivery(ctx, entity.DeliveryQuoteRequest{StoreId: 2, AddressId: "home", UserId: "user"}); err != ErrStoreUnavailable {
+		t.Errorf("inactive store: error = %v, want %v", err, ErrStoreUnavailable)
+	}
+
+	latitude, longitude := -6.5950, 106.8166
+	if _, err := u.QuoteDelivery(ctx, entity.DeliveryQuoteRequest{StoreId: 1, Latitude: &latitude, Longitude: &longitude}); err != ErrOutOfDeliveryRange {
+		t.Errorf("coordinates out of range: error = %v, want %v", err, ErrOutOfDeliveryRange)
+	}
+
+	if _, err := u.QuoteDelivery(ctx, entity.DeliveryQuoteRequest{StoreId: 1, AddressId: "old", UserId: "user"}); err != ErrAddressNotLocated {
+		t.Errorf("saved address without coordinates: error = %v, want %v", err, ErrAddressNotLocated)
+	}
+
+	if _, err := u.QuoteDelivery(ctx, entity.DeliveryQuoteRequest{StoreId: 1}); err != ErrAddressNotLocated {
+		t.Errorf("no address: error = %v, want %v", err, ErrAddressNotLocated)
+	}
+}
+
+func TestAdvanceStoreTransaction(t *testing.T) {
+	ctx := context.Background()
+	storeID := 1
+	repo := &stubTransactionRepository{transactions: map[string]*entity.Transaction{
+		"ORDER-1": {Id: "ORDER-1", Status: entity.StatusPaid, StoreId: &storeID},
+	}}
+	u := NewTransactionUseCase(repo, newStubProductFinder(), newStubStoreFinder(), newStubAddressFinder(), newStubTranslationFinder(), thirdparty.NewFakePaymentProvider(), testDeliveryRules)
+	staff := entity.TransactionActor{Kind: entity.ActorStaff, UserId: "staff"}
+
+	if err := u.AdvanceStoreTransaction(ctx, 2, "ORDER-1", entity.StatusPreparing, staff, ""); err != sql.ErrNoRows {
+		t.Errorf("order of another store: error = %v, want %v", err, sql.ErrNoRows)
+	}
+
+	if err := u.AdvanceStoreTransaction(ctx, 1, "ORDER-1", entity.StatusRefunded, staff, ""); err != ErrInvalidTransition {
+		t.Errorf("refund by staff: error = %v, want %v", err, ErrInvalidTransition)
+	}
+
+	if err := u.AdvanceStoreTransaction(ctx, 1, "ORDER-1", entity.StatusReady, staff, ""); err != ErrInvalidTransition {
+		t.Errorf("skipping preparing: error = %v, want %v", err, ErrInvalidTransition)
+	}
+
+	for _, status := range []string{entity.StatusPreparing, entity.StatusReady, entity.StatusCompleted} {
+		if err := u.AdvanceStoreTransaction(ctx, 1, "ORDER-1", status, staff, ""); err != nil {
+			t.Fatalf("advance to %s: error = %v", status, err)
+		}
+	}
+
+	transaction, _ := u.GetDetailTransaction(ctx, "ORDER-1")
+	if transaction.Status != entity.StatusCompleted || len(transaction.Timeline) != 3 {
+		t.Fatalf("transaction = %s with %d events, want completed with 3", transaction.Status, len(transaction.Timeline))
+	}
+
+	last := transaction.Timeline[2]
+	if *last.FromStatus != entity.StatusReady || last.ToStatus != entity.StatusCompleted || last.Actor != entity.ActorStaff || *last.ActorId != "staff" {
+		t.Errorf("last event = %+v", last)
+	}
+}
+
+func TestCancelTransaction(t *testing.T) {
+	ctx := context.Background()
+	repo := &stubTransactionRepository{transactions: map[string]*entity.Transaction{
+		"ORDER-1": newPaidTransaction("ORDER-1", entity.StatusPendingPayment),
+		"ORDER-2": newPaidTransaction("ORDER-2", entity.StatusPaid),
+		"ORDER-3": newPaidTransaction("ORDER-3", entity.StatusPreparing),
+	}}
+	provider := thirdparty.NewFakePaymentProvider()
+	u := NewTransactionUseCase(repo, newStubProductFinder(), newStubStoreFinder(), newStubAddressFinder(), newStubTranslationFinder(), provider, testDeliveryRules)
+	provider.CreateCharge(ctx, repo.transactions["ORDER-1"])
+	provider.CreateCharge(ctx, repo.transactions["ORDER-2"])
+	provider.SetStatus("ORDER-2", "settlement")
+
+	if err := u.CancelTransaction(ctx, "ORDER-1", "other", ""); err != sql.ErrNoRows {
+		t.Errorf("cancel by another user: error = %v, want %v", err, sql.ErrNoRows)
+	}
+
+	if err := u.CancelTransaction(ctx, "ORDER-3", "user", ""); err != ErrNotCancellable {
+		t.Errorf("cancel while preparing: error = %v, want %v", err, ErrNotCancellable)
+	}
+
+	if err := u.CancelTransaction(ctx, "ORDER-1", "user", ""); err != nil {
+		t.Fatalf("cancel unpaid: error = %v", err)
+	}
+	payment, _ := provider.GetStatus(ctx, "ORDER-1")
+	if repo.transactions["ORDER-1"].Status != entity.StatusCancelled || payment.Status != entity.StatusCancelled || len(repo.refunds) != 0 {
+		t.Errorf("cancel unpaid: status = %s, payment = %s, refunds = %d", repo.transactions["ORDER-1"].Status, payment.Status, len(repo.refunds))
+	}
+
+	if err := u.CancelTransaction(ctx, "ORDER-2", "user", "changed my mind"); err != nil {
+		t.Fatalf("cancel paid: error = %v", err)
+	}
+	if repo.transactions["ORDER-2"].Status != entity.StatusCancelled {
+		t.Errorf("cancel paid: status = %s, want cancelled", repo.transactions["ORDER-2"].Status)
+	}
+	if refunded := provider.Refunds(); len(refunded) != 1 || refunded[0].Amount != 71000 || repo.refunds[0].Status != entity.RefundSucceeded {
+		t.Errorf("cancel paid: refunds = %+v", repo.refunds)
+	}
+}
+
+func TestRefundTransaction(t *testing.T) {
+	ctx := context.Background()
+	repo := &stubTransactionRepository{transactions: map[string]*entity.Transaction{
+		"ORDER-1": newPaidTransaction("ORDER-1", entity.StatusCompleted),
+	}}
+	provider := thirdparty.NewFakePaymentProvider()
+	u := NewTransactionUseCase(repo, newStubProductFinder(), newStubStoreFinder(), newStubAddressFinder(), newStubTranslationFinder(), provider, testDeliveryRules)
+	admin := entity.TransactionActor{Kind: entity.ActorStaff, UserId: "admin"}
+	provider.CreateCharge(ctx, repo.transactions["ORDER-1"])
+	provider.SetStatus("ORDER-1", "settlement")
+
+	refund, err := u.RefundTransaction(ctx, "ORDER-1", entity.RefundRequest{OrderIds: []int64{2}, Reason: "spilled"}, admin)
+	if err != nil {
+		t.Fatalf("partial refund: error = %v", err)
+	}
+	if refund.Amount != 32000 || refund.Status != entity.RefundSucceeded || repo.transactions["ORDER-1"].Status != entity.StatusCompleted {
+		t.Errorf("partial refund = %+v with status %s", refund, repo.transactions["ORDER-1"].Status)
+	}
+
+	if _, err := u.RefundTransaction(ctx, "ORDER-1", entity.RefundRequest{OrderIds: []int64{2}, Reason: "again"}, admin); err != ErrOrderRefunded {
+		t.Errorf("refund a refunded line: error = %v, want %v", err, ErrOrderRefunded)
+	}
+
+	// the last line is refunded with the fees
+	provider.FailRefunds(errors.New("timeout"))
+	refund, err = u.RefundTransaction(ctx, "ORDER-1", entity.RefundRequest{Reason: "closing"}, admin)
+	if !errors.Is(err, ErrPaymentGateway) {
+		t.Fatalf("refund rejected by the gateway: error = %v, want %v", err, ErrPaymentGateway)
+	}
+	if refund.Amount != 39000 || refund.Status != entity.RefundFailed || repo.transactions["ORDER-1"].Status != entity.StatusRefunded {
+		t.Errorf("final refund = %+v with status %s", refund, repo.transactions["ORDER-1"].Status)
+	}
+
+	// a retry in flight has set the refund back to pending
+	repo.refunds[refund.Id-1].Status = entity.RefundPending
+	if _, err := u.RetryRefund(ctx, "ORDER-1", refund.Id); err != ErrRefundNotRetryable {
+		t.Errorf("retry a pending refund: error = %v, want %v", err, ErrRefundNotRetryable)
+	}
+	repo.refunds[refund.Id-1].Status = entity.RefundFailed
+
+	provider.FailRefunds(nil)
+	refund, err = u.RetryRefund(ctx, "ORDER-1", refund.Id)
+	if err != nil || refund.Status != entity.RefundSucceeded {
+		t.Fatalf("retry refund = %+v, error = %v", refund, err)
+	}
+
+	if _, err := u.RetryRefund(ctx, "ORDER-1", refund.Id); err != ErrRefundNotRetryable {
+		t.Errorf("retry a succeeded refund: error = %v, want %v", err, ErrRefundNotRetryable)
+	}
+}
+
+func TestRefundTxReplansConcurrentRefunds(t *testing.T) {
+	ctx := context.Background()
+	repo := &stubTransactionRepository{transactions: map[string]*entity.Transaction{
+		"ORDER-1": newPaidTransaction("ORDER-1", entity.StatusCancelled),
+	}}
+	u := NewTransactionUseCase(repo, newStubProductFinder(), newStubStoreFinder(), newStubAddressFinder(), newStubTranslationFinder(), thirdparty.NewFakePaymentProvider(), testDeliveryRules)
+	transaction := repo.transactions["ORDER-1"]
+	system := entity.TransactionActor{Kind: entity.ActorSystem}
+
+	// two deliveries of a late payment plan their refund before either is recorded
+	first, _, _ := entity.NewRefund(*transaction, nil, nil, "paid after cancelled", system)
+	second, _, _ := entity.NewRefund(*transaction, nil, nil, "paid after cancelled", system)
+
+	if err := u.refundTx(ctx, transaction, &first, "", system); err != nil {
+		t.Fatalf("first refund: error = %v", err)
+	}
+
+	if err := u.refundTx(ctx, transaction, &second, "", system); err != ErrOrderRefunded {
+		t.Errorf("second refund: error = %v, want %v", err, ErrOrderRefunded)
+	}
+
+	if len(repo.refunds) != 1 || repo.refunds[0].Amount != 71000 {
+		t.Errorf("refunds = %+v, want a single full refund", repo.refunds)
+	}
+}
+
+func TestCanManageTransaction(t *testing.T) {
+	ctx := context.Background()
+	otherStore := 2
+	repo := &stubTransactionRepository{transactions: map[string]*entity.Transaction{
+		"ORDER-1": newPaidTransaction("ORDER-1", entity.StatusPaid),
+		"ORDER-2": {Id: "ORDER-2", Status: entity.StatusPaid, StoreId: &otherStore},
+		"ORDER-3": {Id: "ORDER-3", Status: entity.StatusPaid},
+	}}
+	stores := newStubStoreFinder()
+	stores.staff = map[string][]int64{"store-admin": {1}, "staff": {1}}
+	u := NewTransactionUseCase(repo, newStubProductFinder(), stores, newStubAddressFinder(), newStubTranslationFinder(), thirdparty.NewFakePaymentProvider(), testDeliveryRules)
+
+	tests := []struct {
+		name    string
+		id      string
+		userID  string
+		isAdmin bool
+		want    bool
+	}{
+		{name: "admin of every store", id: "ORDER-2", userID: "admin", isAdmin: true, want: true},
+		{name: "store admin of the store", id: "ORDER-1", userID: "store-admin", isAdmin: true, want: true},
+		{name: "store admin of another store", id: "ORDER-2", userID: "store-admin", isAdmin: true},
+		{name: "store admin without a store", id: "ORDER-3", userID: "store-admin", isAdmin: true},
+		{name: "staff of the store", id: "ORDER-1", userID: "staff", want: true},
+		{name: "staff of another store", id: "ORDER-2", userID: "staff"},
+		{name: "customer", id: "ORDER-1", userID: "user"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := u.CanManageTransaction(ctx, tt.id, tt.userID, tt.isAdmin)
+			if err != nil || got != tt.want {
+				t.Errorf("CanManageTransaction() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+
+	if _, err := u.CanManageTransaction(ctx, "ORDER-9", "admin", true); err != sql.ErrNoRows {
+		t.Errorf("unknown transaction: error = %v, want %v", err, sql.ErrNoRows)
+	}
+}
+
+func TestGetTransaction(t *testing.T) {
+	ctx := context.Background()
+	repo := &stubTransactionRepository{transactions: map[string]*entity.Transaction{
+		"ORDER-1": newPaidTransaction("ORDER-1", entity.StatusPaid),
+	}}
+	stores := newStubStoreFinder()
+	stores.staff = map[string][]int64{"staff": {1}, "other-staff": {2}}
+	u := NewTransactionUseCase(repo, newStubProductFinder(), stores, newStubAddressFinder(), newStubTranslationFinder(), thirdparty.NewFakePaymentProvider(), testDeliveryRules)
+
+	for _, userID := range []string{"user", "staff", "admin"} {
+		if _, err := u.GetTransaction(ctx, "ORDER-1", userID, userID == "admin", helper.DefaultLocale); err != nil {
+			t.Errorf("GetTransaction() by %s: error = %v", userID, err)
+		}
+	}
+
+	for _, userID := range []string{"other-user", "other-staff"} {
+		if _, err := u.GetTransaction(ctx, "ORDER-1", userID, false, helper.DefaultLocale); err != sql.ErrNoRows {
+			t.Errorf("GetTransaction() by %s: error = %v, want %v", userID, err, sql.ErrNoRows)
+		}
+	}
+}
+
+func TestReconcilePayment(t *testing.T) {
+	ctx := context.Background()
+	repo := &stubTransactionRepository{transactions: map[string]*entity.Transaction{
+		"ORDER-1": newPaidTransaction("ORDER-1", entity.StatusPendingPayment),
+		"ORDER-2": newPaidTransaction("ORDER-2", entity.StatusCancelled),
+	}}
+	provider := thirdparty.NewFakePaymentProvider()
+	u := NewTransactionUseCase(repo, newStubProductFinder(), newStubStoreFinder(), newStubAddressFinder(), newStubTranslationFinder(), provider, testDeliveryRules)
+
+	provider.CreateCharge(ctx, repo.transactions["ORDER-2"])
+	provider.SetStatus("ORDER-2", "settlement")
+
+	paid := entity.PaymentUpdate{TransactionId: "ORDER-1", Status: entity.StatusPaid}
+	for i := 0; i < 2; i++ {
+		if err := u.ReconcilePayment(ctx, paid); err != nil {
+			t.Fatalf("paid notification %d: error = %v", i, err)
+		}
+	}
+	if repo.transactions["ORDER-1"].Status != entity.StatusPaid || len(repo.events) != 1 {
+		t.Errorf("paid notification: status = %s with %d events", repo.transactions["ORDER-1"].Status, len(repo.events))
+	}
+
+	expired := entity.PaymentUpdate{TransactionId: "ORDER-1", Status: entity.StatusFailed}
+	if err := u.ReconcilePayment(ctx, expired); err != ErrInvalidTransition {
+		t.Errorf("late expiry: error = %v, want %v", err, ErrInvalidTransition)
+	}
+
+	// paid after the customer cancelled
+	late := entity.PaymentUpdate{TransactionId: "ORDER-2", Status: entity.StatusPaid}
+	for i := 0; i < 2; i++ {
+		if err := u.ReconcilePayment(ctx, late); err != nil {
+			t.Fatalf("late payment %d: error = %v", i, err)
+		}
+	}
+	if refunded := provider.Refunds(); repo.transactions["ORDER-2"].Status != entity.StatusCancelled || len(refunded) != 1 || refunded[0].Amount != 71000 {
+		t.Errorf("late payment: status = %s, refunds = %+v", repo.transactions["ORDER-2"].Status, refunded)
+	}
+}
+
+func TestHandlePaymentNotification(t *testing.T) {
+	ctx := context.Background()
+	repo := &stubTransactionRepository{transactions: map[string]*entity.Transaction{
+		"ORDER-1": newPaidTransaction("ORDER-1", entity.StatusPendingPayment),
+	}}
+	u := NewTransactionUseCase(repo, newStubProductFinder(), newStubStoreFinder(), newStubAddressFinder(), newStubTranslationFinder(), thirdparty.NewFakePaymentProvider(), testDeliveryRules)
+
+	notify := func(key string, status string, amount int) error {
+		return u.HandlePaymentNotification(ctx, entity.PaymentNotification{
+			PaymentUpdate: entity.PaymentUpdate{TransactionId: "ORDER-1", Status: status, GrossAmount: amount},
+			DedupeKey:     key,
+		})
+	}
+
+	if err := notify("forged amount", entity.StatusPaid, 1000); err != ErrAmountMismatch {
+		t.Fatalf("amount mismatch: error = %v, want %v", err, ErrAmountMismatch)
+	}
+	if repo.transactions["ORDER-1"].Status != entity.StatusPendingPayment || repo.notifications[0].ProcessedAt != nil || repo.notifications[0].Error == "" {
+		t.Errorf("amount mismatch applied: %+v", repo.notifications[0])
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := notify("settlement", entity.StatusPaid, 71000); err != nil {
+			t.Fatalf("settlement delivery %d: error = %v", i, err)
+		}
+	}
+	if len(repo.notifications) != 2 || len(repo.events) != 1 || repo.notifications[1].ProcessedAt == nil {
+		t.Errorf("settlement stored %d times with %d events", len(repo.notifications), len(repo.events))
+	}
+
+	// an expiry delivered after the settlement leaves the order paid
+	if err := notify("expire", entity.StatusFailed, 71000); err != ErrInvalidTransition {
+		t.Errorf("late expiry: error = %v, want %v", err, ErrInvalidTransition)
+	}
+	if repo.transactions["ORDER-1"].Status != entity.StatusPaid || repo.notifications[2].ProcessedAt == nil {
+		t.Errorf("late expiry: status = %s, notification = %+v", repo.transactions["ORDER-1"].Status, repo.notifications[2])
+	}
+
+	if _, err := u.ReplayPaymentNotification(ctx, "ORDER-2", 1); err != sql.ErrNoRows {
+		t.Errorf("replay of another transaction: error = %v, want %v", err, sql.ErrNoRows)
+	}
+
+	replayed, err := u.ReplayPaymentNotification(ctx, "ORDER-1", 1)
+	if err != nil || replayed.ProcessedAt != nil || replayed.Error != ErrAmountMismatch.Error() {
+		t.Errorf("replay = %+v, error = %v", replayed, err)
+	}
+}
+
+func TestFakeProviderNotification(t *testing.T) {
+	ctx := context.Background()
+	repo := &stubTransactionRepository{transactions: map[string]*entity.Transaction{
+		"ORDER-1": newPaidTransaction("ORDER-1", entity.StatusPendingPayment),
+	}}
+	provider := thirdparty.NewFakePaymentProvider()
+	u := NewTransactionUseCase(repo, newStubProductFinder(), newStubStoreFinder(), newStubAddressFinder(), newStubTranslationFinder(), provider, testDeliveryRules)
+
+	provider.CreateCharge(ctx, repo.transactions["ORDER-1"])
+	provider.SetStatus("ORDER-1", "settlement")
+
+	n, err := u.ParsePaymentNotification(provider.Notification("ORDER-1"))
+	if err != nil {
+		t.Fatalf("parse notification: error = %v", err)
+	}
+
+	if err := u.HandlePaymentNotification(ctx, *n); err != nil {
+		t.Fatalf("handle notification: error = %v", err)
+	}
+
+	if status := repo.transactions["ORDER-1"].Status; status != entity.StatusPaid {
+		t.Errorf("status = %s, want %s", status, entity.StatusPaid)
+	}
+
+	forged := thirdparty.NewMidtransProvider("server-key", false)
+	if _, err := forged.ParseNotification(provider.Notification("ORDER-1")); err != thirdparty.ErrInvalidSignature {
+		t.Errorf("fake notification at midtrans: error = %v, want %v", err, thirdparty.ErrInvalidSignature)
+	}
+}
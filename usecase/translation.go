@@ -0,0 +1,230 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/yosepalexsander/waysbucks-api/entity"
+	"github.com/yosepalexsander/waysbucks-api/helper"
+	"github.com/yosepalexsander/waysbucks-api/repository"
+)
+
+func (u *ProductUseCase) FindProductTranslations(ctx context.Context, productID int) ([]entity.Translation, error) {
+	return u.translations.FindProductTranslations(ctx, productID)
+}
+
+func (u *ProductUseCase) FindToppingTranslations(ctx context.Context, toppingID int) ([]entity.Translation, error) {
+	return u.translations.FindToppingTranslations(ctx, toppingID)
+}
+
+func (u *ProductUseCase) SaveProductTranslation(ctx context.Context, productID int, locale string, req entity.TranslationRequest) error {
+	defer u.cache.invalidate()
+
+	if !helper.IsSupportedLocale(locale) {
+		return ErrUnsupportedLocale
+	}
+
+	if _, err := u.repo.FindProduct(ctx, productID); err != nil {
+		return err
+	}
+
+	return u.translations.SaveProductTranslation(ctx, productID, entity.NewTranslation(locale, req))
+}
+
+func (u *ProductUseCase) SaveToppingTranslation(ctx context.Context, toppingID int, locale string, req entity.TranslationRequest) error {
+	defer u.cache.invalidate()
+
+	if !helper.IsSupportedLocale(locale) {
+		return ErrUnsupportedLocale
+	}
+
+	if _, err := u.repo.FindTopping(ctx, toppingID); err != nil {
+		return err
+	}
+
+	return u.translations.SaveToppingTranslation(ctx, toppingID, entity.NewTranslation(locale, req))
+}
+
+func (u *ProductUseCase) DeleteProductTranslation(ctx context.Context, productID int, locale string) error {
+	defer u.cache.invalidate()
+
+	return u.translations.DeleteProductTranslation(ctx, productID, locale)
+}
+
+func (u *ProductUseCase) DeleteToppingTranslation(ctx context.Context, toppingID int, locale string) error {
+	defer u.cache.invalidate()
+
+	return u.translations.DeleteToppingTranslation(ctx, toppingID, locale)
+}
+
+// translator replaces product and topping names with their translation in
+// the requested locale. Content without a translation is left as is.
+type translator struct {
+	repo repository.TranslationFinder
+}
+
+func (t translator) productTranslations(ctx context.Context, locale string) (map[int]entity.Translation, error) {
+	if t.repo == nil || locale == helper.DefaultLocale {
+		return nil, nil
+	}
+
+	translations, err := t.repo.FindProductTranslationsByLocale(ctx, locale)
+	if err != nil {
+		return nil, err
+	}
+
+	byProduct := make(map[int]entity.Translation, len(translations))
+	for _, t := range translations {
+		byProduct[t.Id] = t
+	}
+
+	return byProduct, nil
+}
+
+func (t translator) toppingNames(ctx context.Context, locale string) (map[int]string, error) {
+	if t.repo == nil || locale == helper.DefaultLocale {
+		return nil, nil
+	}
+
+	translations, err := t.repo.FindToppingTranslationsByLocale(ctx, locale)
+	if err != nil {
+		return nil, err
+	}
+
+	byTopping := make(map[int]string, len(translations))
+	for _, t := range translations {
+		byTopping[t.Id] = t.Name
+	}
+
+	return byTopping, nil
+}
+
+func (u *ProductUseCase) translator() translator {
+	return translator{u.translations}
+}
+
+// translateProducts replaces product names and descriptions
+func (t translator) translateProducts(ctx context.Context, products []entity.Product, locale string) error {
+	if len(products) == 0 {
+		return nil
+	}
+
+	byProduct, err := t.productTranslations(ctx, locale)
+	if err != nil {
+		return err
+	}
+
+	for i := range products {
+		if tr, ok := byProduct[products[i].Id]; ok {
+			products[i].Name = tr.Name
+			if tr.Description != "" {
+				products[i].Description = tr.Description
+			}
+		}
+	}
+
+	return nil
+}
+
+func (t translator) translateToppings(ctx context.Context, toppings []entity.ProductTopping, locale string) error {
+	if len(toppings) == 0 {
+		return nil
+	}
+
+	byTopping, err := t.toppingNames(ctx, locale)
+	if err != nil {
+		return err
+	}
+
+	for i := range toppings {
+		if name, ok := byTopping[toppings[i].Id]; ok {
+			toppings[i].Name = name
+		}
+	}
+
+	return nil
+}
+
+// translateCarts replaces the product and topping names of cart lines
+func (t translator) translateCarts(ctx context.Context, carts []entity.Cart, locale string) error {
+	if len(carts) == 0 {
+		return nil
+	}
+
+	byProduct, err := t.productTranslations(ctx, locale)
+	if err != nil {
+		return err
+	}
+
+	byTopping, err := t.toppingNames(ctx, locale)
+	if err != nil {
+		return err
+	}
+
+	for i := range carts {
+		if tr, ok := byProduct[carts[i].Product.Id]; ok {
+			carts[i].Product.Name = tr.Name
+		}
+
+		for j := range carts[i].Topping {
+			if name, ok := byTopping[carts[i].Topping[j].Id]; ok {
+				carts[i].Topping[j].Name = name
+			}
+		}
+	}
+
+	return nil
+}
+
+// translateCustomizations replaces the product names of saved customizations
+func (t translator) translateCustomizations(ctx context.Context, customizations []entity.Customization, locale string) error {
+	if len(customizations) == 0 {
+		return nil
+	}
+
+	byProduct, err := t.productTranslations(ctx, locale)
+	if err != nil {
+		return err
+	}
+
+	for i := range customizations {
+		if tr, ok := byProduct[customizations[i].ProductId]; ok && customizations[i].Product != nil {
+			customizations[i].Product.Name = tr.Name
+		}
+	}
+
+	return nil
+}
+
+// translateOrders replaces the product and topping names of the transaction orders
+func (t translator) translateOrders(ctx context.Context, transactions []entity.Transaction, locale string) error {
+	if len(transactions) == 0 {
+		return nil
+	}
+
+	byProduct, err := t.productTranslations(ctx, locale)
+	if err != nil {
+		return err
+	}
+
+	byTopping, err := t.toppingNames(ctx, locale)
+	if err != nil {
+		return err
+	}
+
+	for i := range transactions {
+		orders := transactions[i].Orders
+		for j := range orders {
+			if tr, ok := byProduct[orders[j].ProductId]; ok {
+				orders[j].Name = tr.Name
+			}
+
+			for k := range orders[j].Toppings {
+				if name, ok := byTopping[orders[j].Toppings[k].Id]; ok {
+					orders[j].Toppings[k].Name = name
+				}
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,119 @@
+package usecase
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/yosepalexsander/waysbucks-api/entity"
+	"github.com/yosepalexsander/waysbucks-api/helper"
+)
+
+// stubTranslationFinder has Indonesian names for product 1 and topping 1
+type stubTranslationFinder struct {
+	products map[string][]entity.Translation
+	toppings map[string][]entity.Translation
+}
+
+func (s *stubTranslationFinder) FindProductTranslations(ctx context.Context, productID int) ([]entity.Translation, error) {
+	return []entity.Translation{}, nil
+}
+
+func (s *stubTranslationFinder) FindToppingTranslations(ctx context.Context, toppingID int) ([]entity.Translation, error) {
+	return []entity.Translation{}, nil
+}
+
+func (s *stubTranslationFinder) FindProductTranslationsByLocale(ctx context.Context, locale string) ([]entity.Translation, error) {
+	return s.products[locale], nil
+}
+
+func (s *stubTranslationFinder) FindToppingTranslationsByLocale(ctx context.Context, locale string) ([]entity.Translation, error) {
+	return s.toppings[locale], nil
+}
+
+func newStubTranslationFinder() *stubTranslationFinder {
+	return &stubTranslationFinder{
+		products: map[string][]entity.Translation{
+			helper.LocaleIndonesian: {{Id: 1, Locale: helper.LocaleIndonesian, Name: "Kopi Susu", Description: "Kopi dengan susu"}},
+		},
+		toppings: map[string][]entity.Translation{
+			helper.LocaleIndonesian: {{Id: 1, Locale: helper.LocaleIndonesian, Name: "Mutiara"}},
+		},
+	}
+}
+
+func TestTranslateCarts(t *testing.T) {
+	tests := []struct {
+		locale       string
+		wantProducts []string
+		wantToppings []string
+	}{
+		{locale: helper.DefaultLocale, wantProducts: []string{"Latte", "Mocha"}, wantToppings: []string{"Boba", "Jelly"}},
+		{locale: helper.LocaleIndonesian, wantProducts: []string{"Kopi Susu", "Mocha"}, wantToppings: []string{"Mutiara", "Jelly"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.locale, func(t *testing.T) {
+			carts := []entity.Cart{
+				{Id: 1, Product: entity.CartProduct{Id: 1, Name: "Latte"}, Topping: []entity.CartTopping{{Id: 1, Name: "Boba"}}},
+				{Id: 2, Product: entity.CartProduct{Id: 2, Name: "Mocha"}, Topping: []entity.CartTopping{{Id: 2, Name: "Jelly"}}},
+			}
+
+			if err := (translator{newStubTranslationFinder()}).translateCarts(context.Background(), carts, tt.locale); err != nil {
+				t.Fatalf("translateCarts() error = %v", err)
+			}
+
+			products := []string{carts[0].Product.Name, carts[1].Product.Name}
+			toppings := []string{carts[0].Topping[0].Name, carts[1].Topping[0].Name}
+			if !reflect.DeepEqual(products, tt.wantProducts) || !reflect.DeepEqual(toppings, tt.wantToppings) {
+				t.Errorf("translateCarts() products = %v toppings = %v, want %v %v", products, toppings, tt.wantProducts, tt.wantToppings)
+			}
+		})
+	}
+}
+
+func TestTranslateCustomizations(t *testing.T) {
+	customizations := []entity.Customization{
+		{Id: 1, Name: "My usual", ProductId: 1, Product: &entity.CartProduct{Id: 1, Name: "Latte"}},
+		{Id: 2, Name: "Without product", ProductId: 1},
+		{Id: 3, Name: "Weekend", ProductId: 2, Product: &entity.CartProduct{Id: 2, Name: "Mocha"}},
+	}
+
+	if err := (translator{newStubTranslationFinder()}).translateCustomizations(context.Background(), customizations, helper.LocaleIndonesian); err != nil {
+		t.Fatalf("translateCustomizations() error = %v", err)
+	}
+
+	if customizations[0].Product.Name != "Kopi Susu" || customizations[0].Name != "My usual" {
+		t.Errorf("customization = %+v, want the product name translated and its own name kept", customizations[0])
+	}
+
+	if customizations[2].Product.Name != "Mocha" {
+		t.Errorf("untranslated product name = %s, want Mocha", customizations[2].Product.Name)
+	}
+}
+
+func TestGetTransactionTranslatesOrders(t *testing.T) {
+	ctx := context.Background()
+	transaction := newPaidTransaction("ORDER-1", entity.StatusPaid)
+	transaction.Orders[0].ProductId = 1
+	transaction.Orders[0].Name = "Latte"
+	transaction.Orders[0].Toppings = []entity.OrderTopping{{Id: 1, Name: "Boba"}}
+	transaction.Orders[1].ProductId = 2
+	transaction.Orders[1].Name = "Mocha"
+
+	repo := &stubTransactionRepository{transactions: map[string]*entity.Transaction{"ORDER-1": transaction}}
+	u := NewTransactionUseCase(repo, newStubProductFinder(), newStubStoreFinder(), newStubAddressFinder(), newStubTranslationFinder(), nil, testDeliveryRules)
+
+	got, err := u.GetTransaction(ctx, "ORDER-1", "user", false, helper.LocaleIndonesian)
+	if err != nil {
+		t.Fatalf("GetTransaction() error = %v", err)
+	}
+
+	if got.Orders[0].Name != "Kopi Susu" || got.Orders[0].Toppings[0].Name != "Mutiara" {
+		t.Errorf("first order = %+v, want the product and topping translated", got.Orders[0])
+	}
+
+	if got.Orders[1].Name != "Mocha" {
+		t.Errorf("second order name = %s, want Mocha", got.Orders[1].Name)
+	}
+}